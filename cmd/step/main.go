@@ -7,6 +7,7 @@ import (
 	"os"
 	"reflect"
 	"regexp"
+	"runtime"
 	"strings"
 	"time"
 
@@ -15,13 +16,14 @@ import (
 	"github.com/smallstep/certificates/ca"
 	"github.com/smallstep/cli-utils/command"
 	"github.com/smallstep/cli-utils/step"
-	"github.com/smallstep/cli-utils/ui"
 	"github.com/smallstep/cli-utils/usage"
 	"go.step.sm/crypto/jose"
 	"go.step.sm/crypto/pemutil"
 
 	"github.com/smallstep/cli/command/version"
 	"github.com/smallstep/cli/internal/plugin"
+	"github.com/smallstep/cli/internal/prompt"
+	"github.com/smallstep/cli/internal/termcolor"
 	"github.com/smallstep/cli/utils"
 
 	// Enabled cas interfaces.
@@ -67,6 +69,11 @@ func main() {
 
 	defer panicHandler()
 
+	// disable colors if the output is not a terminal, or if NO_COLOR is set
+	if termcolor.ShouldDisable() {
+		termcolor.Disable()
+	}
+
 	// create new instance of app
 	app := newApp(os.Stdout, os.Stderr)
 
@@ -78,14 +85,14 @@ func main() {
 			if os.Getenv("STEPDEBUG") == "1" {
 				fmt.Fprintf(os.Stderr, "%+v\n\n%s", err, messenger.Message())
 			} else {
-				fmt.Fprintln(os.Stderr, messenger.Message())
+				fmt.Fprintln(os.Stderr, termcolor.Error(messenger.Message()))
 				fmt.Fprintln(os.Stderr, "Re-run with STEPDEBUG=1 for more info.")
 			}
 		} else {
 			if os.Getenv("STEPDEBUG") == "1" {
 				fmt.Fprintf(os.Stderr, "%+v\n", err)
 			} else {
-				fmt.Fprintln(os.Stderr, err)
+				fmt.Fprintln(os.Stderr, termcolor.Error(err.Error()))
 			}
 		}
 		//nolint:gocritic // ignore exitAfterDefer error because the defer is required for recovery.
@@ -97,10 +104,10 @@ func newApp(stdout, stderr io.Writer) *cli.App {
 	// Define default file writers and prompters for go.step.sm/crypto
 	pemutil.WriteFile = utils.WriteFile
 	pemutil.PromptPassword = func(msg string) ([]byte, error) {
-		return ui.PromptPassword(msg)
+		return prompt.PromptPassword(msg)
 	}
 	jose.PromptPassword = func(msg string) ([]byte, error) {
-		return ui.PromptPassword(msg)
+		return prompt.PromptPassword(msg)
 	}
 
 	// Override global framework components
@@ -131,6 +138,55 @@ func newApp(stdout, stderr io.Writer) *cli.App {
 		Usage: "path to the config file to use for CLI flags",
 	})
 
+	// Flag to disable the colors in the output
+	app.Flags = append(app.Flags, cli.BoolFlag{
+		Name:  "no-color",
+		Usage: "disable the colors in the output, colors are also disabled if NO_COLOR is set",
+	})
+	// Flag to control if the written files are flushed to disk
+	app.Flags = append(app.Flags, cli.BoolTFlag{
+		Name:   "fsync",
+		Usage:  "flush the written files and their directories to disk, use --fsync=false to skip it in ephemeral environments",
+		EnvVar: "STEP_FSYNC",
+	})
+	// Flag to answer the prompts using a file descriptor
+	app.Flags = append(app.Flags, cli.StringFlag{
+		Name:  "prompt-fd",
+		Usage: "answer the prompts with the JSON protocol on the file descriptor <n>, or on the <in>,<out> file descriptors",
+	})
+	// Flag to set the umask of the written files
+	app.Flags = append(app.Flags, cli.StringFlag{
+		Name:   "umask",
+		Usage:  "set the octal <umask> applied to the permissions of the written files, e.g. 027; it is ignored on Windows",
+		EnvVar: "STEP_UMASK",
+	})
+	app.Before = func(ctx *cli.Context) error {
+		if ctx.GlobalBool("no-color") {
+			termcolor.Disable()
+		}
+		utils.SetFsync(ctx.GlobalBoolT("fsync"))
+		if s := ctx.GlobalString("umask"); s != "" {
+			mask, err := utils.ParseUmask(s)
+			if err != nil {
+				return fmt.Errorf("error parsing flag '--umask': %w", err)
+			}
+			// Windows does not have a umask, the flag is ignored
+			if runtime.GOOS == "windows" {
+				fmt.Fprintln(stderr, "warning: the flag '--umask' is not supported on Windows and will be ignored")
+			} else if err := utils.SetUmask(mask); err != nil {
+				return err
+			}
+		}
+		if fd := ctx.GlobalString("prompt-fd"); fd != "" {
+			p, err := prompt.ParseFD(fd)
+			if err != nil {
+				return fmt.Errorf("error parsing flag '--prompt-fd': %w", err)
+			}
+			prompt.Set(p)
+		}
+		return nil
+	}
+
 	// Action runs on `step` or `step <command>` if the command is not enabled.
 	app.Action = func(ctx *cli.Context) error {
 		args := ctx.Args()
@@ -0,0 +1,306 @@
+package ca
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/certificates/api"
+	"github.com/smallstep/certificates/ca"
+	"github.com/smallstep/certificates/pki"
+	"github.com/smallstep/cli-utils/command"
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/cli-utils/ui"
+	"go.step.sm/crypto/x509util"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/utils"
+	"github.com/smallstep/cli/utils/cautils"
+)
+
+func bundleCommand() cli.Command {
+	return cli.Command{
+		Name:   "bundle",
+		Action: command.ActionFunc(bundleAction),
+		Usage:  "download a bundle with the root and intermediate certificates",
+		UsageText: `**step ca bundle**
+[**--out**=<file>] [**--force**] [**--tls-cipher-suites**=<list>]
+[**--ca-url**=<uri>] [**--root**=<file>] [**--context**=<name>]`,
+		Description: `**step ca bundle** downloads the root and intermediate certificates of the
+CA and assembles them in a single PEM bundle that peers can use to verify the
+certificates issued by the CA.
+
+The certificates in the bundle are deduplicated and in chain order: each
+intermediate certificate is followed by its issuer, and the root certificates
+are at the end. If the CA has several chains, each one is written in full,
+starting from the intermediate closest to the leaf, before the next one, and an
+intermediate shared by several chains is only written once. The command fails
+if an intermediate certificate does not chain to one of the roots of the CA.
+
+## EXAMPLES
+
+Download the verification bundle with flags set by <step ca bootstrap>:
+'''
+$ step ca bundle --out verify.pem
+'''
+
+Download the verification bundle with custom flags:
+'''
+$ step ca bundle --out verify.pem \
+    --ca-url https://ca.example.com \
+    --root /path/to/root_ca.crt
+'''
+
+Print the verification bundle:
+'''
+$ step ca bundle
+'''`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "out,output-file",
+				Usage: "The <file> to write the bundle to. Defaults to printing the bundle to STDOUT.",
+			},
+			tlsCipherSuitesFlag,
+			flags.CaURL,
+			flags.Force,
+			flags.Root,
+			flags.Context,
+		},
+	}
+}
+
+func bundleAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 0); err != nil {
+		return err
+	}
+
+	caURL, err := flags.ParseCaURL(ctx)
+	if err != nil {
+		return err
+	}
+
+	root := ctx.String("root")
+	if root == "" {
+		root = pki.GetRootCAPath()
+		if _, err := os.Stat(root); err != nil {
+			return errs.RequiredFlag(ctx, "root")
+		}
+	}
+
+	cipherSuites, err := cautils.ParseCipherSuitesFlag(ctx)
+	if err != nil {
+		return err
+	}
+
+	options := []ca.ClientOption{ca.WithRootFile(root)}
+	if len(cipherSuites) > 0 {
+		rootCAs, err := x509util.ReadCertPool(root)
+		if err != nil {
+			return err
+		}
+		options = []ca.ClientOption{ca.WithTransport(cautils.NewTransport(rootCAs, cipherSuites))}
+	}
+	client, err := ca.NewClient(caURL, options...)
+	if err != nil {
+		return err
+	}
+	roots, err := client.Roots()
+	if err != nil {
+		return err
+	}
+	intermediates, err := getIntermediates(caURL, root, cipherSuites)
+	if err != nil {
+		return err
+	}
+
+	var rootCerts []*x509.Certificate
+	for _, crt := range roots.Certificates {
+		rootCerts = append(rootCerts, crt.Certificate)
+	}
+	bundle, err := orderBundle(rootCerts, intermediates)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	for _, crt := range bundle {
+		data = append(data, pem.EncodeToMemory(&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: crt.Raw,
+		})...)
+	}
+
+	if outFile := ctx.String("out"); outFile != "" {
+		if err := utils.WriteFile(outFile, data, 0600); err != nil {
+			return err
+		}
+		ui.Printf("The verification bundle has been saved in %s.\n", outFile)
+	} else {
+		fmt.Print(string(data))
+	}
+	return nil
+}
+
+// getIntermediates downloads the intermediate certificates of the CA, using
+// only the given cipher suites if any.
+func getIntermediates(caURL, root string, cipherSuites []uint16) ([]*x509.Certificate, error) {
+	u, err := url.Parse(caURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing %s", caURL)
+	}
+	rootCAs, err := x509util.ReadCertPool(root)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{
+		Transport: cautils.NewTransport(rootCAs, cipherSuites),
+	}
+
+	u = u.ResolveReference(&url.URL{Path: "/intermediates"})
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return nil, errors.Wrapf(err, "client GET %s failed", u)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusNotImplemented:
+		return nil, errors.Errorf("client GET %s failed: the CA does not support downloading the intermediate certificates", u)
+	case resp.StatusCode >= 400:
+		return nil, errors.Errorf("client GET %s failed with status code %d", u, resp.StatusCode)
+	}
+
+	var intermediates api.IntermediatesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&intermediates); err != nil {
+		return nil, errors.Wrapf(err, "error reading %s", u)
+	}
+	var certs []*x509.Certificate
+	for _, crt := range intermediates.Certificates {
+		if crt.Certificate != nil {
+			certs = append(certs, crt.Certificate)
+		}
+	}
+	return certs, nil
+}
+
+// orderBundle returns the deduplicated intermediate and root certificates in
+// chain order, with each intermediate followed by its issuer, and the roots
+// at the end.
+func orderBundle(roots, intermediates []*x509.Certificate) ([]*x509.Certificate, error) {
+	roots = uniqueCertificates(roots)
+	if len(roots) == 0 {
+		return nil, errors.New("error creating bundle: the CA did not return any root certificate")
+	}
+
+	// Check that all the intermediates chain to a root, adding them level by
+	// level starting from the roots.
+	intermediates = removeCertificates(uniqueCertificates(intermediates), roots)
+	placed := append([]*x509.Certificate{}, roots...)
+	pending := intermediates
+	for len(pending) > 0 {
+		var level, next []*x509.Certificate
+		for _, crt := range pending {
+			if issuedByAny(crt, placed) {
+				level = append(level, crt)
+			} else {
+				next = append(next, crt)
+			}
+		}
+		if len(level) == 0 {
+			return nil, errors.Errorf("error creating bundle: intermediate certificate %q does not chain to a root certificate",
+				next[0].Subject.String())
+		}
+		placed = append(placed, level...)
+		pending = next
+	}
+
+	// Add the path of each intermediate that has not issued another one,
+	// followed by its issuers up to the root. An issuer shared by several
+	// paths is only added after the first one.
+	var bundle []*x509.Certificate
+	added := make(map[string]bool)
+	addPath := func(crt *x509.Certificate) {
+		for crt != nil && !added[string(crt.Raw)] {
+			added[string(crt.Raw)] = true
+			bundle = append(bundle, crt)
+			crt = findIssuer(crt, intermediates)
+		}
+	}
+	for _, crt := range intermediates {
+		if !issuesAny(crt, intermediates) {
+			addPath(crt)
+		}
+	}
+	// Intermediates that issued each other are not in any path.
+	for _, crt := range intermediates {
+		addPath(crt)
+	}
+	return append(bundle, roots...), nil
+}
+
+// findIssuer returns the first certificate in issuers, other than crt, that
+// issued crt, or nil if there is none.
+func findIssuer(crt *x509.Certificate, issuers []*x509.Certificate) *x509.Certificate {
+	for _, issuer := range issuers {
+		if issuer != crt && issuedByAny(crt, []*x509.Certificate{issuer}) {
+			return issuer
+		}
+	}
+	return nil
+}
+
+// issuesAny returns true if issuer has issued any certificate in certs other
+// than itself.
+func issuesAny(issuer *x509.Certificate, certs []*x509.Certificate) bool {
+	for _, crt := range certs {
+		if crt != issuer && issuedByAny(crt, []*x509.Certificate{issuer}) {
+			return true
+		}
+	}
+	return false
+}
+
+func issuedByAny(crt *x509.Certificate, issuers []*x509.Certificate) bool {
+	for _, issuer := range issuers {
+		if bytes.Equal(crt.RawIssuer, issuer.RawSubject) && crt.CheckSignatureFrom(issuer) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueCertificates(certs []*x509.Certificate) []*x509.Certificate {
+	var unique []*x509.Certificate
+	seen := make(map[string]bool)
+	for _, crt := range certs {
+		if !seen[string(crt.Raw)] {
+			seen[string(crt.Raw)] = true
+			unique = append(unique, crt)
+		}
+	}
+	return unique
+}
+
+func removeCertificates(certs, remove []*x509.Certificate) []*x509.Certificate {
+	var result []*x509.Certificate
+	for _, crt := range certs {
+		found := false
+		for _, r := range remove {
+			if crt.Equal(r) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, crt)
+		}
+	}
+	return result
+}
@@ -0,0 +1,128 @@
+package ca
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/crypto/keyutil"
+	"go.step.sm/crypto/minica"
+
+	"github.com/smallstep/certificates/api"
+)
+
+func Test_orderBundle(t *testing.T) {
+	ca, err := minica.New()
+	require.NoError(t, err)
+	other, err := minica.New()
+	require.NoError(t, err)
+
+	// Issuing intermediate signed by the intermediate of the minica.
+	pub, _, err := keyutil.GenerateDefaultKeyPair()
+	require.NoError(t, err)
+	issuing, err := ca.Sign(&x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Issuing CA"},
+		PublicKey:             pub,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	})
+	require.NoError(t, err)
+	// Second issuing intermediate signed by the intermediate of the minica.
+	sibling, err := ca.Sign(&x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Sibling Issuing CA"},
+		PublicKey:             pub,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	})
+	require.NoError(t, err)
+	// Issuing intermediate signed by the intermediate of the other minica.
+	otherIssuing, err := other.Sign(&x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Other Issuing CA"},
+		PublicKey:             pub,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name          string
+		roots         []*x509.Certificate
+		intermediates []*x509.Certificate
+		want          []*x509.Certificate
+		wantErr       bool
+	}{
+		{"ok", []*x509.Certificate{ca.Root}, []*x509.Certificate{ca.Intermediate}, []*x509.Certificate{ca.Intermediate, ca.Root}, false},
+		{"ok/no-intermediates", []*x509.Certificate{ca.Root}, nil, []*x509.Certificate{ca.Root}, false},
+		{"ok/dedupe", []*x509.Certificate{ca.Root, ca.Root}, []*x509.Certificate{ca.Intermediate, ca.Intermediate, ca.Root}, []*x509.Certificate{ca.Intermediate, ca.Root}, false},
+		{"ok/chain-order", []*x509.Certificate{ca.Root}, []*x509.Certificate{ca.Intermediate, issuing}, []*x509.Certificate{issuing, ca.Intermediate, ca.Root}, false},
+		{"ok/multiple-roots", []*x509.Certificate{ca.Root, other.Root}, []*x509.Certificate{other.Intermediate, ca.Intermediate}, []*x509.Certificate{other.Intermediate, ca.Intermediate, ca.Root, other.Root}, false},
+		{"ok/two-chains", []*x509.Certificate{ca.Root, other.Root}, []*x509.Certificate{ca.Intermediate, issuing, other.Intermediate, otherIssuing},
+			[]*x509.Certificate{issuing, ca.Intermediate, otherIssuing, other.Intermediate, ca.Root, other.Root}, false},
+		{"ok/shared-issuer", []*x509.Certificate{ca.Root}, []*x509.Certificate{ca.Intermediate, issuing, sibling},
+			[]*x509.Certificate{issuing, ca.Intermediate, sibling, ca.Root}, false},
+		{"fail/no-roots", nil, []*x509.Certificate{ca.Intermediate}, nil, true},
+		{"fail/unchained", []*x509.Certificate{ca.Root}, []*x509.Certificate{ca.Intermediate, other.Intermediate}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := orderBundle(tt.roots, tt.intermediates)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_getIntermediates(t *testing.T) {
+	ca, err := minica.New()
+	require.NoError(t, err)
+
+	status := http.StatusOK
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/intermediates" {
+			http.NotFound(w, r)
+			return
+		}
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		json.NewEncoder(w).Encode(api.IntermediatesResponse{
+			Certificates: []api.Certificate{{Certificate: ca.Intermediate}},
+		})
+	}))
+	defer srv.Close()
+
+	rootFile := filepath.Join(t.TempDir(), "root_ca.crt")
+	require.NoError(t, os.WriteFile(rootFile, pem.EncodeToMemory(&pem.Block{
+		Type: "CERTIFICATE", Bytes: srv.Certificate().Raw,
+	}), 0600))
+
+	certs, err := getIntermediates(srv.URL, rootFile, nil)
+	require.NoError(t, err)
+	if assert.Len(t, certs, 1) {
+		assert.Equal(t, ca.Intermediate.Raw, certs[0].Raw)
+	}
+
+	status = http.StatusNotImplemented
+	_, err = getIntermediates(srv.URL, rootFile, nil)
+	assert.ErrorContains(t, err, "does not support")
+
+	status = http.StatusInternalServerError
+	_, err = getIntermediates(srv.URL, rootFile, nil)
+	assert.ErrorContains(t, err, "status code 500")
+}
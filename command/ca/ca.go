@@ -1,6 +1,8 @@
 package ca
 
 import (
+	"time"
+
 	"github.com/urfave/cli"
 
 	"github.com/smallstep/cli-utils/command"
@@ -70,6 +72,8 @@ $ step ca renew internal.crt internal.key \
 			initCommand(),
 			bootstrapCommand(),
 			tokenCommand(),
+			inspectTokenCommand(),
+			whoamiCommand(),
 			certificateCommand(),
 			rekeyCertificateCommand(),
 			renewCertificateCommand(),
@@ -78,10 +82,15 @@ $ step ca renew internal.crt internal.key \
 			signCertificateCommand(),
 			rootCommand(),
 			rootsCommand(),
+			selftestCommand(),
+			crlCommand(),
 			federationCommand(),
+			bundleCommand(),
 			acme.Command(),
 			policy.Command(),
 			admin.Command(),
+			ledgerCommand(),
+			receiptCommand(),
 		},
 	}
 
@@ -144,6 +153,239 @@ challenge validation requests.`,
 		Usage: "The <fingerprint> of the targeted root certificate.",
 	}
 
+	noEKUFlag = cli.BoolFlag{
+		Name: "no-eku",
+		Usage: `Request a certificate without the extended key usage extension. The request
+sets the 'extKeyUsage' template variable to an empty list, so it only takes effect
+if the provisioner template allows it. This flag is incompatible with setting the
+'extKeyUsage' variable using the **--set** or **--set-file** flags.`,
+	}
+
+	strictFlag = cli.BoolFlag{
+		Name: "strict",
+		Usage: `Enable all the safety checks on the issued certificate. It is equivalent to
+using the **--strict-sans**, **--require-chain**, and **--warn-expiry-fatal**
+flags: the command fails if the CA modifies the requested SANs, if the
+certificate chain does not verify with the root certificate, if the certificate
+expires after its issuer, or if the CA reduces the requested validity. It also
+fails if the token is expired or not yet valid. No files are written if a check
+fails.`,
+	}
+
+	strictSANsFlag = cli.BoolFlag{
+		Name: "strict-sans",
+		Usage: `Fail if the SANs in the issued certificate are not the same as the ones in the
+certificate request.`,
+	}
+
+	requireChainFlag = cli.BoolFlag{
+		Name: "require-chain",
+		Usage: `Fail if the issued certificate chain does not verify with the root certificate,
+or if the certificate expires after its issuer.`,
+	}
+
+	warnExpiryFatalFlag = cli.BoolFlag{
+		Name: "warn-expiry-fatal",
+		Usage: `Fail instead of printing a warning if the CA reduces the validity requested
+with the **--not-after** flag.`,
+	}
+
+	onClampFlag = cli.StringFlag{
+		Name: "on-clamp",
+		Usage: `The <policy> used if the CA reduces the validity requested with the
+**--not-after** flag. With 'fail', no files are written and the command exits
+with a non-zero status code.
+
+: <policy> is a case-sensitive string and must be one of:
+
+    **accept**
+    :  Use the certificate without printing anything
+
+    **warn**
+    :  Use the certificate and print a warning (default)
+
+    **fail**
+    :  Fail without writing the certificate, the default with **--strict** and
+    **--warn-expiry-fatal**`,
+		Value: "warn",
+	}
+
+	chainOrderFlag = cli.StringFlag{
+		Name: "chain-order",
+		Usage: `The <order> of the certificates in the certificate file.
+
+: <order> is a case-sensitive string and must be one of:
+
+    **leaf-first**
+    :  The leaf certificate first, followed by its issuers (default)
+
+    **leaf-last**
+    :  The issuers first, followed by the leaf certificate`,
+		Value: "leaf-first",
+	}
+
+	lineEndingFlag = cli.StringFlag{
+		Name: "line-ending",
+		Usage: `The line <ending> of the PEM files written by the command.
+
+: <ending> is a case-sensitive string and must be one of:
+
+    **lf**
+    :  Unix line endings (default)
+
+    **crlf**
+    :  Windows line endings`,
+		Value: "lf",
+	}
+
+	allowedSignatureAlgsFlag = cli.StringSliceFlag{
+		Name: "allowed-signature-algs",
+		Usage: `The list of signature algorithms allowed in the certificates returned by the
+CA, the command fails if the certificate or an intermediate is signed with a
+different algorithm. Use the flag multiple times or a comma-separated list to
+allow multiple algorithms. The names are case-insensitive, for example
+'SHA256-RSA', 'SHA256-RSAPSS', 'ECDSA-SHA256', or 'Ed25519'. By default, the
+RSA PKCS #1 v1.5 and PSS, and ECDSA algorithms with SHA-256, SHA-384, and
+SHA-512, and Ed25519 are allowed. Unknown names are rejected before requesting
+the certificate.`,
+	}
+
+	showFlag = cli.BoolFlag{
+		Name: "show",
+		Usage: `Print a summary of the new certificate and the PEM of the certificate chain
+in addition to writing <crt-file>. The output is highlighted unless the colors
+are disabled with the '--no-color' flag, or the output is not a terminal.`,
+	}
+
+	preflightFlag = cli.BoolFlag{
+		Name: "preflight",
+		Usage: `Check the health of the CA before generating a token, so a single-use token
+is not wasted if the CA is not reachable. The check is skipped if the token is
+given with the **--token** flag, or in offline mode.`,
+	}
+
+	syslogFlag = cli.BoolFlag{
+		Name: "syslog",
+		Usage: `Send a record of the issuance, with the subject, serial number, and expiration
+of the new certificate, to the local syslog. It is not supported on Windows.`,
+	}
+
+	syslogFacilityFlag = cli.StringFlag{
+		Name: "syslog-facility",
+		Usage: `The syslog <facility> used by the **--syslog** flag: 'kern', 'user', 'mail',
+'daemon', 'auth', 'syslog', 'lpr', 'news', 'uucp', 'cron', 'authpriv', 'ftp',
+or 'local0' to 'local7'.`,
+		Value: "user",
+	}
+
+	syslogPriorityFlag = cli.StringFlag{
+		Name: "syslog-priority",
+		Usage: `The syslog <priority> used by the **--syslog** flag: 'emerg', 'alert', 'crit',
+'err', 'warning', 'notice', 'info', or 'debug'.`,
+		Value: "info",
+	}
+
+	clockSkewFlag = cli.DurationFlag{
+		Name: "clock-skew",
+		Usage: `The <duration> allowed for the clock differences between this host and the
+CA when the validity of the token is checked locally. If the token is expired or
+not yet valid a warning is printed, and with the **--strict** flag the command fails
+before generating a key or contacting the CA.`,
+		Value: time.Minute,
+	}
+
+	minKeyStrengthFlag = cli.IntFlag{
+		Name: "min-key-strength",
+		Usage: `Fail if the security strength of the key in the certificate request is lower
+than the given <bits>. The strength is defined by NIST SP 800-57: an RSA key of
+2048 bits has 112 bits of security, 3072 bits have 128, 7680 have 192, and
+15360 have 256; the EC curves P-256, P-384, and P-521 have 128, 192, and 256,
+and Ed25519 has 128 bits of security.`,
+	}
+
+	crossSignedFlag = cli.BoolFlag{
+		Name: "cross-signed",
+		Usage: `Write the certificate chain that verifies with the trusted **--root**. Use it
+during a root rotation, when the CA returns a chain to a new root and also
+provides an intermediate cross-signed by the old one. A warning is printed, and
+the chain returned by the CA is written, if no compatible chain is found.`,
+	}
+
+	tlsCipherSuitesFlag = cli.StringFlag{
+		Name: "tls-cipher-suites",
+		Usage: `The comma separated <list> of TLS 1.2 cipher suites allowed in the connection
+with the CA, using the Go names, e.g. TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256.
+Unknown and insecure suites are rejected. The TLS 1.3 suites are always enabled.
+If the flag is not set, the default suites of Go are used.`,
+	}
+
+	rootOutFlag = cli.StringFlag{
+		Name: "root-out",
+		Usage: `Write the root certificate that validates the new certificate chain to <file>.
+The root is one of the roots in the **--root** flag, or the root obtained with
+the fingerprint in the token.`,
+	}
+
+	skiFlag = cli.StringFlag{
+		Name: "ski",
+		Usage: `The <method> used to compute the subject key identifier of the new
+certificate. The value is passed to the certificate template in the
+**subjectKeyId** variable, and requires a template that uses it.
+
+: <method> is one of:
+
+    **sha1-pubkey**
+    :  The SHA-1 hash of the subject public key (RFC 5280).
+
+    **sha256-pubkey**
+    :  The leftmost 160 bits of the SHA-256 hash of the subject public key (RFC 7093).
+
+    **<hex>**
+    :  A literal subject key identifier in hexadecimal, e.g. 0a1b2c or 0a:1b:2c.
+
+: A warning is printed if the certificate does not have the requested subject key
+identifier, and the command fails with **--strict**.`,
+	}
+
+	deadlineFromTokenFlag = cli.BoolFlag{
+		Name: "deadline-from-token",
+		Usage: `Cap the not after of the new certificate with the expiration of the token in
+the **--token** flag. A warning is printed if the requested not after is reduced,
+or if the certificate would be valid for less than 5 minutes. Requires the
+**--token** flag.`,
+	}
+
+	issuerHintFlag = cli.StringFlag{
+		Name: "issuer-hint",
+		Usage: `Use the CA URL in the audiences of the token whose host contains <substring>.
+The audiences with the same scheme and host are considered the same CA, and the
+command fails if no CA, or more than one, matches. The selected URL takes
+precedence over **--ca-url**.`,
+	}
+
+	verifyTokenFlag = cli.BoolFlag{
+		Name: "verify-token",
+		Usage: `Verify the signature of the token with the key of the provisioner, obtained
+from the CA, before sending the request. Only tokens of JWK provisioners can be
+verified.`,
+	}
+
+	maxResponseSizeFlag = cli.Int64Flag{
+		Name: "max-response-size",
+		Usage: `The maximum <size> in bytes of a response from the CA. The command fails if
+the CA sends a larger response. If the flag is not set, the size of the
+responses is not limited.`,
+	}
+
+	sanPolicyFileFlag = cli.StringFlag{
+		Name: "san-policy-file",
+		Usage: `Check the SANs against the X.509 name policy in <file> before requesting the
+certificate. The file uses the format of the x509 policy of a provisioner, e.g.
+'{"allow": {"dns": ["*.corp.example.com"]}}', and the command fails if any SAN is
+not allowed. The SANs in the certificate request are checked, including the ones
+added from the token, and if there are no SANs, the subject is checked.`,
+	}
+
 	provisionerKidFlag = cli.StringFlag{
 		Name:  "kid",
 		Usage: "The provisioner <kid> to use.",
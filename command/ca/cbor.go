@@ -0,0 +1,97 @@
+package ca
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"go.step.sm/crypto/pemutil"
+
+	"github.com/smallstep/cli/utils"
+)
+
+// cborEnvelope is the CBOR map written by the --cbor-out flag. The map uses
+// integer keys to keep it compact, the certificates are DER encoded, and the
+// private key is a PKCS #8 DER encoded key.
+type cborEnvelope struct {
+	Certificate []byte       `cbor:"1,keyasint"`
+	Chain       [][]byte     `cbor:"2,keyasint"`
+	PrivateKey  []byte       `cbor:"3,keyasint"`
+	Roots       [][]byte     `cbor:"4,keyasint"`
+	Metadata    cborMetadata `cbor:"5,keyasint"`
+}
+
+// cborMetadata is the information of the leaf certificate in the CBOR
+// envelope. The times are Unix timestamps.
+type cborMetadata struct {
+	Subject     string   `cbor:"1,keyasint"`
+	SANs        []string `cbor:"2,keyasint,omitempty"`
+	Serial      []byte   `cbor:"3,keyasint"`
+	NotBefore   int64    `cbor:"4,keyasint"`
+	NotAfter    int64    `cbor:"5,keyasint"`
+	Fingerprint []byte   `cbor:"6,keyasint"`
+}
+
+// newCBOREnvelope returns the CBOR envelope with the certificate chain, with
+// the leaf first, the private key, and the root certificates.
+func newCBOREnvelope(certs []*x509.Certificate, pk crypto.PrivateKey, roots []*x509.Certificate) (*cborEnvelope, error) {
+	if len(certs) == 0 {
+		return nil, errors.New("error creating CBOR envelope: certificate chain is empty")
+	}
+	keyBlock, err := pemutil.Serialize(pk, pemutil.WithPKCS8(true))
+	if err != nil {
+		return nil, err
+	}
+
+	leaf := certs[0]
+	sum := sha256.Sum256(leaf.Raw)
+	env := &cborEnvelope{
+		Certificate: leaf.Raw,
+		Chain:       make([][]byte, 0, len(certs)-1),
+		PrivateKey:  keyBlock.Bytes,
+		Roots:       make([][]byte, 0, len(roots)),
+		Metadata: cborMetadata{
+			Subject:     leaf.Subject.CommonName,
+			SANs:        certificateSANs(leaf),
+			Serial:      leaf.SerialNumber.Bytes(),
+			NotBefore:   leaf.NotBefore.Unix(),
+			NotAfter:    leaf.NotAfter.Unix(),
+			Fingerprint: sum[:],
+		},
+	}
+	for _, crt := range certs[1:] {
+		env.Chain = append(env.Chain, crt.Raw)
+	}
+	for _, crt := range roots {
+		env.Roots = append(env.Roots, crt.Raw)
+	}
+	return env, nil
+}
+
+// writeCBOREnvelope writes the CBOR envelope with the certificate chain in
+// crtFile, the private key, and the root certificate in roots that validates
+// the chain in filename.
+func writeCBOREnvelope(ctx *cli.Context, filename, crtFile string, pk crypto.PrivateKey, roots []*x509.Certificate) error {
+	certs, err := readCertificateChain(ctx, crtFile)
+	if err != nil {
+		return err
+	}
+	root, err := bundleRoot(roots, certs)
+	if err != nil {
+		return err
+	}
+
+	env, err := newCBOREnvelope(certs, pk, []*x509.Certificate{root})
+	if err != nil {
+		return err
+	}
+	b, err := cbor.Marshal(env)
+	if err != nil {
+		return errors.Wrap(err, "error encoding CBOR envelope")
+	}
+	return utils.WriteFile(filename, b, 0600)
+}
@@ -0,0 +1,64 @@
+package ca
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/crypto/minica"
+)
+
+func Test_newCBOREnvelope(t *testing.T) {
+	ca, err := minica.New()
+	require.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	leaf, err := ca.Sign(&x509.Certificate{
+		Subject:   pkix.Name{CommonName: "foo"},
+		DNSNames:  []string{"foo.internal"},
+		PublicKey: key.Public(),
+	})
+	require.NoError(t, err)
+
+	env, err := newCBOREnvelope([]*x509.Certificate{leaf, ca.Intermediate}, key, []*x509.Certificate{ca.Root})
+	require.NoError(t, err)
+
+	b, err := cbor.Marshal(env)
+	require.NoError(t, err)
+
+	// The envelope must be readable without the Go types.
+	var m map[int]interface{}
+	require.NoError(t, cbor.Unmarshal(b, &m))
+	assert.Equal(t, leaf.Raw, m[1])
+	assert.Equal(t, []interface{}{ca.Intermediate.Raw}, m[2])
+	assert.Equal(t, []interface{}{ca.Root.Raw}, m[4])
+
+	var got cborEnvelope
+	require.NoError(t, cbor.Unmarshal(b, &got))
+	assert.Equal(t, env, &got)
+
+	pk, err := x509.ParsePKCS8PrivateKey(got.PrivateKey)
+	require.NoError(t, err)
+	assert.Equal(t, key, pk)
+
+	sum := sha256.Sum256(leaf.Raw)
+	assert.Equal(t, cborMetadata{
+		Subject:     "foo",
+		SANs:        []string{"foo.internal"},
+		Serial:      leaf.SerialNumber.Bytes(),
+		NotBefore:   leaf.NotBefore.Unix(),
+		NotAfter:    leaf.NotAfter.Unix(),
+		Fingerprint: sum[:],
+	}, got.Metadata)
+
+	_, err = newCBOREnvelope(nil, key, nil)
+	assert.Error(t, err)
+}
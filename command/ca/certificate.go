@@ -1,12 +1,23 @@
 package ca
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"fmt"
+	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/urfave/cli"
 
+	"github.com/smallstep/certificates/api"
 	"github.com/smallstep/cli-utils/command"
 	"github.com/smallstep/cli-utils/errs"
 	"github.com/smallstep/cli-utils/step"
@@ -14,7 +25,11 @@ import (
 	"go.step.sm/crypto/pemutil"
 
 	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/ledger"
+	"github.com/smallstep/cli/internal/store"
+	"github.com/smallstep/cli/internal/tracing"
 	"github.com/smallstep/cli/token"
+	"github.com/smallstep/cli/utils"
 	"github.com/smallstep/cli/utils/cautils"
 )
 
@@ -26,15 +41,49 @@ func certificateCommand() cli.Command {
 		UsageText: `**step ca certificate** <subject> <crt-file> <key-file>
 [**--token**=<token>]  [**--issuer**=<name>] [**--provisioner-password-file**=<file>]
 [**--not-before**=<time|duration>] [**--not-after**=<time|duration>]
-[**--san**=<SAN>] [**--set**=<key=value>] [**--set-file**=<file>]
+[**--san**=<SAN>] [**--dns**=<dns>] [**--ip**=<ip>] [**--email**=<email>]
+[**--uri**=<uri>] [**--set**=<key=value>] [**--set-file**=<file>] [**--no-eku**]
+[**--strict**] [**--strict-sans**] [**--require-chain**] [**--warn-expiry-fatal**]
+[**--on-clamp**=<policy>] [**--chain-order**=<order>] [**--line-ending**=<ending>]
+[**--clock-skew**=<duration>] [**--allowed-signature-algs**=<list>] [**--show**]
+[**--preflight**] [**--syslog**] [**--syslog-facility**=<facility>] [**--syslog-priority**=<priority>]
+[**--subject-dn**=<name>] [**--otel-endpoint**=<uri>]
 [**--acme**=<file>] [**--standalone**] [**--webroot**=<file>]
+[**--eab-key-id**=<kid>] [**--eab-key-file**=<file>]
 [**--contact**=<email>] [**--http-listen**=<address>] [**--bundle**]
-[**--kty**=<type>] [**--curve**=<curve>] [**--size**=<size>] [**--console**]
+[**--kty**=<type>] [**--curve**=<curve>] [**--size**=<size>] [**--key**=<file>]
+[**--console**]
 [**--x5c-cert**=<file>] [**--x5c-key**=<file>] [**--k8ssa-token-path**=<file>]
 [**--offline**] [**--password-file**] [**--ca-url**=<uri>] [**--root**=<file>]
-[**--context**=<name>]`,
+[**--context**=<name>] [**--ledger**=<file>]
+[**--san-from-metadata**] [**--cloud**=<name>]
+[**--spiffe-template**=<template>] [**--spiffe-trust-domain**=<domain>]
+[**--spiffe-workload**=<name>]
+[**--compare**=<crt-file>] [**--apply**] [**--state-file**=<file>]
+[**--lock-file**=<file>] [**--lock-timeout**=<duration>]
+[**--rotate-if-expires-in**=<duration>] [**--pre-check-expiry-only**]
+[**--echo-expiry-only**] [**--warn**=<duration>] [**--critical**=<duration>]
+[**--extra-extension**=<oid:critical:file>] [**--preserve-extensions**]
+[**--min-key-strength**=<bits>] [**--verify-key**] [**--tls-cipher-suites**=<list>]
+[**--cross-signed**] [**--max-response-size**=<size>] [**--encrypted-key-file**=<file>]
+[**--encrypted-key-password-file**=<file>]
+[**--root-out**=<file>] [**--ski**=<method>] [**--deadline-from-token**]
+[**--issuer-hint**=<substring>] [**--verify-token**]
+[**--auto-provisioner**] [**--receipt-out**=<file>] [**--tar-out**=<file>] [**--cbor-out**=<file>]
+[**--serial-file**=<file>] [**--quiet-success**] [**--verbose-error**]
+[**--san-policy-file**=<file>] [**--csr-out**=<file>]
+
+**step ca certificate** <subject> **--crt-url**=<uri> **--key-url**=<uri>
+[**--token**=<token>] [**--issuer**=<name>] [**--ca-url**=<uri>] [**--root**=<file>]
+
+**step ca certificate** <subject> **--memory**
+[**--token**=<token>] [**--issuer**=<name>] [**--ca-url**=<uri>] [**--root**=<file>]`,
 		Description: `**step ca certificate** command generates a new certificate pair
 
+The certificate and the private key are handled as a unit: if <crt-file> or
+<key-file> exist, the **--force** flag is required to replace them, and both
+files are written, or none of them if there is an error.
+
 ## POSITIONAL ARGUMENTS
 
 <subject>
@@ -48,6 +97,18 @@ are configured (via the --san flag) then the <subject> will be set as the only S
 <key-file>
 :  File to write the private key (PEM format)
 
+## EXIT CODES
+
+With the **--pre-check-expiry-only** flag, this command returns '0' if the
+certificate does not need to be rotated, '1' if the certificate expires within
+the **--rotate-if-expires-in** window, '2' if the certificate file does not
+exist, and '255' for any other error.
+
+With the **--echo-expiry-only** flag, this command returns '0' if the
+certificate is healthy, '1' if the certificate expires within the **--warn**
+window, '2' if the certificate is expired, expires within the **--critical**
+window, or the certificate file does not exist, and '255' for any other error.
+
 ## EXAMPLES
 
 Request a new certificate for a given domain. There are no additional SANs
@@ -67,6 +128,20 @@ IP Address extensions (1.1.1.1, 10.2.3.4) and 1 DNS Name extension (hello.exampl
 $ step ca certificate --san 1.1.1.1 --san hello.example.com --san 10.2.3.4 foobar internal.crt internal.key
 '''
 
+Request a new certificate with Subject Alternative Names of explicit types, the
+command fails if a value does not match the type of the flag:
+'''
+$ step ca certificate --dns hello.example.com --ip 10.2.3.4 \
+	--uri spiffe://example.com/hello foobar internal.crt internal.key
+'''
+
+Request a new certificate on an AWS instance adding the hostname and private IP
+address of the instance, read from the instance metadata, as SANs:
+'''
+$ step ca certificate --san-from-metadata --cloud aws \
+	internal.example.com internal.crt internal.key
+'''
+
 Request a new certificate with a 1h validity:
 '''
 $ TOKEN=$(step ca token internal.example.com)
@@ -89,6 +164,296 @@ $ step ca certificate --offline \
 	internal.example.com internal.crt internal.key
 '''
 
+Request a new certificate using a JWK provisioner without prompting for the
+password used to decrypt the provisioner key:
+'''
+$ step ca certificate --provisioner-password-file ./provisioner-pass.txt \
+	internal.example.com internal.crt internal.key
+'''
+
+Request a new certificate using the first provisioner that can generate a token
+for the subject, the JWK provisioners use the password in the given file:
+'''
+$ step ca certificate --auto-provisioner \
+	--provisioner-password-file ./provisioner-pass.txt \
+	internal.example.com internal.crt internal.key
+'''
+
+Request a new certificate and write a receipt signed by the new private key, the
+receipt is a JWS with the subject, serial number, and fingerprint of the
+certificate, and the time of issuance:
+'''
+$ step ca certificate --receipt-out internal.receipt \
+	internal.example.com internal.crt internal.key
+'''
+
+Request a new certificate and package the certificate, the intermediates, the
+private key, and the root certificate in a compressed tarball:
+'''
+$ step ca certificate --tar-out internal.tar.gz \
+	internal.example.com internal.crt internal.key
+'''
+
+Request a new certificate and keep a copy of the certificate signing request
+sent to the CA:
+'''
+$ step ca certificate --csr-out internal.csr \
+	internal.example.com internal.crt internal.key
+'''
+
+Request a new certificate only if the SANs are allowed by a local policy:
+'''
+$ cat policy.json
+{
+  "allow": {
+    "dns": ["*.corp.example.com"]
+  }
+}
+$ step ca certificate --san-policy-file policy.json \
+	--san www.corp.example.com --san api.corp.example.com \
+	www.corp.example.com www.crt www.key
+'''
+
+Request a new certificate and write the certificate chain, the private key, and
+the root certificate in a CBOR envelope for a constrained device:
+'''
+$ step ca certificate --cbor-out device.cbor \
+	device.example.com device.crt device.key
+'''
+
+Request a new certificate for an existing private key read from STDIN:
+'''
+$ cat internal.key | step ca certificate --key - \
+	internal.example.com internal.crt internal.key
+'''
+
+Check that the CA issues a certificate for the subject without writing any
+file:
+'''
+$ step ca certificate --memory --token $TOKEN internal.example.com
+'''
+
+Request a new certificate and record its serial number in a file with the latest
+serial number of each subject:
+'''
+$ step ca certificate --serial-file serials.json \
+	internal.example.com internal.crt internal.key
+'''
+
+Request a new certificate and send a record of the issuance to the local syslog
+using the local0 facility:
+'''
+$ step ca certificate --syslog --syslog-facility local0 \
+	internal.example.com internal.crt internal.key
+'''
+
+Request a new certificate and export the time spent in each phase to an
+OpenTelemetry collector:
+'''
+$ step ca certificate --otel-endpoint http://localhost:4318 \
+	internal.example.com internal.crt internal.key
+'''
+
+Request a new certificate with a full subject distinguished name, commas in the
+values must be escaped:
+'''
+$ step ca certificate --subject-dn "CN=internal.example.com,O=Acme\, Inc.,C=US" \
+	internal.example.com internal.crt internal.key
+'''
+
+Reissue a certificate carrying forward the custom extensions of the existing
+one, the provisioner template must include the extensions in the certificate
+request:
+'''
+$ step ca certificate --force --preserve-extensions \
+	internal.example.com internal.crt internal.key
+'''
+
+Request a new certificate and record it in the default ledger of issued
+certificates, the next certificates are recorded in it without the flag, and the
+ledger can be verified using **step ca ledger verify**:
+'''
+$ step ca certificate --ledger $(step path)/ledger.json \
+	internal.example.com internal.crt internal.key
+'''
+
+Request a new certificate enabling all the safety checks on the issued
+certificate; the SANs must not be modified by the CA, the chain must verify with
+the root certificate and not outlive its issuer, and the validity must not be
+reduced:
+'''
+$ step ca certificate --strict --not-after 24h \
+	internal.example.com internal.crt internal.key
+'''
+
+Request a new certificate with the issuers first and the leaf certificate at
+the end of the certificate file:
+'''
+$ step ca certificate --chain-order leaf-last internal.example.com internal.crt internal.key
+'''
+
+Request a new certificate only if the subject, SANs, key type, or validity have
+changed since the last run, or if the existing certificate is no longer valid:
+'''
+$ step ca certificate --state-file internal.state --not-after 24h \
+	internal.example.com internal.crt internal.key
+'''
+
+Request a new certificate, waiting for other processes on the host using the
+same lock file to finish first:
+'''
+$ step ca certificate --lock-file /var/lock/step-ca.lock --lock-timeout 5m \
+	internal.example.com internal.crt internal.key
+'''
+
+Request a new certificate only if the existing one expires in less than 8 hours:
+'''
+$ step ca certificate --rotate-if-expires-in 8h internal.example.com internal.crt internal.key
+'''
+
+Check if the existing certificate expires in less than 8 hours, without
+contacting the CA:
+'''
+$ step ca certificate --pre-check-expiry-only --rotate-if-expires-in 8h \
+	internal.example.com internal.crt internal.key
+The certificate internal.crt expires in 15h59m12s.
+'''
+
+Use the existing certificate as a Kubernetes liveness probe, failing with '1'
+if it expires in less than 24 hours, and with '2' if it expires in less than 1
+hour or it is expired:
+'''
+$ step ca certificate --echo-expiry-only --warn 24h --critical 1h \
+	internal.example.com internal.crt
+The certificate internal.crt expires in 15h59m12s.
+'''
+
+Request a new certificate with a custom extension in the certificate request,
+using an object identifier under your own private enterprise number, here the
+example number 32473 of RFC 5612. The CA embeds it if the provisioner template
+copies the certificate request extensions in '.Insecure.CR.Extensions':
+'''
+$ step ca certificate --extra-extension 1.3.6.1.4.1.32473.1:false:ext.der \
+	internal.example.com internal.crt internal.key
+'''
+
+Request a new certificate and store the certificate and the key in a Kubernetes
+secret in the namespace of the pod:
+'''
+$ step ca certificate internal.example.com \
+	--crt-url k8s://default/internal-tls#tls.crt --key-url k8s://default/internal-tls#tls.key
+'''
+
+Request a new certificate and store the certificate and the key in a secret in
+HashiCorp Vault, using the VAULT_ADDR and VAULT_TOKEN environment variables:
+'''
+$ step ca certificate internal.example.com \
+	--crt-url vault://secret/internal#certificate --key-url vault://secret/internal#key
+'''
+
+Request a new certificate failing if the key is weaker than 128 bits of
+security, e.g. an RSA key of less than 3072 bits:
+'''
+$ step ca certificate --min-key-strength 128 --kty RSA --size 2048 \
+	internal.example.com internal.crt internal.key
+'''
+
+Request a new certificate valid for 30 days, failing without writing any file
+if the CA reduces the validity:
+'''
+$ step ca certificate --not-after 720h --on-clamp fail \
+  internal.example.com internal.crt internal.key
+'''
+
+Request a new certificate and write the certificate and the key with Windows
+line endings:
+'''
+$ step ca certificate --line-ending crlf internal.example.com internal.crt internal.key
+'''
+
+Request a new certificate and check that the new key signs data that verifies
+with the public key of the certificate before writing them:
+'''
+$ step ca certificate --verify-key internal.example.com internal.crt internal.key
+'''
+
+Request a new certificate and write the private key twice, unencrypted in
+internal.key and encrypted with the password in pass.txt in internal.enc.key:
+'''
+$ step ca certificate --encrypted-key-file internal.enc.key --encrypted-key-password-file pass.txt \
+  internal.example.com internal.crt internal.key
+'''
+
+Request a new certificate allowing only the ECDSA cipher suites with AES-GCM in
+the connection with the CA:
+'''
+$ step ca certificate internal.example.com internal.crt internal.key \
+  --tls-cipher-suites TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384
+'''
+
+Request a new certificate during a root rotation, writing the chain with the
+cross-signed intermediate if the new chain does not verify with the old root:
+'''
+$ step ca certificate --cross-signed --root old_root_ca.crt \
+  internal.example.com internal.crt internal.key
+'''
+
+Request a new certificate trusting the old and the new roots during a root
+rotation, and write the root that validates the new chain in internal_root.crt:
+'''
+$ cat old_root_ca.crt new_root_ca.crt > roots.crt
+$ step ca certificate --root roots.crt --root-out internal_root.crt \
+  internal.example.com internal.crt internal.key
+'''
+
+Request a new certificate with the subject key identifier computed with the
+RFC 5280 method, using a provisioner with a template that sets the
+**subjectKeyId** field to **{{ toJson .Insecure.User.subjectKeyId }}**:
+'''
+$ step ca certificate --ski sha1-pubkey internal.example.com internal.crt internal.key
+'''
+
+Request a new certificate from the CA in the audiences of a token issued for
+several CAs that contains "ca.eu":
+'''
+$ step ca certificate --token $TOKEN --issuer-hint ca.eu \
+  internal.example.com internal.crt internal.key
+'''
+
+Request a new certificate that does not outlive the token used to request it:
+'''
+$ TOKEN=$(step ca token --not-after 1h internal.example.com)
+$ step ca certificate --token $TOKEN --deadline-from-token \
+  internal.example.com internal.crt internal.key
+'''
+
+Request a new certificate with a SPIFFE ID built from the trust domain and the
+workload name:
+'''
+$ step ca certificate web web.crt web.key \
+  --spiffe-template "spiffe://{{.TrustDomain}}/ns/{{.Env.NAMESPACE}}/{{.Workload}}" \
+  --spiffe-trust-domain example.org --spiffe-workload web
+'''
+
+Request a new certificate in CI, printing nothing on success and all the
+details on failure:
+'''
+$ step ca certificate --quiet-success --verbose-error \
+  --provisioner-password-file /run/secrets/password \
+  internal.example.com internal.crt internal.key
+'''
+
+Show the differences between the certificate that would be issued and an
+existing one, without replacing it:
+'''
+$ step ca certificate --compare internal.crt internal.example.com internal.crt internal.key
+'''
+
+Show the differences with the existing certificate and replace it:
+'''
+$ step ca certificate --compare internal.crt --apply internal.example.com internal.crt internal.key
+'''
+
 Request a new certificate using an OIDC provisioner:
 '''
 $ step ca certificate --token $(step oauth --oidc --bare) joe@example.com joe.crt joe.key
@@ -125,6 +490,12 @@ $ cat path/to/data.json
 $ step ca certificate foo.internal foo.crt foo.key --set-file path/to/data.json
 '''
 
+Request a certificate without the extended key usage extension, the provisioner
+template must use the 'extKeyUsage' variable:
+'''
+$ step ca certificate foo.internal foo.crt foo.key --no-eku
+'''
+
 **step CA ACME** - In order to use the step CA ACME protocol you must add a
 ACME provisioner to the step CA config. See **step ca provisioner add -h**.
 
@@ -149,6 +520,14 @@ step CA does not impose this requirement.
 '''
 $ step ca certificate foo.internal foo.crt foo.key \
 --acme https://acme-staging-v02.api.letsencrypt.org/directory --san bar.internal
+'''
+
+Request a new certificate from an ACME server that requires an external account
+binding (EAB). The HMAC key in the file is base64url encoded:
+'''
+$ step ca certificate foo.internal foo.crt foo.key \
+--acme https://acme.example.com/directory \
+--eab-key-id kid-1 --eab-key-file eab.key
 '''`,
 		Flags: []cli.Flag{
 			cli.StringSliceFlag{
@@ -156,6 +535,53 @@ $ step ca certificate foo.internal foo.crt foo.key \
 				Usage: `Add <dns|ip|email|uri> Subject Alternative Name(s) (SANs)
 that should be authorized. Use the '--san' flag multiple times to configure
 multiple SANs. The '--san' flag and the '--token' flag are mutually exclusive.`,
+			},
+			cli.StringSliceFlag{
+				Name: "dns",
+				Usage: `Add a <dns> name Subject Alternative Name (SAN). Use the '--dns' flag
+multiple times to add multiple DNS names. The SANs are merged with the ones in
+the '--san' flag.`,
+			},
+			cli.StringSliceFlag{
+				Name: "ip",
+				Usage: `Add an <ip> address Subject Alternative Name (SAN). Use the '--ip' flag
+multiple times to add multiple IP addresses. The SANs are merged with the ones
+in the '--san' flag.`,
+			},
+			cli.StringSliceFlag{
+				Name: "email",
+				Usage: `Add an <email> address Subject Alternative Name (SAN). Use the '--email'
+flag multiple times to add multiple email addresses. The SANs are merged with
+the ones in the '--san' flag.`,
+			},
+			cli.StringSliceFlag{
+				Name: "uri",
+				Usage: `Add a <uri> Subject Alternative Name (SAN). Use the '--uri' flag multiple
+times to add multiple URIs. The SANs are merged with the ones in the '--san'
+flag.`,
+			},
+			cli.BoolFlag{
+				Name: "san-from-metadata",
+				Usage: `Add the hostname and the private IP addresses of the instance as Subject
+Alternative Names (SANs). They are read from the metadata endpoint of the cloud
+configured with the **--cloud** flag. The '--san-from-metadata' flag and the
+'--token' flag are mutually exclusive.`,
+			},
+			cli.StringFlag{
+				Name: "cloud",
+				Usage: `The <name> of the cloud where the command runs, used by the
+**--san-from-metadata** flag.
+
+: <name> is a case-insensitive string and must be one of:
+
+    **aws**
+    :  Amazon Web Services
+
+    **gcp**
+    :  Google Cloud Platform
+
+    **azure**
+    :  Microsoft Azure`,
 			},
 			cli.StringFlag{
 				Name:  "attestation-ca-url",
@@ -177,6 +603,21 @@ multiple SANs. The '--san' flag and the '--token' flag are mutually exclusive.`,
 			},
 			flags.TemplateSet,
 			flags.TemplateSetFile,
+			noEKUFlag,
+			strictFlag,
+			strictSANsFlag,
+			requireChainFlag,
+			warnExpiryFatalFlag,
+			onClampFlag,
+			chainOrderFlag,
+			lineEndingFlag,
+			clockSkewFlag,
+			allowedSignatureAlgsFlag,
+			showFlag,
+			preflightFlag,
+			syslogFlag,
+			syslogFacilityFlag,
+			syslogPriorityFlag,
 			flags.CaConfig,
 			flags.CaURL,
 			flags.Root,
@@ -184,9 +625,69 @@ multiple SANs. The '--san' flag and the '--token' flag are mutually exclusive.`,
 			flags.Context,
 			flags.Provisioner,
 			flags.ProvisionerPasswordFile,
+			cli.BoolFlag{
+				Name: "auto-provisioner",
+				Usage: `Use the first provisioner that successfully generates a token for the
+subject instead of prompting for one. The provisioners are tried in the order
+returned by the CA, and the selected one is reported. OIDC and ACME
+provisioners are skipped, and JWK provisioners are only tried if the
+**--provisioner-password-file** flag is used. The '--issuer' flag can be used
+to restrict the provisioners to try.`,
+			},
+			cli.StringFlag{
+				Name: "receipt-out",
+				Usage: `Write a receipt of the issuance to <file>. The receipt is a JWS signed by
+the new private key with the subject, serial number, and SHA-256 fingerprint of
+the certificate, and the time of issuance. The certificate is included in the
+x5c header, and the receipt can be verified with **step ca receipt verify**.`,
+			},
+			cli.StringFlag{
+				Name: "tar-out",
+				Usage: `Write a tarball to <file> with the leaf certificate in cert.pem, the
+intermediate certificates in chain.pem, the private key in key.pem, and the
+root certificate in root.pem. The tarball is compressed with gzip if <file> ends
+with '.tar.gz' or '.tgz'. The root certificate is read from the **--root** flag
+or the default location.`,
+			},
+			cli.StringFlag{
+				Name: "csr-out",
+				Usage: `Write the certificate signing request sent to the CA to <file>. The file is
+written before the request is sent, so it is kept even if the CA rejects it.`,
+			},
+			sanPolicyFileFlag,
+			cli.StringFlag{
+				Name: "cbor-out",
+				Usage: `Write a CBOR envelope to <file> for devices that cannot parse PEM or JSON.
+The envelope is a CBOR map with integer keys: 1 is the DER leaf certificate, 2
+the array of DER intermediate certificates, 3 the PKCS #8 DER private key, 4 the
+array of DER root certificates, and 5 a map with the metadata of the leaf
+certificate: 1 is the subject, 2 the SANs, 3 the serial number, 4 and 5 the not
+before and not after as Unix timestamps, and 6 the SHA-256 fingerprint. The root
+certificate is read from the **--root** flag or the default location.`,
+			},
 			flags.KTY,
 			flags.Curve,
 			flags.Size,
+			cli.StringFlag{
+				Name: "key",
+				Usage: `The private key <file> used in the certificate request instead of generating a
+new one. Use a hyphen ("-") to read the key from STDIN. The '--key' flag is
+incompatible with the '--kty', '--curve', and '--size' flags.`,
+			},
+			cli.StringFlag{
+				Name: "otel-endpoint",
+				Usage: `The OTLP/HTTP <uri> of an OpenTelemetry collector, e.g.
+"http://localhost:4318". If set, the token generation, the key generation, and
+the sign request are exported as spans of a "step ca certificate" trace, using
+the JSON encoding. The path "/v1/traces" is used if the <uri> does not have one.`,
+			},
+			cli.StringFlag{
+				Name: "subject-dn",
+				Usage: `The full distinguished <name> of the subject of the certificate request, in the
+RFC 4514 format, e.g. "CN=foo,O=Acme\, Inc.,C=US". Special characters in the
+values must be escaped with a backslash. The common name must match the
+<subject>, and it is added if the name does not have one.`,
+			},
 			flags.NotAfter,
 			flags.NotBefore,
 			flags.AttestationURI,
@@ -205,47 +706,509 @@ multiple SANs. The '--san' flag and the '--token' flag are mutually exclusive.`,
 			acmeWebrootFlag,
 			acmeContactFlag,
 			acmeHTTPListenFlag,
+			flags.EABKeyID,
+			flags.EABKeyFile,
 			flags.K8sSATokenPathFlag,
+			cli.StringFlag{
+				Name: "spiffe-template",
+				Usage: `Add the SPIFFE ID rendered from the Go text/template <template> as a URI SAN,
+e.g. "spiffe://{{.TrustDomain}}/{{.Workload}}". The variables are .TrustDomain
+and .Workload, from the **--spiffe-trust-domain** and **--spiffe-workload**
+flags, and .Env with the environment variables, e.g. {{.Env.NAMESPACE}}. The
+command fails if the result is not a valid SPIFFE ID.`,
+			},
+			cli.StringFlag{
+				Name:   "spiffe-trust-domain",
+				Usage:  `The trust <domain> used in the **--spiffe-template** flag.`,
+				EnvVar: "STEP_SPIFFE_TRUST_DOMAIN",
+			},
+			cli.StringFlag{
+				Name:   "spiffe-workload",
+				Usage:  `The workload <name> used in the **--spiffe-template** flag.`,
+				EnvVar: "STEP_SPIFFE_WORKLOAD",
+			},
+			cli.StringFlag{
+				Name: "compare",
+				Usage: `Issue the certificate to a temporary location and print the differences in the
+subject, SANs, validity, key type, and issuer with the existing certificate in
+<crt-file>. The new certificate and key are not written unless the **--apply**
+flag is also used.`,
+			},
+			cli.BoolFlag{
+				Name:  "apply",
+				Usage: `Write the new certificate and key after printing the differences. Requires the **--compare** flag.`,
+			},
+			cli.StringFlag{
+				Name: "ledger",
+				Usage: `Append a record of the issued certificate to the ledger <file>. Each record
+contains the serial number, the subject, the time of issuance, and a hash chained
+to the previous record. Use **step ca ledger verify** to verify the chain. If the
+flag is not set and the default ledger, $(step path)/ledger.json, exists, the
+record is appended to it.`,
+			},
+			cli.StringFlag{
+				Name: "state-file",
+				Usage: `Store a hash of the requested subject, SANs, key type, and validity in the
+state <file>. If the hash in the file matches the current request and the
+existing certificate is still valid, the issuance is skipped.`,
+			},
+			cli.StringFlag{
+				Name: "lock-file",
+				Usage: `Acquire an exclusive lock on the lock <file> before requesting the certificate.
+Use the same lock file in different processes to serialize the requests to the
+CA from a host. The lock is released when the command exits. This flag is not
+supported on Windows.`,
+			},
+			cli.DurationFlag{
+				Name: "lock-timeout",
+				Usage: `The maximum <duration> to wait for the lock in the **--lock-file** flag.
+Requires the **--lock-file** flag.`,
+				Value: time.Minute,
+			},
+			cli.DurationFlag{
+				Name: "rotate-if-expires-in",
+				Usage: `Request a new certificate only if the existing certificate in <crt-file> does
+not exist, or if it expires within the given <duration>.`,
+			},
+			cli.BoolFlag{
+				Name: "pre-check-expiry-only",
+				Usage: `Print the time until the existing certificate in <crt-file> expires, and exit
+with a status code indicating whether it expires within the
+**--rotate-if-expires-in** window. The CA is not contacted and no certificate is
+issued. Requires the **--rotate-if-expires-in** flag.`,
+			},
+			cli.BoolFlag{
+				Name: "echo-expiry-only",
+				Usage: `Print the time until the existing certificate in <crt-file> expires, and exit
+with a status code indicating whether it expires within the **--warn** or
+**--critical** windows. The CA is not contacted, no certificate is issued, and
+the <key-file> is not required.`,
+			},
+			cli.DurationFlag{
+				Name: "warn",
+				Usage: `The <duration> before the expiration of the certificate when
+**--echo-expiry-only** exits with '1'.`,
+			},
+			cli.DurationFlag{
+				Name: "critical",
+				Usage: `The <duration> before the expiration of the certificate when
+**--echo-expiry-only** exits with '2'. Expired certificates always exit with '2'.`,
+			},
+			cli.StringSliceFlag{
+				Name: "extra-extension",
+				Usage: `Add a custom extension to the certificate request using the format
+<oid:critical:file>, where <oid> is the object identifier in dotted notation,
+<critical> is true or false, and <file> contains the DER encoded value of the
+extension. The extension is only added to the certificate if the provisioner's
+template includes the extensions in the certificate request. Use the flag
+multiple times to add multiple extensions.`,
+			},
+			cli.BoolFlag{
+				Name: "preserve-extensions",
+				Usage: `Add the non-standard extensions in the existing <crt-file> to the certificate
+request, so they are carried forward when the certificate is reissued. The
+standard X.509 extensions, and the ones added by the CA, are derived from the
+flags and the provisioner. The extensions in the **--extra-extension** flag take
+precedence. The command fails before sending the request if one of the
+extensions is critical. A warning is printed, or an error with **--strict**, if
+the new certificate does not contain the preserved extensions. Use the flag with
+**step ca renew** and **step ca rekey** to renew a certificate.`,
+			},
+			minKeyStrengthFlag,
+			tlsCipherSuitesFlag,
+			maxResponseSizeFlag,
+			rootOutFlag,
+			skiFlag,
+			deadlineFromTokenFlag,
+			issuerHintFlag,
+			verifyTokenFlag,
+			crossSignedFlag,
+			cli.BoolFlag{
+				Name: "verify-key",
+				Usage: `Sign a random nonce with the new private key and verify the signature with the
+public key of the issued certificate before writing them. The command fails if
+the signature does not verify.`,
+			},
+			cli.StringFlag{
+				Name: "encrypted-key-file",
+				Usage: `Write a copy of the private key to <file>, in PKCS #8 format encrypted with the
+password in the **--encrypted-key-password-file** flag. The private key in <key-file>
+is still written unencrypted.`,
+			},
+			cli.StringFlag{
+				Name: "encrypted-key-password-file",
+				Usage: `The path to the <file> containing the password to encrypt the private key
+written by **--encrypted-key-file**.`,
+			},
+			cli.StringFlag{
+				Name: "serial-file",
+				Usage: `Record the serial number of the new certificate in the JSON <file>, keyed by
+the <subject>. The previous serial number of the subject is replaced, and the
+file is created if it does not exist.`,
+			},
+			cli.BoolFlag{
+				Name: "quiet-success",
+				Usage: `Do not print anything if the certificate is issued, the exit status reports the
+result. The output is printed to stderr if the command fails. The prompts are
+not printed either, use the flags that avoid them, e.g. **--token** or
+**--provisioner-password-file**.`,
+			},
+			cli.BoolFlag{
+				Name: "verbose-error",
+				Usage: `Print the resolved configuration and the full chain of errors to stderr if the
+command fails. The value of the **--token** flag is not printed.`,
+			},
+			cli.BoolFlag{
+				Name: "memory",
+				Usage: `Request the certificate and keep it and the private key only in memory. No
+files are written and the certificate is not printed, the exit status reports
+whether the certificate was issued. The positional arguments <crt-file> and <key-file>
+must be omitted.`,
+			},
+			cli.StringFlag{
+				Name: "crt-url",
+				Usage: `The <uri> where the certificate is written instead of <crt-file>. Requires the
+**--key-url** flag, and the positional arguments <crt-file> and <key-file> must
+be omitted. If both URIs are local files, the certificate and the key are
+replaced as a unit, like <crt-file> and <key-file>.
+
+: <uri> must have one of the following formats:
+
+    **file://<path>**
+    :  Write the certificate in the local file <path>
+
+    **vault://<mount>/<path>#<field>**
+    :  Write the certificate in a field of a secret in a Vault KV version 2
+    secrets engine. The server and token are read from the VAULT_ADDR and
+    VAULT_TOKEN environment variables
+
+    **k8s://<namespace>/<secret>#<key>**
+    :  Write the certificate in a key of a Kubernetes secret using the
+    credentials of the service account of the pod`,
+			},
+			cli.StringFlag{
+				Name: "key-url",
+				Usage: `The <uri> where the private key is written instead of <key-file>. It supports
+the same formats as the **--crt-url** flag, and it requires the **--crt-url**
+flag.`,
+			},
 		},
 	}
 }
 
-func certificateAction(ctx *cli.Context) error {
-	if err := errs.MinMaxNumberOfArguments(ctx, 2, 3); err != nil {
-		return err
+func certificateAction(ctx *cli.Context) (err error) {
+	if ctx.Bool("quiet-success") || ctx.Bool("verbose-error") {
+		finish, captureErr := startOutputCapture(ctx)
+		if captureErr != nil {
+			return captureErr
+		}
+		defer func() {
+			err = finish(err)
+		}()
+	}
+
+	// The phases of the issuance are exported as spans if --otel-endpoint is
+	// set, the tracer is nil and does nothing otherwise.
+	endpoint := ctx.String("otel-endpoint")
+	tracer, err := tracing.New(endpoint, "step ca certificate")
+	if err != nil {
+		return errs.InvalidFlagValueMsg(ctx, "otel-endpoint", endpoint, err.Error())
+	}
+	defer func() {
+		if ferr := tracer.Flush(err); ferr != nil {
+			ui.Printf(`{{ "warning:" | yellow }} %s`+"\n", ferr)
+		}
+	}()
+
+	crtURL, keyURL := ctx.String("crt-url"), ctx.String("key-url")
+	switch {
+	case crtURL == "" && keyURL != "":
+		return errs.RequiredWithFlag(ctx, "key-url", "crt-url")
+	case crtURL != "" && keyURL == "":
+		return errs.RequiredWithFlag(ctx, "crt-url", "key-url")
 	}
 
-	// Allow two arguments with the attestation uri.
-	if ctx.NArg() == 2 && ctx.String("attestation-uri") == "" {
-		return errs.TooFewArguments(ctx)
+	mode, err := checkCertificateMode(ctx)
+	if err != nil {
+		return err
+	}
+	switch mode {
+	case storeMode, memoryMode:
+		if err := errs.NumberOfArguments(ctx, 1); err != nil {
+			return err
+		}
+	default:
+		if err := errs.MinMaxNumberOfArguments(ctx, 2, 3); err != nil {
+			return err
+		}
+		// Allow two arguments with the attestation uri, or if only the
+		// expiry of the certificate is checked.
+		if ctx.NArg() == 2 && ctx.String("attestation-uri") == "" && !ctx.Bool("echo-expiry-only") {
+			return errs.TooFewArguments(ctx)
+		}
 	}
 
 	args := ctx.Args()
 	subject := args.Get(0)
 	crtFile, keyFile := args.Get(1), args.Get(2)
 
+	// With --crt-url and --key-url the certificate and key are written to a
+	// temporary directory and copied to the store at the end.
+	if mode == storeMode {
+		for _, u := range []string{crtURL, keyURL} {
+			if _, err := store.Parse(u); err != nil {
+				return err
+			}
+		}
+		// Local files are written as a pair, like the positional arguments.
+		crtPath, crtOK := storeFilePath(crtURL)
+		keyPath, keyOK := storeFilePath(keyURL)
+		if crtOK && keyOK {
+			if err := checkCertificatePair(crtPath, keyPath); err != nil {
+				return err
+			}
+		}
+	}
+
+	if ctx.Bool("echo-expiry-only") {
+		if ctx.Bool("pre-check-expiry-only") {
+			return errs.NewExitError(errs.IncompatibleFlagWithFlag(ctx, "echo-expiry-only", "pre-check-expiry-only"), 255)
+		}
+		warn, critical := ctx.Duration("warn"), ctx.Duration("critical")
+		if critical > warn && ctx.IsSet("warn") {
+			return errs.NewExitError(errors.New("flag '--critical' cannot be greater than flag '--warn'"), 255)
+		}
+		return echoExpiry(ctx, crtFile, warn, critical)
+	}
+	for _, name := range []string{"warn", "critical"} {
+		if ctx.IsSet(name) {
+			return errs.RequiredWithFlag(ctx, name, "echo-expiry-only")
+		}
+	}
+
+	rotateIfExpiresIn := ctx.Duration("rotate-if-expires-in")
+	if ctx.Bool("pre-check-expiry-only") {
+		if !ctx.IsSet("rotate-if-expires-in") {
+			return errs.NewExitError(errs.RequiredWithFlag(ctx, "pre-check-expiry-only", "rotate-if-expires-in"), 255)
+		}
+		return preCheckExpiry(ctx, crtFile, rotateIfExpiresIn)
+	}
+	if ctx.IsSet("rotate-if-expires-in") {
+		if leaf, err := readLeafCertificate(ctx, crtFile); err == nil && time.Until(leaf.NotAfter) > rotateIfExpiresIn {
+			ui.Printf("The certificate %s expires in %s, skipping issuance.\n", crtFile, time.Until(leaf.NotAfter).Round(time.Second))
+			return nil
+		}
+	}
+
 	tok := ctx.String("token")
 	offline := ctx.Bool("offline")
 	sans := ctx.StringSlice("san")
 
+	typedSANs, err := cautils.TypedSANs(ctx)
+	if err != nil {
+		return err
+	}
+	sans = append(sans, typedSANs...)
+
 	// offline and token are incompatible because the token is generated before
 	// the start of the offline CA.
 	if offline && tok != "" {
 		return errs.IncompatibleFlagWithFlag(ctx, "offline", "token")
 	}
 
+	// The provisioner password is only used to generate a new token.
+	if tok != "" && ctx.String("provisioner-password-file") != "" {
+		return errs.IncompatibleFlagWithFlag(ctx, "token", "provisioner-password-file")
+	}
+
+	if ctx.Bool("syslog") {
+		if err := checkSyslog(ctx); err != nil {
+			return err
+		}
+	}
+
+	crlf, err := cautils.ParseLineEnding(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The roots of the bundles are read before requesting the certificate.
+	var tarRoots, cborRoots []*x509.Certificate
+	if ctx.String("tar-out") != "" {
+		if tarRoots, err = readBundleRoots(ctx, "tar-out"); err != nil {
+			return err
+		}
+	}
+	if ctx.String("cbor-out") != "" {
+		if cborRoots, err = readBundleRoots(ctx, "cbor-out"); err != nil {
+			return err
+		}
+	}
+
+	encryptedKeyFile := ctx.String("encrypted-key-file")
+	if encryptedKeyFile != "" {
+		if ctx.String("encrypted-key-password-file") == "" {
+			return errs.RequiredWithFlag(ctx, "encrypted-key-file", "encrypted-key-password-file")
+		}
+		if ctx.String("attestation-uri") != "" {
+			return errs.IncompatibleFlagWithFlag(ctx, "encrypted-key-file", "attestation-uri")
+		}
+	} else if ctx.String("encrypted-key-password-file") != "" {
+		return errs.RequiredWithFlag(ctx, "encrypted-key-password-file", "encrypted-key-file")
+	}
+
+	if keyIn := ctx.String("key"); keyIn != "" {
+		for _, name := range []string{"kty", "curve", "size"} {
+			if ctx.IsSet(name) {
+				return errs.IncompatibleFlagWithFlag(ctx, "key", name)
+			}
+		}
+		if keyIn == "-" {
+			if err := checkSingleStdin(ctx, "'--key'"); err != nil {
+				return err
+			}
+		}
+	}
+
+	if dn := ctx.String("subject-dn"); dn != "" {
+		if _, err := cautils.ParseDistinguishedName(dn); err != nil {
+			return errs.InvalidFlagValueMsg(ctx, "subject-dn", dn, err.Error())
+		}
+	}
+
+	if ctx.Bool("auto-provisioner") {
+		switch {
+		case tok != "":
+			return errs.IncompatibleFlagWithFlag(ctx, "auto-provisioner", "token")
+		case offline:
+			return errs.IncompatibleFlagWithFlag(ctx, "auto-provisioner", "offline")
+		}
+	}
+
+	if ctx.Bool("san-from-metadata") {
+		cloud := ctx.String("cloud")
+		switch {
+		case tok != "":
+			return errs.MutuallyExclusiveFlags(ctx, "token", "san-from-metadata")
+		case cloud == "":
+			return errs.RequiredWithFlag(ctx, "san-from-metadata", "cloud")
+		}
+		metadataSANs, err := cautils.GetMetadataSANs(cloud)
+		if err != nil {
+			return err
+		}
+		sans = append(sans, metadataSANs...)
+	}
+
+	if text := ctx.String("spiffe-template"); text != "" {
+		if tok != "" {
+			return errs.MutuallyExclusiveFlags(ctx, "token", "spiffe-template")
+		}
+		data := cautils.NewSPIFFETemplateData(ctx.String("spiffe-trust-domain"), ctx.String("spiffe-workload"))
+		id, err := cautils.RenderSPIFFETemplate(text, data)
+		if err != nil {
+			return errs.InvalidFlagValueMsg(ctx, "spiffe-template", text, err.Error())
+		}
+		sans = append(sans, id)
+	}
+
+	// Remove the SANs added more than once, with the different flags or with
+	// a different case.
+	var dropped []string
+	sans, dropped = cautils.NormalizeSANs(sans)
+	for _, san := range dropped {
+		ui.Printf(`{{ "warning:" | yellow }} ignoring duplicate SAN {{ %s }}`+"\n", strconv.Quote(san))
+	}
+
+	// Check the SANs before generating the token or contacting the CA. If no
+	// SANs are given, the subject is used as a SAN.
+	if policyFile := ctx.String("san-policy-file"); policyFile != "" {
+		requested := sans
+		if len(requested) == 0 {
+			requested = []string{subject}
+		}
+		if err := checkSANPolicy(policyFile, requested); err != nil {
+			return err
+		}
+	}
+
+	compareFile := ctx.String("compare")
+	if ctx.Bool("apply") && compareFile == "" {
+		return errs.RequiredWithFlag(ctx, "apply", "compare")
+	}
+	var compareCert *x509.Certificate
+	if compareFile != "" {
+		var err error
+		if compareCert, err = readLeafCertificate(ctx, compareFile); err != nil {
+			return err
+		}
+	}
+
+	if lockFile := ctx.String("lock-file"); lockFile != "" {
+		if err := checkLockFileSupported(); err != nil {
+			return err
+		}
+		unlock, err := utils.AcquireLockFile(lockFile, ctx.Duration("lock-timeout"))
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	} else if ctx.IsSet("lock-timeout") {
+		return errs.RequiredWithFlag(ctx, "lock-timeout", "lock-file")
+	}
+
+	stateFile := ctx.String("state-file")
+	var specHash string
+	if stateFile != "" {
+		specHash = certificateSpecHash(ctx, subject, sans)
+		if isCertificateUpToDate(ctx, stateFile, specHash, crtFile, keyFile) {
+			ui.Printf("The certificate %s is up to date, skipping issuance.\n", crtFile)
+			return nil
+		}
+	}
+
+	// The certificate and the key are replaced as a unit, and they are not
+	// written with --compare unless --apply is used.
+	if compareFile == "" || ctx.Bool("apply") {
+		if err := checkCertificatePair(crtFile, keyFile); err != nil {
+			return err
+		}
+	}
+
+	var preserved []pkix.Extension
+	if ctx.Bool("preserve-extensions") {
+		if _, err := os.Stat(crtFile); err == nil {
+			leaf, err := readLeafCertificate(ctx, crtFile)
+			if err != nil {
+				return err
+			}
+			preserved = cautils.NonStandardExtensions(leaf)
+			if err := checkPreservableExtensions(preserved); err != nil {
+				return err
+			}
+		}
+	}
+
 	// certificate flow unifies online and offline flows on a single api
-	flow, err := cautils.NewCertificateFlow(ctx)
+	flow, err := cautils.NewCertificateFlow(ctx, cautils.WithExtensions(preserved), cautils.WithTracer(tracer))
 	if err != nil {
 		return err
 	}
 
 	if tok == "" {
 		// Use the ACME protocol with a different certificate authority.
-		if ctx.IsSet("acme") {
+		if mode == acmeMode {
 			return cautils.ACMECreateCertFlow(ctx, "")
 		}
-		if tok, err = flow.GenerateToken(ctx, subject, sans); err != nil {
+		if ctx.Bool("preflight") && !offline {
+			if err := preflightCheck(ctx); err != nil {
+				return err
+			}
+		}
+		span := tracer.Start("token generation")
+		tok, err = flow.GenerateToken(ctx, subject, sans)
+		span.End(err)
+		if err != nil {
 			var acmeTokenErr *cautils.ACMETokenError
 			if errors.As(err, &acmeTokenErr) {
 				return cautils.ACMECreateCertFlow(ctx, acmeTokenErr.Name)
@@ -254,16 +1217,34 @@ func certificateAction(ctx *cli.Context) error {
 		}
 	}
 
-	req, pk, err := flow.CreateSignRequest(ctx, tok, subject, sans)
+	// The validity of the token is checked before generating the key.
+	jwt, err := token.ParseInsecure(tok)
 	if err != nil {
 		return err
 	}
+	if err := checkTokenTime(ctx, jwt); err != nil {
+		return err
+	}
 
-	jwt, err := token.ParseInsecure(tok)
+	req, pk, err := flow.CreateSignRequest(ctx, tok, subject, sans)
 	if err != nil {
 		return err
 	}
 
+	if minStrength := ctx.Int("min-key-strength"); minStrength > 0 {
+		if err := checkKeyStrength(req.CsrPEM.PublicKey, minStrength); err != nil {
+			return err
+		}
+	}
+
+	// The token can add SANs to the certificate request, so the policy is
+	// checked again with the SANs that are sent to the CA.
+	if policyFile := ctx.String("san-policy-file"); policyFile != "" {
+		if err := checkCSRSANPolicy(policyFile, req.CsrPEM.CertificateRequest); err != nil {
+			return err
+		}
+	}
+
 	switch jwt.Payload.Type() {
 	case token.JWK: // Validate that subject matches the CSR common name.
 		if ctx.String("token") != "" && len(sans) > 0 {
@@ -279,16 +1260,239 @@ func certificateAction(ctx *cli.Context) error {
 		return errors.New("token is not supported")
 	}
 
-	if err := flow.Sign(ctx, tok, req.CsrPEM, crtFile); err != nil {
-		return err
+	out := &certificateOutput{
+		flow:        flow,
+		tok:         tok,
+		req:         req,
+		pk:          pk,
+		subject:     subject,
+		crlf:        crlf,
+		compareCert: compareCert,
+		preserved:   preserved,
+		tarRoots:    tarRoots,
+		cborRoots:   cborRoots,
 	}
+	switch mode {
+	case memoryMode:
+		return out.memory(ctx)
+	case storeMode:
+		return out.store(ctx, crtURL, keyURL)
+	default:
+		return out.files(ctx, crtFile, keyFile, stateFile, specHash)
+	}
+}
 
-	_, err = pemutil.Serialize(pk, pemutil.ToFile(keyFile, 0600))
+// compareAndSign signs the certificate into tmpFile and prints the
+// differences with the given certificate. The new certificate must only be
+// written if the --apply flag is used, in this case, it returns true.
+func compareAndSign(ctx *cli.Context, flow *cautils.CertificateFlow, tok string, req *api.SignRequest, compareCert *x509.Certificate, tmpFile string) (bool, error) {
+	if err := flow.Sign(ctx, tok, req.CsrPEM, tmpFile); err != nil {
+		return false, err
+	}
+	newCert, err := readLeafCertificate(ctx, tmpFile)
+	if err != nil {
+		return false, err
+	}
+
+	printCertificateDiff(os.Stdout, compareCert, newCert)
+	if !ctx.Bool("apply") {
+		ui.Println("The certificate has not been written, use the --apply flag to write it.")
+		return false, nil
+	}
+	return true, nil
+}
+
+// checkPreservableExtensions returns an error if one of the extensions to carry
+// forward cannot be preserved. The critical extensions are rejected, the CA
+// drops the extensions in the request that are not in the provisioner
+// template, and the certificate would have a different meaning without them.
+func checkPreservableExtensions(extensions []pkix.Extension) error {
+	for _, ext := range extensions {
+		if ext.Critical {
+			return errors.Errorf("the extension %s is critical and it cannot be preserved", ext.Id)
+		}
+	}
+	return nil
+}
+
+// checkPreservedExtensions returns an error if certificate does not contain
+// all the given extensions.
+func checkPreservedExtensions(cert *x509.Certificate, preserved []pkix.Extension) error {
+	var missing []string
+	for _, ext := range preserved {
+		found := false
+		for _, e := range cert.Extensions {
+			if e.Id.Equal(ext.Id) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, ext.Id.String())
+		}
+	}
+	if len(missing) > 0 {
+		return errors.Errorf("the CA has not preserved the extensions %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// appendLedger records the leaf certificate in the given crtFile in the ledger.
+func appendLedger(ctx *cli.Context, ledgerFile, crtFile string) error {
+	leaf, err := readLeafCertificate(ctx, crtFile)
 	if err != nil {
 		return err
 	}
+	if _, err := ledger.Append(ledgerFile, leaf); err != nil {
+		return errors.Wrap(err, "error writing ledger")
+	}
+	return nil
+}
+
+// readLeafCertificate reads the leaf certificate from a certificate file
+// written using the order in the --chain-order flag.
+func readLeafCertificate(ctx *cli.Context, filename string) (*x509.Certificate, error) {
+	certs, err := pemutil.ReadCertificateBundle(filename)
+	if err != nil {
+		return nil, err
+	}
+	if ctx.String("chain-order") == "leaf-last" {
+		return certs[len(certs)-1], nil
+	}
+	return certs[0], nil
+}
+
+// certificateSpecHash returns a hash of the properties of the requested
+// certificate used by the --state-file flag.
+func certificateSpecHash(ctx *cli.Context, subject string, sans []string) string {
+	sorted := append([]string{}, sans...)
+	sort.Strings(sorted)
+	spec := []string{
+		"subject=" + subject,
+		"sans=" + strings.Join(sorted, ","),
+		"kty=" + ctx.String("kty"),
+		"curve=" + ctx.String("curve"),
+		"size=" + ctx.String("size"),
+		"not-before=" + ctx.String("not-before"),
+		"not-after=" + ctx.String("not-after"),
+	}
+	sum := sha256.Sum256([]byte(strings.Join(spec, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// isCertificateUpToDate returns true if the hash in the stateFile matches the
+// given one, and if the existing certificate and key are still valid.
+func isCertificateUpToDate(ctx *cli.Context, stateFile, specHash, crtFile, keyFile string) bool {
+	b, err := os.ReadFile(stateFile)
+	if err != nil || strings.TrimSpace(string(b)) != specHash {
+		return false
+	}
+	if keyFile != "" {
+		if _, err := os.Stat(keyFile); err != nil {
+			return false
+		}
+	}
+	leaf, err := readLeafCertificate(ctx, crtFile)
+	if err != nil {
+		return false
+	}
+	now := time.Now()
+	return now.After(leaf.NotBefore) && now.Before(leaf.NotAfter)
+}
 
-	ui.PrintSelected("Certificate", crtFile)
-	ui.PrintSelected("Private Key", keyFile)
+// preCheckExpiry prints the time until the leaf certificate in crtFile expires,
+// and returns an error with exit code 1 if it expires within the given window.
+func preCheckExpiry(ctx *cli.Context, crtFile string, window time.Duration) error {
+	remaining, err := printExpiry(ctx, crtFile)
+	if err != nil {
+		return err
+	}
+	if remaining <= window {
+		return errs.NewExitError(errors.Errorf("certificate %s expires within %s", crtFile, window), 1)
+	}
 	return nil
 }
+
+// echoExpiry prints the time until the certificate in crtFile expires, and
+// returns an error with the exit code 1 if it expires within the warn window,
+// or with the exit code 2 if it is expired or it expires within the critical
+// window.
+func echoExpiry(ctx *cli.Context, crtFile string, warn, critical time.Duration) error {
+	remaining, err := printExpiry(ctx, crtFile)
+	if err != nil {
+		return err
+	}
+	switch {
+	case remaining <= 0:
+		return errs.NewExitError(errors.Errorf("certificate %s is expired", crtFile), 2)
+	case remaining <= critical:
+		return errs.NewExitError(errors.Errorf("certificate %s expires within %s", crtFile, critical), 2)
+	case remaining <= warn:
+		return errs.NewExitError(errors.Errorf("certificate %s expires within %s", crtFile, warn), 1)
+	default:
+		return nil
+	}
+}
+
+// printExpiry prints and returns the time until the certificate in crtFile
+// expires. It returns an error with the exit code 2 if the file does not
+// exist, or with the exit code 255 if it cannot be read.
+func printExpiry(ctx *cli.Context, crtFile string) (time.Duration, error) {
+	if _, err := os.Stat(crtFile); err != nil {
+		if os.IsNotExist(err) {
+			return 0, errs.NewExitError(errs.FileError(err, crtFile), 2)
+		}
+		return 0, errs.NewExitError(errs.FileError(err, crtFile), 255)
+	}
+	leaf, err := readLeafCertificate(ctx, crtFile)
+	if err != nil {
+		return 0, errs.NewExitError(err, 255)
+	}
+
+	remaining := time.Until(leaf.NotAfter).Round(time.Second)
+	if remaining <= 0 {
+		fmt.Printf("The certificate %s expired %s ago.\n", crtFile, -remaining)
+	} else {
+		fmt.Printf("The certificate %s expires in %s.\n", crtFile, remaining)
+	}
+	return remaining, nil
+}
+
+// copyPairToStore writes the contents of the certificate and key files in the
+// stores described by the given URIs. If both URIs are local files, they are
+// written as a pair, so both are replaced or none. Other stores are written
+// one after the other.
+func copyPairToStore(crtFile, crtURL, keyFile, keyURL string) error {
+	crtData, err := os.ReadFile(crtFile)
+	if err != nil {
+		return errs.FileError(err, crtFile)
+	}
+	keyData, err := os.ReadFile(keyFile)
+	if err != nil {
+		return errs.FileError(err, keyFile)
+	}
+
+	crtPath, crtOK := storeFilePath(crtURL)
+	keyPath, keyOK := storeFilePath(keyURL)
+	if crtOK && keyOK {
+		return writeCertificatePair(crtPath, crtData, keyPath, keyData)
+	}
+
+	if err := store.Write(context.Background(), crtURL, crtData, 0600); err != nil {
+		return err
+	}
+	return store.Write(context.Background(), keyURL, keyData, 0600)
+}
+
+// storeFilePath returns the local path of a file URI, and false if the URI is
+// not a file URI.
+func storeFilePath(rawuri string) (string, bool) {
+	u, err := store.Parse(rawuri)
+	if err != nil {
+		return "", false
+	}
+	if filename, ok := store.FilePath(u); ok && filename != "" {
+		return filename, true
+	}
+	return "", false
+}
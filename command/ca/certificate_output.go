@@ -0,0 +1,293 @@
+package ca
+
+import (
+	"crypto"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/certificates/api"
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/cli-utils/ui"
+	"go.step.sm/crypto/pemutil"
+
+	"github.com/smallstep/cli/internal/ledger"
+	"github.com/smallstep/cli/utils"
+	"github.com/smallstep/cli/utils/cautils"
+)
+
+// certificateMode is the way step ca certificate delivers the new certificate.
+// Each mode is named after the flag that selects it.
+type certificateMode string
+
+const (
+	filesMode  certificateMode = ""
+	storeMode  certificateMode = "crt-url"
+	memoryMode certificateMode = "memory"
+	acmeMode   certificateMode = "acme"
+)
+
+// certificateModes are the modes selected by a flag, in order of precedence,
+// with the flags that cannot be used with each mode.
+var certificateModes = []struct {
+	mode         certificateMode
+	incompatible []string
+}{
+	{storeMode, []string{"memory", "acme", "state-file", "rotate-if-expires-in", "pre-check-expiry-only",
+		"echo-expiry-only", "preserve-extensions"}},
+	{memoryMode, []string{"acme", "state-file", "rotate-if-expires-in", "pre-check-expiry-only", "echo-expiry-only",
+		"compare", "ledger", "receipt-out", "tar-out", "cbor-out", "csr-out", "serial-file", "syslog", "lock-file",
+		"verify-key", "encrypted-key-file", "encrypted-key-password-file"}},
+	{acmeMode, []string{"state-file", "subject-dn", "compare", "ledger", "receipt-out", "tar-out", "cbor-out",
+		"csr-out", "serial-file", "preserve-extensions", "verify-key", "encrypted-key-file", "chain-order"}},
+}
+
+// checkCertificateMode returns the mode selected by the flags, and an error if
+// a flag that cannot be used with that mode is set.
+func checkCertificateMode(ctx *cli.Context) (certificateMode, error) {
+	for _, m := range certificateModes {
+		if !ctx.IsSet(string(m.mode)) {
+			continue
+		}
+		for _, name := range m.incompatible {
+			if ctx.IsSet(name) {
+				return "", errs.IncompatibleFlagWithFlag(ctx, string(m.mode), name)
+			}
+		}
+		return m.mode, nil
+	}
+	return filesMode, nil
+}
+
+// certificateOutput is a certificate request ready to be sent to the CA, with
+// the options used to write the certificate.
+type certificateOutput struct {
+	flow        *cautils.CertificateFlow
+	tok         string
+	req         *api.SignRequest
+	pk          crypto.PrivateKey
+	subject     string
+	crlf        bool
+	compareCert *x509.Certificate
+	preserved   []pkix.Extension
+	tarRoots    []*x509.Certificate
+	cborRoots   []*x509.Certificate
+}
+
+// memory requests the certificate and only keeps it in memory, this mode is
+// meant to test the issuance without writing or printing anything.
+func (o *certificateOutput) memory(ctx *cli.Context) error {
+	_, err := o.flow.TLSCertificate(ctx, o.tok, o.req.CsrPEM, o.pk)
+	return err
+}
+
+// store writes the certificate and the key in a temporary directory, and it
+// copies them to the stores in the --crt-url and --key-url flags.
+func (o *certificateOutput) store(ctx *cli.Context, crtURL, keyURL string) error {
+	dir, err := os.MkdirTemp("", "step-ca-certificate")
+	if err != nil {
+		return errors.Wrap(err, "error creating temporary directory")
+	}
+	defer os.RemoveAll(dir)
+	crtFile, keyFile := filepath.Join(dir, "certificate.crt"), filepath.Join(dir, "certificate.key")
+
+	if ok, err := o.write(ctx, crtFile, keyFile); err != nil || !ok {
+		return err
+	}
+	if err := copyPairToStore(crtFile, crtURL, keyFile, keyURL); err != nil {
+		return err
+	}
+
+	printCertificateOutput(ctx, crtURL, keyURL)
+	return nil
+}
+
+// files writes the certificate and the key in the files of the positional
+// arguments, and it updates the --state-file with the given hash.
+func (o *certificateOutput) files(ctx *cli.Context, crtFile, keyFile, stateFile, specHash string) error {
+	if ok, err := o.write(ctx, crtFile, keyFile); err != nil || !ok {
+		return err
+	}
+
+	// The state file is replaced atomically and without asking, an
+	// interrupted write must not leave a partial hash.
+	if stateFile != "" {
+		if err := utils.ReplaceFile(stateFile, []byte(specHash+"\n"), 0600); err != nil {
+			return err
+		}
+	}
+
+	printCertificateOutput(ctx, crtFile, keyFile)
+	return nil
+}
+
+// write signs the certificate, and it writes it with the private key and the
+// other outputs in the flags. It returns false if the certificate is not
+// written because --compare is used without --apply.
+func (o *certificateOutput) write(ctx *cli.Context, crtFile, keyFile string) (bool, error) {
+	// The CSR is written before it is sent to the CA.
+	if csrFile := ctx.String("csr-out"); csrFile != "" {
+		csrData := cautils.FormatLineEnding(pem.EncodeToMemory(&pem.Block{
+			Type:  "CERTIFICATE REQUEST",
+			Bytes: o.req.CsrPEM.Raw,
+		}), o.crlf)
+		if err := utils.WriteFile(csrFile, csrData, 0644); err != nil {
+			return false, errs.FileError(err, csrFile)
+		}
+	}
+
+	// The certificate is signed into a temporary file, and it's written with
+	// the private key at the end, so both files are written or none.
+	dir, err := os.MkdirTemp("", "step-ca-certificate")
+	if err != nil {
+		return false, errors.Wrap(err, "error creating temporary directory")
+	}
+	defer os.RemoveAll(dir)
+	tmpFile := filepath.Join(dir, "certificate.crt")
+
+	if o.compareCert != nil {
+		ok, err := compareAndSign(ctx, o.flow, o.tok, o.req, o.compareCert, tmpFile)
+		if err != nil || !ok {
+			return false, err
+		}
+	} else if err := o.flow.Sign(ctx, o.tok, o.req.CsrPEM, tmpFile); err != nil {
+		return false, err
+	}
+
+	if len(o.preserved) > 0 {
+		leaf, err := readLeafCertificate(ctx, tmpFile)
+		if err != nil {
+			return false, err
+		}
+		if err := checkPreservedExtensions(leaf, o.preserved); err != nil {
+			if ctx.Bool("strict") {
+				return false, err
+			}
+			ui.Printf(`{{ "warning:" | yellow }} %s`+"\n", err)
+		}
+	}
+
+	crtData, err := os.ReadFile(tmpFile)
+	if err != nil {
+		return false, errs.FileError(err, tmpFile)
+	}
+	keyBlock, err := pemutil.Serialize(o.pk)
+	if err != nil {
+		return false, err
+	}
+	keyData := cautils.FormatLineEnding(pem.EncodeToMemory(keyBlock), o.crlf)
+	if ctx.Bool("verify-key") {
+		leaf, err := readLeafCertificate(ctx, tmpFile)
+		if err != nil {
+			return false, err
+		}
+		if err := verifyKeyPair(leaf, keyData); err != nil {
+			return false, err
+		}
+	}
+	var extraFiles []pairFile
+	encryptedKeyFile := ctx.String("encrypted-key-file")
+	if encryptedKeyFile != "" {
+		block, err := pemutil.Serialize(o.pk, pemutil.WithPKCS8(true), pemutil.WithPasswordFile(ctx.String("encrypted-key-password-file")))
+		if err != nil {
+			return false, err
+		}
+		extraFiles = append(extraFiles, pairFile{encryptedKeyFile, cautils.FormatLineEnding(pem.EncodeToMemory(block), o.crlf)})
+	}
+	if err := writeCertificatePair(crtFile, crtData, keyFile, keyData, extraFiles...); err != nil {
+		return false, err
+	}
+	if encryptedKeyFile != "" {
+		ui.Printf(`{{ "warning:" | red }} the private key in %s is NOT encrypted, `+
+			`remove it as soon as it is not needed and use %s instead`+"\n", keyFile, encryptedKeyFile)
+	}
+
+	// Without --ledger, the issuance is recorded in the default ledger if
+	// it has been created.
+	ledgerFile := ctx.String("ledger")
+	if ledgerFile == "" && utils.FileExists(ledger.DefaultPath()) {
+		ledgerFile = ledger.DefaultPath()
+	}
+	if ledgerFile != "" {
+		if err := appendLedger(ctx, ledgerFile, crtFile); err != nil {
+			return false, err
+		}
+	}
+
+	if receiptFile := ctx.String("receipt-out"); receiptFile != "" {
+		leaf, err := readLeafCertificate(ctx, crtFile)
+		if err != nil {
+			return false, err
+		}
+		if err := writeReceipt(receiptFile, leaf, o.pk); err != nil {
+			return false, err
+		}
+	}
+
+	if tarFile := ctx.String("tar-out"); tarFile != "" {
+		if err := writeTarBundle(ctx, tarFile, crtFile, o.pk, o.tarRoots, o.crlf); err != nil {
+			return false, err
+		}
+	}
+
+	if cborFile := ctx.String("cbor-out"); cborFile != "" {
+		if err := writeCBOREnvelope(ctx, cborFile, crtFile, o.pk, o.cborRoots); err != nil {
+			return false, err
+		}
+	}
+
+	if serialFile := ctx.String("serial-file"); serialFile != "" {
+		leaf, err := readLeafCertificate(ctx, crtFile)
+		if err != nil {
+			return false, err
+		}
+		if err := updateSerialFile(serialFile, o.subject, leaf.SerialNumber.String()); err != nil {
+			return false, err
+		}
+	}
+
+	if ctx.Bool("show") {
+		if err := showCertificateChain(crtFile); err != nil {
+			return false, err
+		}
+	}
+
+	if ctx.Bool("syslog") {
+		leaf, err := readLeafCertificate(ctx, crtFile)
+		if err != nil {
+			return false, err
+		}
+		if err := logIssuance(ctx, leaf); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// printCertificateOutput prints the location of the certificate, the key, and
+// the other files written.
+func printCertificateOutput(ctx *cli.Context, crtFile, keyFile string) {
+	ui.PrintSelected("Certificate", crtFile)
+	ui.PrintSelected("Private Key", keyFile)
+	if encryptedKeyFile := ctx.String("encrypted-key-file"); encryptedKeyFile != "" {
+		ui.PrintSelected("Encrypted Private Key", encryptedKeyFile)
+	}
+	if receiptFile := ctx.String("receipt-out"); receiptFile != "" {
+		ui.PrintSelected("Receipt", receiptFile)
+	}
+	if tarFile := ctx.String("tar-out"); tarFile != "" {
+		ui.PrintSelected("Tarball", tarFile)
+	}
+	if cborFile := ctx.String("cbor-out"); cborFile != "" {
+		ui.PrintSelected("CBOR Envelope", cborFile)
+	}
+	if csrFile := ctx.String("csr-out"); csrFile != "" {
+		ui.PrintSelected("Certificate Request", csrFile)
+	}
+}
@@ -0,0 +1,56 @@
+package ca
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+)
+
+func Test_checkCertificateMode(t *testing.T) {
+	newContext := func(t *testing.T, args ...string) *cli.Context {
+		t.Helper()
+		fs := flag.NewFlagSet("contrive", 0)
+		for _, name := range []string{"crt-url", "key-url", "acme", "state-file", "subject-dn", "ledger", "chain-order"} {
+			_ = fs.String(name, "", "")
+		}
+		for _, name := range []string{"memory", "preserve-extensions", "show"} {
+			_ = fs.Bool(name, false, "")
+		}
+		require.NoError(t, fs.Parse(args))
+		return cli.NewContext(&cli.App{}, fs, nil)
+	}
+
+	tests := []struct {
+		name    string
+		ctx     *cli.Context
+		want    certificateMode
+		wantErr string
+	}{
+		{"ok/files", newContext(t, "--ledger", "ledger.json"), filesMode, ""},
+		{"ok/store", newContext(t, "--crt-url", "file:foo.crt", "--key-url", "file:foo.key", "--ledger", "ledger.json"), storeMode, ""},
+		{"ok/memory", newContext(t, "--memory", "--show"), memoryMode, ""},
+		{"ok/acme", newContext(t, "--acme", "https://acme.example.com/directory", "--show"), acmeMode, ""},
+		{"fail/store-memory", newContext(t, "--crt-url", "file:foo.crt", "--memory"), "", "flag '--crt-url' is incompatible with '--memory'"},
+		{"fail/store-acme", newContext(t, "--crt-url", "file:foo.crt", "--acme", "https://acme.example.com/directory"), "", "flag '--crt-url' is incompatible with '--acme'"},
+		{"fail/store-preserve-extensions", newContext(t, "--crt-url", "file:foo.crt", "--preserve-extensions"), "", "flag '--crt-url' is incompatible with '--preserve-extensions'"},
+		{"fail/memory-ledger", newContext(t, "--memory", "--ledger", "ledger.json"), "", "flag '--memory' is incompatible with '--ledger'"},
+		{"fail/memory-acme", newContext(t, "--memory", "--acme", "https://acme.example.com/directory"), "", "flag '--memory' is incompatible with '--acme'"},
+		{"fail/acme-state-file", newContext(t, "--acme", "https://acme.example.com/directory", "--state-file", "state"), "", "flag '--acme' is incompatible with '--state-file'"},
+		{"fail/acme-subject-dn", newContext(t, "--acme", "https://acme.example.com/directory", "--subject-dn", "CN=foo"), "", "flag '--acme' is incompatible with '--subject-dn'"},
+		{"fail/acme-chain-order", newContext(t, "--acme", "https://acme.example.com/directory", "--chain-order", "leaf-last"), "", "flag '--acme' is incompatible with '--chain-order'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := checkCertificateMode(tt.ctx)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package ca
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+	"go.step.sm/crypto/minica"
+)
+
+func mustWriteCertificate(t *testing.T, ca *minica.CA, notAfter time.Time) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	crt, err := ca.Sign(&x509.Certificate{
+		DNSNames:  []string{"foo.internal"},
+		PublicKey: key.Public(),
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter:  notAfter,
+	})
+	require.NoError(t, err)
+	fn := filepath.Join(t.TempDir(), "foo.crt")
+	require.NoError(t, os.WriteFile(fn, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: crt.Raw}), 0600))
+	return fn
+}
+
+func Test_isCertificateUpToDate(t *testing.T) {
+	ca, err := minica.New()
+	require.NoError(t, err)
+
+	fs := flag.NewFlagSet("contrive", 0)
+	_ = fs.String("kty", "EC", "")
+	_ = fs.String("not-after", "24h", "")
+	ctx := cli.NewContext(&cli.App{}, fs, nil)
+
+	hash := certificateSpecHash(ctx, "foo.internal", []string{"foo.internal", "10.0.0.1"})
+	assert.Equal(t, hash, certificateSpecHash(ctx, "foo.internal", []string{"10.0.0.1", "foo.internal"}))
+	assert.NotEqual(t, hash, certificateSpecHash(ctx, "foo.internal", []string{"foo.internal"}))
+
+	stateFile := filepath.Join(t.TempDir(), "state")
+	require.NoError(t, os.WriteFile(stateFile, []byte(hash+"\n"), 0600))
+	keyFile := filepath.Join(t.TempDir(), "foo.key")
+	require.NoError(t, os.WriteFile(keyFile, []byte("key"), 0600))
+
+	valid := mustWriteCertificate(t, ca, time.Now().Add(time.Hour))
+	expired := mustWriteCertificate(t, ca, time.Now().Add(-time.Minute))
+
+	tests := []struct {
+		name      string
+		stateFile string
+		hash      string
+		crtFile   string
+		keyFile   string
+		want      bool
+	}{
+		{"ok", stateFile, hash, valid, keyFile, true},
+		{"ok/no-key-file", stateFile, hash, valid, "", true},
+		{"fail/changed", stateFile, "0000", valid, keyFile, false},
+		{"fail/no-state", filepath.Join(t.TempDir(), "missing"), hash, valid, keyFile, false},
+		{"fail/expired", stateFile, hash, expired, keyFile, false},
+		{"fail/missing-cert", stateFile, hash, filepath.Join(t.TempDir(), "missing.crt"), keyFile, false},
+		{"fail/missing-key", stateFile, hash, valid, filepath.Join(t.TempDir(), "missing.key"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isCertificateUpToDate(ctx, tt.stateFile, tt.hash, tt.crtFile, tt.keyFile))
+		})
+	}
+}
+
+func Test_preCheckExpiry(t *testing.T) {
+	ca, err := minica.New()
+	require.NoError(t, err)
+
+	ctx := cli.NewContext(&cli.App{}, flag.NewFlagSet("contrive", 0), nil)
+	crtFile := mustWriteCertificate(t, ca, time.Now().Add(2*time.Hour))
+
+	exitCode := func(err error) int {
+		var exitErr cli.ExitCoder
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		return 0
+	}
+
+	tests := []struct {
+		name    string
+		crtFile string
+		window  time.Duration
+		want    int
+	}{
+		{"ok", crtFile, time.Hour, 0},
+		{"rotate", crtFile, 3 * time.Hour, 1},
+		{"missing", filepath.Join(t.TempDir(), "missing.crt"), time.Hour, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, exitCode(preCheckExpiry(ctx, tt.crtFile, tt.window)))
+		})
+	}
+}
+
+func Test_echoExpiry(t *testing.T) {
+	ca, err := minica.New()
+	require.NoError(t, err)
+
+	ctx := cli.NewContext(&cli.App{}, flag.NewFlagSet("contrive", 0), nil)
+	crtFile := mustWriteCertificate(t, ca, time.Now().Add(2*time.Hour))
+	expired := mustWriteCertificate(t, ca, time.Now().Add(-time.Minute))
+	invalid := filepath.Join(t.TempDir(), "invalid.crt")
+	require.NoError(t, os.WriteFile(invalid, []byte("not a certificate"), 0600))
+
+	exitCode := func(err error) int {
+		var exitErr cli.ExitCoder
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		return 0
+	}
+
+	tests := []struct {
+		name     string
+		crtFile  string
+		warn     time.Duration
+		critical time.Duration
+		want     int
+	}{
+		{"ok", crtFile, time.Hour, 30 * time.Minute, 0},
+		{"ok/no-thresholds", crtFile, 0, 0, 0},
+		{"warn", crtFile, 3 * time.Hour, time.Hour, 1},
+		{"critical", crtFile, 4 * time.Hour, 3 * time.Hour, 2},
+		{"expired", expired, 0, 0, 2},
+		{"missing", filepath.Join(t.TempDir(), "missing.crt"), time.Hour, 0, 2},
+		{"invalid", invalid, time.Hour, 0, 255},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, exitCode(echoExpiry(ctx, tt.crtFile, tt.warn, tt.critical)))
+		})
+	}
+}
+
+func Test_checkPreservedExtensions(t *testing.T) {
+	foo := pkix.Extension{Id: asn1.ObjectIdentifier{1, 2, 3}}
+	bar := pkix.Extension{Id: asn1.ObjectIdentifier{1, 2, 4}}
+	cert := &x509.Certificate{Extensions: []pkix.Extension{foo}}
+
+	assert.NoError(t, checkPreservedExtensions(cert, nil))
+	assert.NoError(t, checkPreservedExtensions(cert, []pkix.Extension{foo}))
+	assert.ErrorContains(t, checkPreservedExtensions(cert, []pkix.Extension{foo, bar}), "1.2.4")
+}
+
+func Test_checkPreservableExtensions(t *testing.T) {
+	tests := []struct {
+		name       string
+		extensions []pkix.Extension
+		wantErr    bool
+	}{
+		{"ok/empty", nil, false},
+		{"ok", []pkix.Extension{{Id: asn1.ObjectIdentifier{1, 2, 3}, Value: []byte{5, 0}}}, false},
+		{"fail/critical", []pkix.Extension{
+			{Id: asn1.ObjectIdentifier{1, 2, 3}, Value: []byte{5, 0}},
+			{Id: asn1.ObjectIdentifier{1, 2, 4}, Critical: true, Value: []byte{5, 0}},
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPreservableExtensions(tt.extensions)
+			if tt.wantErr {
+				assert.ErrorContains(t, err, "1.2.4")
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package ca
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// certificateDiff is a field that can change between two certificates.
+type certificateDiff struct {
+	Name     string
+	Old, New string
+}
+
+// diffCertificates returns the list of the fields compared by the --compare
+// flag of step ca certificate.
+func diffCertificates(oldCert, newCert *x509.Certificate) []certificateDiff {
+	return []certificateDiff{
+		{"Subject", oldCert.Subject.String(), newCert.Subject.String()},
+		{"SANs", strings.Join(certificateSANs(oldCert), ", "), strings.Join(certificateSANs(newCert), ", ")},
+		{"Not Before", oldCert.NotBefore.UTC().Format(time.RFC3339), newCert.NotBefore.UTC().Format(time.RFC3339)},
+		{"Not After", oldCert.NotAfter.UTC().Format(time.RFC3339), newCert.NotAfter.UTC().Format(time.RFC3339)},
+		{"Key Type", publicKeyType(oldCert.PublicKey), publicKeyType(newCert.PublicKey)},
+		{"Issuer", oldCert.Issuer.String(), newCert.Issuer.String()},
+	}
+}
+
+// printCertificateDiff writes the differences between the two certificates
+// to w.
+func printCertificateDiff(w io.Writer, oldCert, newCert *x509.Certificate) {
+	for _, d := range diffCertificates(oldCert, newCert) {
+		if d.Old == d.New {
+			fmt.Fprintf(w, "  %s: %s\n", d.Name, d.New)
+		} else {
+			fmt.Fprintf(w, "~ %s: %s -> %s\n", d.Name, d.Old, d.New)
+		}
+	}
+}
+
+// certificateSANs returns the string representation of all the SANs in the
+// certificate.
+func certificateSANs(cert *x509.Certificate) []string {
+	sans := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+	for _, u := range cert.URIs {
+		sans = append(sans, u.String())
+	}
+	return sans
+}
+
+// publicKeyType returns a description of the type of the public key, e.g.
+// "EC P-256" or "RSA 2048".
+func publicKeyType(pub crypto.PublicKey) string {
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		return "EC " + k.Curve.Params().Name
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", k.N.BitLen())
+	case ed25519.PublicKey:
+		return "OKP Ed25519"
+	default:
+		return fmt.Sprintf("%T", k)
+	}
+}
@@ -0,0 +1,123 @@
+package ca
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_certificateSANs(t *testing.T) {
+	tests := []struct {
+		name string
+		cert *x509.Certificate
+		want []string
+	}{
+		{"empty", &x509.Certificate{}, []string{}},
+		{"dns", &x509.Certificate{DNSNames: []string{"foo.internal", "bar.internal"}}, []string{"foo.internal", "bar.internal"}},
+		{"all", &x509.Certificate{
+			DNSNames:       []string{"foo.internal"},
+			IPAddresses:    []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("::1")},
+			EmailAddresses: []string{"jane@example.com"},
+			URIs:           []*url.URL{{Scheme: "spiffe", Host: "example.com", Path: "/foo"}},
+		}, []string{"foo.internal", "10.0.0.1", "::1", "jane@example.com", "spiffe://example.com/foo"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, certificateSANs(tt.cert))
+		})
+	}
+}
+
+func Test_publicKeyType(t *testing.T) {
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		pub  crypto.PublicKey
+		want string
+	}{
+		{"ec/P-256", p256.Public(), "EC P-256"},
+		{"ec/P-384", p384.Public(), "EC P-384"},
+		{"rsa/2048", rsaKey.Public(), "RSA 2048"},
+		{"okp/Ed25519", edPub, "OKP Ed25519"},
+		{"unknown", []byte("key"), "[]uint8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, publicKeyType(tt.pub))
+		})
+	}
+}
+
+func Test_printCertificateDiff(t *testing.T) {
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+
+	notBefore := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	oldCert := &x509.Certificate{
+		Subject:   pkix.Name{CommonName: "foo"},
+		Issuer:    pkix.Name{CommonName: "Intermediate CA"},
+		DNSNames:  []string{"foo.internal"},
+		NotBefore: notBefore,
+		NotAfter:  notBefore.Add(24 * time.Hour),
+		PublicKey: p256.Public(),
+	}
+	newCert := &x509.Certificate{
+		Subject:   pkix.Name{CommonName: "foo"},
+		Issuer:    pkix.Name{CommonName: "Intermediate CA"},
+		DNSNames:  []string{"foo.internal", "bar.internal"},
+		NotBefore: notBefore.Add(time.Hour),
+		NotAfter:  notBefore.Add(25 * time.Hour),
+		PublicKey: p384.Public(),
+	}
+
+	tests := []struct {
+		name             string
+		oldCert, newCert *x509.Certificate
+		want             string
+	}{
+		{"same", oldCert, oldCert, `  Subject: CN=foo
+  SANs: foo.internal
+  Not Before: 2024-01-01T00:00:00Z
+  Not After: 2024-01-02T00:00:00Z
+  Key Type: EC P-256
+  Issuer: CN=Intermediate CA
+`},
+		{"changed", oldCert, newCert, `  Subject: CN=foo
+~ SANs: foo.internal -> foo.internal, bar.internal
+~ Not Before: 2024-01-01T00:00:00Z -> 2024-01-01T01:00:00Z
+~ Not After: 2024-01-02T00:00:00Z -> 2024-01-02T01:00:00Z
+~ Key Type: EC P-256 -> EC P-384
+  Issuer: CN=Intermediate CA
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			printCertificateDiff(&buf, tt.oldCert, tt.newCert)
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package ca
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/x509"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/command"
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/cli-utils/ui"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/utils"
+	"github.com/smallstep/cli/utils/cautils"
+)
+
+func crlCommand() cli.Command {
+	return cli.Command{
+		Name:   "crl",
+		Action: command.ActionFunc(crlAction),
+		Usage:  "create a certificate revocation list using the offline CA",
+		UsageText: `**step ca crl** **--out**=<file> **--revoked**=<file>
+[**--crl-validity**=<duration>] [**--ca-config**=<file>] [**--password-file**=<file>]`,
+		Description: `**step ca crl** creates a certificate revocation list (CRL) signed by the
+intermediate key of the CA, without contacting it. The CRL contains the serial
+numbers in the **--revoked** file, and it is written in DER format. If the
+**--out** file has a previous CRL, the new CRL number is the previous one plus
+one, and the serial numbers already in it keep their revocation time. The new
+serial numbers are revoked at the current time.
+
+This command is intended for air-gapped environments with access to the CA
+configuration and keys. The revocations in the database of the CA are not
+included.
+
+The file in the **--revoked** flag contains one serial number per line, in
+decimal, or in hexadecimal with a "0x" prefix or with its bytes separated by
+colons. Empty lines and lines starting with "#" are ignored.
+
+## EXAMPLES
+
+Create a CRL valid for 7 days with the serial numbers in revoked.txt:
+'''
+$ cat revoked.txt
+# revoked on 2024-01-02
+290689953474116234445085153613905437743
+0x5a:8b:0c:1d
+$ step ca crl --ca-config $(step path)/config/ca.json \
+  --revoked revoked.txt --crl-validity 168h --out crl.der
+'''`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "out",
+				Usage: "The <file> to write the CRL in DER format.",
+			},
+			cli.StringFlag{
+				Name:  "revoked",
+				Usage: "The <file> with the serial numbers of the revoked certificates.",
+			},
+			cli.DurationFlag{
+				Name:  "crl-validity",
+				Usage: "The <duration> until the next CRL update.",
+				Value: 24 * time.Hour,
+			},
+			flags.CaConfig,
+			flags.PasswordFile,
+			flags.Force,
+		},
+	}
+}
+
+func crlAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 0); err != nil {
+		return err
+	}
+
+	outFile := ctx.String("out")
+	if outFile == "" {
+		return errs.RequiredFlag(ctx, "out")
+	}
+	revokedFile := ctx.String("revoked")
+	if revokedFile == "" {
+		return errs.RequiredFlag(ctx, "revoked")
+	}
+	validity := ctx.Duration("crl-validity")
+	if validity <= 0 {
+		return errs.InvalidFlagValueMsg(ctx, "crl-validity", validity.String(), "value must be positive")
+	}
+	caConfig := ctx.String("ca-config")
+	if caConfig == "" {
+		return errs.RequiredFlag(ctx, "ca-config")
+	}
+
+	serials, err := readRevokedSerials(revokedFile)
+	if err != nil {
+		return err
+	}
+
+	offlineCA, err := cautils.NewOfflineCA(ctx, caConfig)
+	if err != nil {
+		return err
+	}
+	issuer, signer, err := offlineCA.X509Signer()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	previous, err := readPreviousCRL(outFile)
+	if err != nil {
+		return err
+	}
+	entries := revocationEntries(serials, previous, now)
+	number := nextCRLNumber(previous, now)
+
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		RevokedCertificateEntries: entries,
+		Number:                    number,
+		ThisUpdate:                now,
+		NextUpdate:                now.Add(validity),
+	}, issuer, signer)
+	if err != nil {
+		return errors.Wrap(err, "error creating certificate revocation list")
+	}
+
+	if err := utils.WriteFile(outFile, der, 0644); err != nil {
+		return errs.FileError(err, outFile)
+	}
+
+	ui.Printf("The certificate revocation list with %d entries has been saved in %s.\n", len(entries), outFile)
+	return nil
+}
+
+// readPreviousCRL returns the CRL in filename, or nil if the file does not
+// exist or it does not have a valid CRL.
+func readPreviousCRL(filename string) (*x509.RevocationList, error) {
+	b, err := os.ReadFile(filename)
+	switch {
+	case os.IsNotExist(err):
+		return nil, nil
+	case err != nil:
+		return nil, errs.FileError(err, filename)
+	}
+	crl, err := x509.ParseRevocationList(b)
+	if err != nil {
+		return nil, nil
+	}
+	return crl, nil
+}
+
+// nextCRLNumber returns the number of the new CRL. If there is a previous
+// CRL, it's the number of that CRL plus one, otherwise the current time in
+// nanoseconds is used, so CRLs created in the same second have increasing
+// numbers.
+func nextCRLNumber(previous *x509.RevocationList, now time.Time) *big.Int {
+	if previous == nil || previous.Number == nil {
+		return big.NewInt(now.UnixNano())
+	}
+	return new(big.Int).Add(previous.Number, big.NewInt(1))
+}
+
+// revocationEntries returns the entries of the new CRL. The serial numbers in
+// the previous CRL keep their revocation time, and the new ones are revoked
+// at now.
+func revocationEntries(serials []*big.Int, previous *x509.RevocationList, now time.Time) []x509.RevocationListEntry {
+	revoked := make(map[string]time.Time)
+	if previous != nil {
+		for _, e := range previous.RevokedCertificateEntries {
+			revoked[e.SerialNumber.String()] = e.RevocationTime
+		}
+	}
+
+	entries := make([]x509.RevocationListEntry, len(serials))
+	for i, sn := range serials {
+		t, ok := revoked[sn.String()]
+		if !ok {
+			t = now
+		}
+		entries[i] = x509.RevocationListEntry{
+			SerialNumber:   sn,
+			RevocationTime: t,
+		}
+	}
+	return entries
+}
+
+// readRevokedSerials reads the serial numbers in the given file.
+func readRevokedSerials(filename string) ([]*big.Int, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, errs.FileError(err, filename)
+	}
+	defer f.Close()
+
+	var serials []*big.Int
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sn, err := parseSerialNumber(line)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error parsing %s: line %d", filename, n)
+		}
+		if key := sn.String(); !seen[key] {
+			seen[key] = true
+			serials = append(serials, sn)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errs.FileError(err, filename)
+	}
+	return serials, nil
+}
+
+// parseSerialNumber parses a serial number in decimal, or in hexadecimal with
+// a 0x prefix or with colon separated bytes. Serial numbers must be positive
+// and have at most 20 octets.
+func parseSerialNumber(s string) (*big.Int, error) {
+	var (
+		sn = new(big.Int)
+		ok bool
+	)
+	switch {
+	case strings.HasPrefix(s, "0x"), strings.HasPrefix(s, "0X"):
+		_, ok = sn.SetString(s[2:], 16)
+	case strings.Contains(s, ":"):
+		_, ok = sn.SetString(strings.ReplaceAll(s, ":", ""), 16)
+	default:
+		_, ok = sn.SetString(s, 10)
+	}
+	switch {
+	case !ok:
+		return nil, errors.Errorf("invalid serial number %q", s)
+	case sn.Sign() <= 0:
+		return nil, errors.Errorf("invalid serial number %q: serial number must be positive", s)
+	case sn.BitLen() > 159:
+		return nil, errors.Errorf("invalid serial number %q: serial number must have at most 20 octets", s)
+	}
+	return sn, nil
+}
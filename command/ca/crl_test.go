@@ -0,0 +1,168 @@
+package ca
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/crypto/minica"
+)
+
+func Test_parseSerialNumber(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    *big.Int
+		wantErr bool
+	}{
+		{"ok/decimal", "1234", big.NewInt(1234), false},
+		{"ok/hex", "0x4d2", big.NewInt(1234), false},
+		{"ok/colons", "04:d2", big.NewInt(1234), false},
+		{"fail/zero", "0", nil, true},
+		{"fail/negative", "-1", nil, true},
+		{"fail/invalid", "foo", nil, true},
+		{"fail/too-long", "0x" + strings.Repeat("ff", 20), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSerialNumber(tt.s)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, 0, tt.want.Cmp(got))
+		})
+	}
+}
+
+func Test_readRevokedSerials(t *testing.T) {
+	dir := t.TempDir()
+	ok := filepath.Join(dir, "ok.txt")
+	require.NoError(t, os.WriteFile(ok, []byte("# comment\n1234\n\n0x4d2\n5678\n"), 0600))
+	bad := filepath.Join(dir, "bad.txt")
+	require.NoError(t, os.WriteFile(bad, []byte("1234\nfoo\n"), 0600))
+
+	got, err := readRevokedSerials(ok)
+	require.NoError(t, err)
+	assert.Equal(t, []*big.Int{big.NewInt(1234), big.NewInt(5678)}, got)
+
+	_, err = readRevokedSerials(bad)
+	assert.ErrorContains(t, err, "line 2")
+
+	_, err = readRevokedSerials(filepath.Join(dir, "missing.txt"))
+	assert.Error(t, err)
+}
+
+func Test_readPreviousCRL(t *testing.T) {
+	ca, err := minica.New()
+	require.NoError(t, err)
+	dir := t.TempDir()
+	now := time.Now()
+
+	previous := filepath.Join(dir, "previous.der")
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(41),
+		ThisUpdate: now,
+		NextUpdate: now.Add(time.Hour),
+	}, ca.Intermediate, ca.Signer)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(previous, der, 0600))
+	invalid := filepath.Join(dir, "invalid.der")
+	require.NoError(t, os.WriteFile(invalid, []byte("not a crl"), 0600))
+
+	tests := []struct {
+		name     string
+		filename string
+		want     *big.Int
+	}{
+		{"ok/previous", previous, big.NewInt(41)},
+		{"ok/missing", filepath.Join(dir, "missing.der"), nil},
+		{"ok/invalid", invalid, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readPreviousCRL(tt.filename)
+			require.NoError(t, err)
+			if tt.want == nil {
+				assert.Nil(t, got)
+				return
+			}
+			require.NotNil(t, got)
+			assert.Equal(t, tt.want, got.Number)
+		})
+	}
+}
+
+func Test_nextCRLNumber(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name     string
+		previous *x509.RevocationList
+		want     *big.Int
+	}{
+		{"ok/previous", &x509.RevocationList{Number: big.NewInt(41)}, big.NewInt(42)},
+		{"ok/no-number", &x509.RevocationList{}, big.NewInt(now.UnixNano())},
+		{"ok/no-previous", nil, big.NewInt(now.UnixNano())},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, nextCRLNumber(tt.previous, now))
+		})
+	}
+}
+
+func Test_revocationEntries(t *testing.T) {
+	ca, err := minica.New()
+	require.NoError(t, err)
+	filename := filepath.Join(t.TempDir(), "crl.der")
+
+	// regenerate writes a new CRL with the serials using the previous one.
+	regenerate := func(t *testing.T, now time.Time, serials ...int64) []x509.RevocationListEntry {
+		t.Helper()
+		sns := make([]*big.Int, len(serials))
+		for i, sn := range serials {
+			sns[i] = big.NewInt(sn)
+		}
+		previous, err := readPreviousCRL(filename)
+		require.NoError(t, err)
+		der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+			RevokedCertificateEntries: revocationEntries(sns, previous, now),
+			Number:                    nextCRLNumber(previous, now),
+			ThisUpdate:                now,
+			NextUpdate:                now.Add(time.Hour),
+		}, ca.Intermediate, ca.Signer)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filename, der, 0600))
+		crl, err := x509.ParseRevocationList(der)
+		require.NoError(t, err)
+		return crl.RevokedCertificateEntries
+	}
+
+	first := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	entries := regenerate(t, first, 1)
+	require.Len(t, entries, 1)
+	assert.Equal(t, first, entries[0].RevocationTime)
+
+	// The previous entry keeps its revocation time.
+	second := first.Add(24 * time.Hour)
+	entries = regenerate(t, second, 1, 2)
+	require.Len(t, entries, 2)
+	assert.Equal(t, big.NewInt(1), entries[0].SerialNumber)
+	assert.Equal(t, first, entries[0].RevocationTime)
+	assert.Equal(t, big.NewInt(2), entries[1].SerialNumber)
+	assert.Equal(t, second, entries[1].RevocationTime)
+
+	// Serials removed from the revoked file are not kept.
+	entries = regenerate(t, second.Add(time.Hour), 2)
+	require.Len(t, entries, 1)
+	assert.Equal(t, big.NewInt(2), entries[0].SerialNumber)
+	assert.Equal(t, second, entries[0].RevocationTime)
+}
@@ -554,16 +554,19 @@ func initAction(ctx *cli.Context) (err error) {
 			if ctxProfile == "" {
 				ctxProfile = ctxName
 			}
-			if err := step.Contexts().Add(&step.Context{
+			unlock, err := utils.LockStepPath()
+			if err != nil {
+				return err
+			}
+			err = utils.SaveContext(&step.Context{
 				Name:      ctxName,
 				Profile:   ctxProfile,
 				Authority: ctxAuthority,
-			}); err != nil {
+			})
+			unlock()
+			if err != nil {
 				return err
 			}
-			if err := step.Contexts().SaveCurrent(ctxName); err != nil {
-				return errors.Wrap(err, "error storing new default context")
-			}
 			if err := step.Contexts().SetCurrent(ctxName); err != nil {
 				return errors.Wrap(err, "error setting context '%s'")
 			}
@@ -0,0 +1,219 @@
+package ca
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/command"
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/cli-utils/ui"
+	"go.step.sm/crypto/jose"
+
+	"github.com/smallstep/cli/token"
+	"github.com/smallstep/cli/utils"
+)
+
+func inspectTokenCommand() cli.Command {
+	return cli.Command{
+		Name:   "inspect-token",
+		Action: command.ActionFunc(inspectTokenAction),
+		Usage:  "decode and show a one-time token",
+		UsageText: `**step ca inspect-token** [<token>]
+[**--verify**] [**--jwks**=<file>] [**--clock-skew**=<duration>]`,
+		Description: `**step ca inspect-token** decodes a one-time token and prints its header and
+payload as JSON. The token is read from STDIN if it is not given.
+
+The token is always decoded, even if it is expired or its signature is not
+valid, because those are often the tokens that need to be inspected. The
+output labels the token as expired, not yet valid, or unverified instead of
+failing. The validity is checked with the tolerance in the **--clock-skew** flag,
+like **step ca certificate** and **step ca sign** do.
+
+With the **--verify** flag, the signature is checked with the key in the
+**--jwks** file that matches the "kid" header of the token. The token is
+printed in any case, but the command fails if the signature is not valid.
+
+## POSITIONAL ARGUMENTS
+
+<token>
+:  The token to inspect. Use a hyphen ("-") or omit it to read it from STDIN.
+
+## EXAMPLES
+
+Inspect a token:
+'''
+$ step ca inspect-token $(step ca token internal.example.com)
+'''
+
+Inspect a token read from STDIN:
+'''
+$ cat token.txt | step ca inspect-token
+'''
+
+Inspect a token and verify its signature with the public keys of the
+provisioners:
+'''
+$ step ca inspect-token --verify --jwks jwks.json $TOKEN
+'''`,
+		Flags: []cli.Flag{
+			cli.BoolFlag{
+				Name:  "verify",
+				Usage: `Verify the signature of the token with the key in the **--jwks** flag.`,
+			},
+			cli.StringFlag{
+				Name: "jwks",
+				Usage: `The JWK Set <file> with the key used to verify the token. The key is selected
+using the "kid" header of the token. Requires the **--verify** flag.`,
+			},
+			clockSkewFlag,
+		},
+	}
+}
+
+// inspectedToken is the representation of a token printed by
+// step ca inspect-token.
+type inspectedToken struct {
+	Header      json.RawMessage `json:"header"`
+	Payload     json.RawMessage `json:"payload"`
+	Signature   string          `json:"signature"`
+	Type        string          `json:"type"`
+	Expired     bool            `json:"expired"`
+	NotYetValid bool            `json:"notYetValid"`
+	Verified    bool            `json:"verified"`
+}
+
+func inspectTokenAction(ctx *cli.Context) error {
+	if err := errs.MinMaxNumberOfArguments(ctx, 0, 1); err != nil {
+		return err
+	}
+
+	verify, jwksFile := ctx.Bool("verify"), ctx.String("jwks")
+	switch {
+	case verify && jwksFile == "":
+		return errs.RequiredWithFlag(ctx, "verify", "jwks")
+	case !verify && jwksFile != "":
+		return errs.RequiredWithFlag(ctx, "jwks", "verify")
+	}
+
+	tok := ctx.Args().Get(0)
+	if tok == "" || tok == "-" {
+		s, err := utils.ReadString(os.Stdin)
+		if err != nil {
+			return err
+		}
+		tok = s
+	}
+
+	out, jwt, err := inspectToken(strings.TrimSpace(tok), time.Now(), ctx.Duration("clock-skew"))
+	if err != nil {
+		return err
+	}
+
+	var verifyErr error
+	if verify {
+		if verifyErr = verifyTokenSignature(jwt, jwksFile); verifyErr == nil {
+			out.Verified = true
+		}
+	}
+
+	b, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "error marshaling token")
+	}
+	fmt.Println(string(b))
+
+	switch {
+	case out.Expired:
+		ui.Printf(`{{ "warning:" | yellow }} the token expired at %s`+"\n", jwt.Payload.Expiry.Time().UTC().Format(time.RFC3339))
+	case out.NotYetValid:
+		ui.Printf(`{{ "warning:" | yellow }} the token is not valid before %s`+"\n", jwt.Payload.NotBefore.Time().UTC().Format(time.RFC3339))
+	}
+	if !verify {
+		ui.Printf(`{{ "warning:" | yellow }} the signature of the token has not been verified` + "\n")
+	}
+	return verifyErr
+}
+
+// inspectToken decodes the token without verifying it, and labels it as
+// expired or not yet valid at the given time, allowing the given clock skew.
+func inspectToken(tok string, now time.Time, skew time.Duration) (*inspectedToken, *token.JSONWebToken, error) {
+	jwt, err := token.ParseInsecure(tok)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	parts := strings.Split(tok, ".")
+	if len(parts) != 3 {
+		return nil, nil, errors.New("error decoding token: token must have three parts")
+	}
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error decoding token header")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error decoding token payload")
+	}
+
+	out := &inspectedToken{
+		Header:    header,
+		Payload:   payload,
+		Signature: parts[2],
+		Type:      tokenTypeName(jwt.Payload.Type()),
+	}
+	if exp := jwt.Payload.Expiry; exp != nil && now.Add(-skew).After(exp.Time()) {
+		out.Expired = true
+	}
+	if nbf := jwt.Payload.NotBefore; nbf != nil && now.Add(skew).Before(nbf.Time()) {
+		out.NotYetValid = true
+	}
+	return out, jwt, nil
+}
+
+// verifyTokenSignature verifies the signature of the token with the key in
+// the JWK Set file with the kid of the token.
+func verifyTokenSignature(jwt *token.JSONWebToken, jwksFile string) error {
+	if len(jwt.Headers) == 0 || jwt.Headers[0].KeyID == "" {
+		return errors.New("error verifying token: token does not have a kid header")
+	}
+	kid := jwt.Headers[0].KeyID
+	jwk, err := jose.ReadKeySet(jwksFile, jose.WithKid(kid))
+	if err != nil {
+		return err
+	}
+	var claims jose.Claims
+	if err := jwt.Claims(jwk.Public(), &claims); err != nil {
+		return errors.Wrapf(jose.TrimPrefix(err), "error verifying token with the key %s", kid)
+	}
+	return nil
+}
+
+func tokenTypeName(typ token.Type) string {
+	switch typ {
+	case token.JWK:
+		return "JWK"
+	case token.X5C:
+		return "X5C"
+	case token.OIDC:
+		return "OIDC"
+	case token.GCP:
+		return "GCP"
+	case token.AWS:
+		return "AWS"
+	case token.Azure:
+		return "Azure"
+	case token.K8sSA:
+		return "K8sSA"
+	case token.Nebula:
+		return "Nebula"
+	default:
+		return "Unknown"
+	}
+}
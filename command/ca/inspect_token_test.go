@@ -0,0 +1,97 @@
+package ca
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/crypto/jose"
+)
+
+func Test_inspectToken(t *testing.T) {
+	signer := func(t *testing.T, kid string) (*jose.JSONWebKey, jose.Signer) {
+		t.Helper()
+		jwk, err := jose.GenerateJWK("EC", "P-256", "ES256", "sig", kid, 0)
+		require.NoError(t, err)
+		s, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: jwk.Key},
+			new(jose.SignerOptions).WithType("JWT").WithHeader("kid", kid))
+		require.NoError(t, err)
+		return jwk, s
+	}
+	sign := func(t *testing.T, s jose.Signer, now time.Time) string {
+		t.Helper()
+		tok, err := jose.Signed(s).Claims(map[string]any{
+			"sub":  "foo.internal",
+			"sans": []string{"foo.internal"},
+			"nbf":  now.Unix(),
+			"exp":  now.Add(5 * time.Minute).Unix(),
+		}).CompactSerialize()
+		require.NoError(t, err)
+		return tok
+	}
+
+	jwk, s := signer(t, "the-kid")
+	_, other := signer(t, "the-kid")
+
+	pub := jwk.Public()
+	b, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{pub}})
+	require.NoError(t, err)
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(jwksFile, b, 0600))
+
+	now := time.Now()
+	tok := sign(t, s, now)
+
+	t.Run("ok", func(t *testing.T) {
+		out, jwt, err := inspectToken(tok, now, time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, "JWK", out.Type)
+		assert.False(t, out.Expired)
+		assert.False(t, out.NotYetValid)
+		assert.False(t, out.Verified)
+		assert.Contains(t, string(out.Payload), `"sub":"foo.internal"`)
+		assert.Contains(t, string(out.Header), `"kid":"the-kid"`)
+		assert.NoError(t, verifyTokenSignature(jwt, jwksFile))
+	})
+
+	t.Run("ok/expired", func(t *testing.T) {
+		out, jwt, err := inspectToken(tok, now.Add(time.Hour), time.Minute)
+		require.NoError(t, err)
+		assert.True(t, out.Expired)
+		assert.NoError(t, verifyTokenSignature(jwt, jwksFile))
+	})
+
+	t.Run("ok/not-yet-valid", func(t *testing.T) {
+		out, _, err := inspectToken(tok, now.Add(-time.Hour), time.Minute)
+		require.NoError(t, err)
+		assert.True(t, out.NotYetValid)
+	})
+
+	t.Run("ok/clock-skew", func(t *testing.T) {
+		out, _, err := inspectToken(tok, now.Add(6*time.Minute), 2*time.Minute)
+		require.NoError(t, err)
+		assert.False(t, out.Expired)
+		out, _, err = inspectToken(tok, now.Add(-90*time.Second), 2*time.Minute)
+		require.NoError(t, err)
+		assert.False(t, out.NotYetValid)
+		out, _, err = inspectToken(tok, now.Add(6*time.Minute), 0)
+		require.NoError(t, err)
+		assert.True(t, out.Expired)
+	})
+
+	t.Run("ok/invalid-signature", func(t *testing.T) {
+		out, jwt, err := inspectToken(sign(t, other, now), now, time.Minute)
+		require.NoError(t, err)
+		assert.False(t, out.Expired)
+		assert.Error(t, verifyTokenSignature(jwt, jwksFile))
+	})
+
+	t.Run("fail/malformed", func(t *testing.T) {
+		_, _, err := inspectToken("not-a-token", now, time.Minute)
+		assert.Error(t, err)
+	})
+}
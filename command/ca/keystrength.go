@@ -0,0 +1,54 @@
+package ca
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+
+	"github.com/pkg/errors"
+)
+
+// keyStrength returns the security strength in bits of the given public key,
+// as defined by NIST SP 800-57 Part 1.
+func keyStrength(pub crypto.PublicKey) (int, error) {
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		// The strength of an elliptic curve key is half of the size of the
+		// curve order: 128 for P-256, 192 for P-384, and 256 for P-521.
+		return k.Curve.Params().N.BitLen() / 2, nil
+	case ed25519.PublicKey:
+		return 128, nil
+	case *rsa.PublicKey:
+		switch size := k.N.BitLen(); {
+		case size >= 15360:
+			return 256, nil
+		case size >= 7680:
+			return 192, nil
+		case size >= 3072:
+			return 128, nil
+		case size >= 2048:
+			return 112, nil
+		case size >= 1024:
+			return 80, nil
+		default:
+			return 0, nil
+		}
+	default:
+		return 0, errors.Errorf("unsupported public key type %T", pub)
+	}
+}
+
+// checkKeyStrength returns an error if the security strength of the given
+// public key is lower than minStrength bits.
+func checkKeyStrength(pub crypto.PublicKey, minStrength int) error {
+	strength, err := keyStrength(pub)
+	if err != nil {
+		return err
+	}
+	if strength < minStrength {
+		return errors.Errorf("the %s key has a security strength of %d bits, the minimum required is %d bits",
+			publicKeyType(pub), strength, minStrength)
+	}
+	return nil
+}
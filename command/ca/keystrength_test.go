@@ -0,0 +1,52 @@
+package ca
+
+import (
+	"crypto"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/crypto/keyutil"
+)
+
+func Test_checkKeyStrength(t *testing.T) {
+	mustPublicKey := func(t *testing.T, kty, crv string, size int) crypto.PublicKey {
+		t.Helper()
+		pub, _, err := keyutil.GenerateKeyPair(kty, crv, size)
+		require.NoError(t, err)
+		return pub
+	}
+
+	p256 := mustPublicKey(t, "EC", "P-256", 0)
+	p384 := mustPublicKey(t, "EC", "P-384", 0)
+	ed25519 := mustPublicKey(t, "OKP", "Ed25519", 0)
+	rsa2048 := mustPublicKey(t, "RSA", "", 2048)
+	rsa3072 := mustPublicKey(t, "RSA", "", 3072)
+
+	tests := []struct {
+		name        string
+		pub         crypto.PublicKey
+		minStrength int
+		wantErr     bool
+	}{
+		{"ok/P-256", p256, 128, false},
+		{"ok/P-384", p384, 192, false},
+		{"ok/Ed25519", ed25519, 128, false},
+		{"ok/RSA 2048", rsa2048, 112, false},
+		{"ok/RSA 3072", rsa3072, 128, false},
+		{"fail/P-256", p256, 192, true},
+		{"fail/Ed25519", ed25519, 192, true},
+		{"fail/RSA 2048", rsa2048, 128, true},
+		{"fail/unsupported", "foo", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkKeyStrength(tt.pub, tt.minStrength)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package ca
+
+import (
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/command"
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/cli-utils/ui"
+
+	"github.com/smallstep/cli/internal/ledger"
+)
+
+func ledgerCommand() cli.Command {
+	return cli.Command{
+		Name:      "ledger",
+		Usage:     "manage the local ledger of issued certificates",
+		UsageText: "**step ca ledger** <subcommand> [arguments] [global-flags] [subcommand-flags]",
+		Description: `**step ca ledger** command group provides facilities to manage the local
+ledger of issued certificates written by **step ca certificate --ledger**.
+
+The ledger is an append-only file with one JSON entry per issued certificate.
+Each entry contains the serial number, the subject, the time of issuance, and
+a hash chained to the previous entry, so any modification of the file can be
+detected.
+
+The default ledger is $(step path)/ledger.json. Once it exists, **step ca
+certificate** records every issuance in it, even without the **--ledger** flag.
+
+## EXAMPLES
+
+Verify the ledger in the default location:
+'''
+$ step ca ledger verify
+'''`,
+		Subcommands: cli.Commands{
+			ledgerVerifyCommand(),
+		},
+	}
+}
+
+func ledgerVerifyCommand() cli.Command {
+	return cli.Command{
+		Name:      "verify",
+		Action:    command.ActionFunc(ledgerVerifyAction),
+		Usage:     "verify the hash chain of the local ledger of issued certificates",
+		UsageText: `**step ca ledger verify** [<ledger-file>]`,
+		Description: `**step ca ledger verify** checks that every entry in the ledger has a valid
+hash and that it is chained to the previous entry. The command fails on the
+first entry that does not verify.
+
+## POSITIONAL ARGUMENTS
+
+<ledger-file>
+:  The ledger file to verify. Defaults to $(step path)/ledger.json.
+
+## EXAMPLES
+
+Verify the ledger in the default location:
+'''
+$ step ca ledger verify
+The ledger /home/user/.step/ledger.json is valid: 12 entries.
+'''
+
+Verify a ledger in a custom location:
+'''
+$ step ca ledger verify /var/lib/step/ledger.json
+'''`,
+	}
+}
+
+func ledgerVerifyAction(ctx *cli.Context) error {
+	if err := errs.MinMaxNumberOfArguments(ctx, 0, 1); err != nil {
+		return err
+	}
+
+	filename := ctx.Args().Get(0)
+	if filename == "" {
+		filename = ledger.DefaultPath()
+	}
+
+	entries, err := ledger.Verify(filename)
+	if err != nil {
+		return err
+	}
+
+	ui.Printf("The ledger %s is valid: %d entries.\n", filename, len(entries))
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+// +build !windows
+
+package ca
+
+func checkLockFileSupported() error {
+	return nil
+}
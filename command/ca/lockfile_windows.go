@@ -0,0 +1,13 @@
+package ca
+
+import (
+	"runtime"
+
+	"github.com/pkg/errors"
+)
+
+// checkLockFileSupported returns an error on Windows, file locks are not
+// implemented and the lock would not serialize the requests.
+func checkLockFileSupported() error {
+	return errors.Errorf("flag '--lock-file' is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,170 @@
+package ca
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/smallstep/cli-utils/command"
+	"github.com/smallstep/cli-utils/errs"
+
+	"github.com/smallstep/cli/utils"
+)
+
+// checkCertificatePair returns an error if the certificate or the private key
+// exist and the --force flag is not used. The certificate and the key are
+// considered a unit, if one of them exists the pair is present.
+func checkCertificatePair(crtFile, keyFile string) error {
+	if command.IsForce() {
+		return nil
+	}
+	for _, name := range []string{crtFile, keyFile} {
+		if name != "" && utils.FileExists(name) {
+			return errors.Errorf("the certificate %s or the private key %s already exist, use the '--force' flag to replace them", crtFile, keyFile)
+		}
+	}
+	return nil
+}
+
+// pairFile is a file written as part of a certificate pair.
+type pairFile struct {
+	name string
+	data []byte
+}
+
+// writeCertificatePair writes the certificate and the private key. The data is
+// first written to temporary files in the same directories, and then renamed,
+// if the key cannot be renamed the previous certificate is restored. If
+// keyFile is empty only the certificate is written. The extra files, like an
+// encrypted copy of the key, are written as part of the same unit.
+func writeCertificatePair(crtFile string, crtData []byte, keyFile string, keyData []byte, extra ...pairFile) error {
+	files := []pairFile{{crtFile, crtData}}
+	if keyFile != "" {
+		files = append(files, pairFile{keyFile, keyData})
+	}
+	return writePairFiles(append(files, extra...))
+}
+
+// writePairFiles writes the files as a unit. All the files are written to
+// temporary files before any of them is renamed, and if one cannot be renamed
+// the files already renamed are restored.
+func writePairFiles(files []pairFile) error {
+	tmps := make([]string, len(files))
+	for i, f := range files {
+		tmp, err := writeTempFile(f.name, f.data)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp)
+		tmps[i] = tmp
+	}
+
+	// The previous content of the renamed files, nil if they did not exist.
+	olds := make([][]byte, 0, len(files))
+	restore := func(err error) error {
+		var failed []string
+		for i, old := range olds {
+			var rerr error
+			if old != nil {
+				rerr = restoreFile(files[i].name, old)
+			} else {
+				rerr = os.Remove(files[i].name)
+			}
+			if rerr != nil {
+				failed = append(failed, rerr.Error())
+			}
+		}
+		if len(failed) > 0 {
+			return errors.Errorf("%v; error restoring the previous files: %s", err, strings.Join(failed, "; "))
+		}
+		return err
+	}
+	names := make([]string, len(files))
+	for i, f := range files {
+		old, err := os.ReadFile(f.name)
+		if err != nil && !os.IsNotExist(err) {
+			return restore(errs.FileError(err, f.name))
+		}
+		if err := os.Rename(tmps[i], f.name); err != nil {
+			return restore(errs.FileError(err, f.name))
+		}
+		olds = append(olds, old)
+		names[i] = f.name
+	}
+	return syncDirs(names...)
+}
+
+// restoreFile replaces filename with its previous content, keeping the mode
+// and the owner of the file.
+func restoreFile(filename string, data []byte) error {
+	tmp, err := writeTempFile(filename, data)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		os.Remove(tmp)
+		return errs.FileError(err, filename)
+	}
+	return nil
+}
+
+// syncDirs flushes the directories of the given files to disk, so the renames
+// are durable.
+func syncDirs(filenames ...string) error {
+	seen := make(map[string]bool)
+	for _, name := range filenames {
+		dir := filepath.Dir(name)
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		if err := utils.SyncDir(dir); err != nil {
+			return errs.FileError(err, dir)
+		}
+	}
+	return nil
+}
+
+// writeTempFile writes the data in a new temporary file in the directory of
+// filename and returns its name. If filename exists, the temporary file has
+// its mode and its owner, so they are kept when it is renamed.
+func writeTempFile(filename string, data []byte) (string, error) {
+	f, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*")
+	if err != nil {
+		return "", errs.FileError(err, filename)
+	}
+	if st, err := os.Stat(filename); err == nil && st.Mode().IsRegular() {
+		if err := copyFileMode(f, st); err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return "", errs.FileError(err, filename)
+		}
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", errs.FileError(err, filename)
+	}
+	if err := utils.SyncFile(f); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return "", errs.FileError(err, filename)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return "", errs.FileError(err, filename)
+	}
+	return f.Name(), nil
+}
+
+// copyFileMode sets the mode and the owner of fi to f. The mode is set first,
+// a file owned by another user cannot be changed after the chown.
+func copyFileMode(f *os.File, fi os.FileInfo) error {
+	if err := f.Chmod(fi.Mode().Perm()); err != nil && runtime.GOOS != "windows" {
+		return err
+	}
+	return chownFile(f, fi)
+}
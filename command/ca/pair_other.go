@@ -0,0 +1,12 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package ca
+
+import "os"
+
+// chownFile is a no-op on Windows and Plan 9, the files do not have a numeric
+// owner and group.
+func chownFile(*os.File, os.FileInfo) error {
+	return nil
+}
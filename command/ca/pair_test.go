@@ -0,0 +1,95 @@
+package ca
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_checkCertificatePair(t *testing.T) {
+	dir := t.TempDir()
+	crtFile := filepath.Join(dir, "foo.crt")
+	keyFile := filepath.Join(dir, "foo.key")
+
+	assert.NoError(t, checkCertificatePair(crtFile, keyFile))
+
+	require.NoError(t, os.WriteFile(keyFile, []byte("key"), 0600))
+	assert.Error(t, checkCertificatePair(crtFile, keyFile))
+
+	require.NoError(t, os.Remove(keyFile))
+	require.NoError(t, os.WriteFile(crtFile, []byte("crt"), 0600))
+	assert.Error(t, checkCertificatePair(crtFile, keyFile))
+}
+
+func Test_writeCertificatePair(t *testing.T) {
+	dir := t.TempDir()
+	crtFile := filepath.Join(dir, "foo.crt")
+	keyFile := filepath.Join(dir, "foo.key")
+
+	require.NoError(t, writeCertificatePair(crtFile, []byte("crt"), keyFile, []byte("key")))
+	assertFile := func(t *testing.T, filename, want string) {
+		t.Helper()
+		b, err := os.ReadFile(filename)
+		require.NoError(t, err)
+		assert.Equal(t, want, string(b))
+	}
+	assertFile(t, crtFile, "crt")
+	assertFile(t, keyFile, "key")
+
+	// The key cannot be renamed, the previous certificate is restored.
+	badKeyFile := filepath.Join(dir, "bad.key")
+	require.NoError(t, os.MkdirAll(filepath.Join(badKeyFile, "not-empty"), 0700))
+	assert.Error(t, writeCertificatePair(crtFile, []byte("new crt"), badKeyFile, []byte("new key")))
+	assertFile(t, crtFile, "crt")
+
+	// The extra file cannot be renamed, the previous pair is restored.
+	encKeyFile := filepath.Join(dir, "foo.enc.key")
+	require.NoError(t, writeCertificatePair(crtFile, []byte("crt"), keyFile, []byte("key"), pairFile{encKeyFile, []byte("enc key")}))
+	assertFile(t, encKeyFile, "enc key")
+	assert.Error(t, writeCertificatePair(crtFile, []byte("new crt"), keyFile, []byte("new key"), pairFile{badKeyFile, []byte("new enc key")}))
+	assertFile(t, crtFile, "crt")
+	assertFile(t, keyFile, "key")
+
+	// No temporary files are left.
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 4)
+}
+
+func Test_writeCertificatePair_mode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not supported on Windows")
+	}
+	dir := t.TempDir()
+	crtFile := filepath.Join(dir, "foo.crt")
+	keyFile := filepath.Join(dir, "foo.key")
+	require.NoError(t, os.WriteFile(crtFile, []byte("crt"), 0600))
+	require.NoError(t, os.Chmod(crtFile, 0644))
+	require.NoError(t, os.WriteFile(keyFile, []byte("key"), 0600))
+	require.NoError(t, os.Chmod(keyFile, 0640))
+
+	// The existing files keep their mode.
+	require.NoError(t, writeCertificatePair(crtFile, []byte("new crt"), keyFile, []byte("new key")))
+	assertMode := func(t *testing.T, filename string, want os.FileMode) {
+		t.Helper()
+		st, err := os.Stat(filename)
+		require.NoError(t, err)
+		assert.Equal(t, want, st.Mode().Perm(), filename)
+	}
+	assertMode(t, crtFile, 0644)
+	assertMode(t, keyFile, 0640)
+
+	// The restored certificate keeps its mode.
+	badKeyFile := filepath.Join(dir, "bad.key")
+	require.NoError(t, os.MkdirAll(filepath.Join(badKeyFile, "not-empty"), 0700))
+	assert.Error(t, writeCertificatePair(crtFile, []byte("newer crt"), badKeyFile, []byte("newer key")))
+	b, err := os.ReadFile(crtFile)
+	require.NoError(t, err)
+	assert.Equal(t, "new crt", string(b))
+	assertMode(t, crtFile, 0644)
+
+}
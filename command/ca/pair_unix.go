@@ -0,0 +1,26 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package ca
+
+import (
+	"os"
+	"syscall"
+)
+
+// chownFile changes the owner and the group of f to the ones in fi, if they
+// are different.
+func chownFile(f *os.File, fi os.FileInfo) error {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	cur, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if c, ok := cur.Sys().(*syscall.Stat_t); ok && c.Uid == st.Uid && c.Gid == st.Gid {
+		return nil
+	}
+	return f.Chown(int(st.Uid), int(st.Gid))
+}
@@ -0,0 +1,40 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package ca
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_writeCertificatePair_owner(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("changing the owner of a file requires root")
+	}
+	dir := t.TempDir()
+	crtFile := filepath.Join(dir, "foo.crt")
+	keyFile := filepath.Join(dir, "foo.key")
+	require.NoError(t, os.WriteFile(crtFile, []byte("crt"), 0600))
+	require.NoError(t, os.Chown(crtFile, 1, 1))
+
+	require.NoError(t, writeCertificatePair(crtFile, []byte("new crt"), keyFile, []byte("new key")))
+	for _, tt := range []struct {
+		filename string
+		uid, gid uint32
+	}{
+		{crtFile, 1, 1},
+		{keyFile, uint32(os.Getuid()), uint32(os.Getgid())},
+	} {
+		st, err := os.Stat(tt.filename)
+		require.NoError(t, err)
+		sys := st.Sys().(*syscall.Stat_t)
+		assert.Equal(t, tt.uid, sys.Uid, tt.filename)
+		assert.Equal(t, tt.gid, sys.Gid, tt.filename)
+	}
+}
@@ -0,0 +1,72 @@
+package ca
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/certificates/ca"
+	"github.com/smallstep/certificates/pki"
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/cli-utils/ui"
+	"go.step.sm/crypto/x509util"
+
+	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/utils/cautils"
+)
+
+// preflightTimeout is the maximum time allowed for the health check of the
+// --preflight flag.
+const preflightTimeout = 10 * time.Second
+
+// preflightCheck checks the health of the CA before minting a token, so a
+// single-use token is not wasted against a CA that is not reachable.
+func preflightCheck(ctx *cli.Context) error {
+	caURL, err := flags.ParseCaURLIfExists(ctx)
+	if err != nil {
+		return err
+	} else if caURL == "" {
+		return errs.RequiredWithFlag(ctx, "preflight", "ca-url")
+	}
+
+	root := ctx.String("root")
+	if root == "" {
+		root = pki.GetRootCAPath()
+		if _, err := os.Stat(root); err != nil {
+			return errs.RequiredWithFlag(ctx, "preflight", "root")
+		}
+	}
+
+	cipherSuites, err := cautils.ParseCipherSuitesFlag(ctx)
+	if err != nil {
+		return err
+	}
+
+	options := []ca.ClientOption{ca.WithRootFile(root)}
+	if len(cipherSuites) > 0 {
+		rootCAs, err := x509util.ReadCertPool(root)
+		if err != nil {
+			return err
+		}
+		options = []ca.ClientOption{ca.WithTransport(cautils.NewTransport(rootCAs, cipherSuites))}
+	}
+	client, err := ca.NewClient(caURL, options...)
+	if err != nil {
+		return err
+	}
+
+	c, cancel := context.WithTimeout(context.Background(), preflightTimeout)
+	defer cancel()
+	start := time.Now()
+	resp, err := client.HealthWithContext(c)
+	if err != nil {
+		return errors.Wrapf(err, "preflight check failed: the CA at %s is not reachable", caURL)
+	}
+	if resp.Status != "ok" {
+		return errors.Errorf("preflight check failed: the CA at %s is not healthy: status %q", caURL, resp.Status)
+	}
+	return ui.PrintSelected("CA Health", resp.Status+" ("+time.Since(start).Round(time.Millisecond).String()+")")
+}
@@ -0,0 +1,49 @@
+package ca
+
+import (
+	"encoding/pem"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+)
+
+func Test_preflightCheck(t *testing.T) {
+	status := "ok"
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"` + status + `"}`))
+	}))
+	defer srv.Close()
+
+	rootFile := filepath.Join(t.TempDir(), "root_ca.crt")
+	require.NoError(t, os.WriteFile(rootFile, pem.EncodeToMemory(&pem.Block{
+		Type: "CERTIFICATE", Bytes: srv.Certificate().Raw,
+	}), 0600))
+
+	newContext := func(t *testing.T, caURL string) *cli.Context {
+		t.Helper()
+		fs := flag.NewFlagSet("contrive", 0)
+		_ = fs.String("ca-url", caURL, "")
+		_ = fs.String("root", rootFile, "")
+		return cli.NewContext(&cli.App{}, fs, nil)
+	}
+
+	assert.NoError(t, preflightCheck(newContext(t, srv.URL)))
+
+	status = "starting"
+	assert.ErrorContains(t, preflightCheck(newContext(t, srv.URL)), "is not healthy")
+
+	srv.Close()
+	assert.ErrorContains(t, preflightCheck(newContext(t, srv.URL)), "is not reachable")
+}
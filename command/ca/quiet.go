@@ -0,0 +1,93 @@
+package ca
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/urfave/cli"
+)
+
+// reportedFlags are the flags always included in the report of the
+// --verbose-error flag, they are usually resolved from the context or the
+// defaults file.
+var reportedFlags = map[string]bool{
+	"ca-url":      true,
+	"root":        true,
+	"context":     true,
+	"provisioner": true,
+}
+
+// redactedFlags are the flags with values that must not be printed.
+var redactedFlags = map[string]bool{
+	"token": true,
+}
+
+// startOutputCapture configures the output of the command using the
+// --quiet-success and --verbose-error flags. With --quiet-success the output
+// to stdout and stderr is written to a temporary file, and it is only printed
+// if the command fails. With --verbose-error the resolved configuration and
+// the error chain are printed if the command fails. The returned function
+// must be called with the result of the command.
+func startOutputCapture(ctx *cli.Context) (func(error) error, error) {
+	stdout, stderr := os.Stdout, os.Stderr
+	var f *os.File
+	if ctx.Bool("quiet-success") {
+		var err error
+		if f, err = os.CreateTemp("", "step-ca-output"); err != nil {
+			return nil, err
+		}
+		os.Stdout, os.Stderr = f, f
+	}
+
+	return func(err error) error {
+		if f != nil {
+			os.Stdout, os.Stderr = stdout, stderr
+			defer os.Remove(f.Name())
+			defer f.Close()
+		}
+		if err == nil {
+			return nil
+		}
+		if f != nil {
+			if _, err := f.Seek(0, io.SeekStart); err == nil {
+				io.Copy(stderr, f)
+			}
+		}
+		if ctx.Bool("verbose-error") {
+			writeErrorReport(stderr, ctx, err)
+		}
+		return err
+	}, nil
+}
+
+// writeErrorReport writes the flags used in the command and all the errors in
+// the chain of err.
+func writeErrorReport(w io.Writer, ctx *cli.Context, err error) {
+	fmt.Fprintln(w, "Resolved configuration:")
+	for _, name := range ctx.FlagNames() {
+		if !ctx.IsSet(name) && !reportedFlags[name] {
+			continue
+		}
+		v := fmt.Sprint(ctx.Generic(name))
+		switch {
+		case v == "", v == "[]":
+			continue
+		case redactedFlags[name]:
+			v = "<redacted>"
+		}
+		fmt.Fprintf(w, "  %s: %s\n", name, v)
+	}
+
+	// The wrappers with a stack trace repeat the message of the error they
+	// wrap, they are printed once.
+	fmt.Fprintln(w, "Error chain:")
+	var last string
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if msg := e.Error(); msg != last {
+			fmt.Fprintf(w, "  %s\n", msg)
+			last = msg
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package ca
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+)
+
+func newQuietContext(t *testing.T, args ...string) *cli.Context {
+	t.Helper()
+	cmd := cli.Command{Flags: []cli.Flag{
+		cli.BoolFlag{Name: "quiet-success"},
+		cli.BoolFlag{Name: "verbose-error"},
+		cli.StringFlag{Name: "token"},
+		cli.StringFlag{Name: "ca-url"},
+		cli.StringFlag{Name: "not-after"},
+		cli.StringSliceFlag{Name: "san"},
+	}}
+	fs := flag.NewFlagSet("contrive", 0)
+	for _, f := range cmd.Flags {
+		f.Apply(fs)
+	}
+	require.NoError(t, fs.Parse(args))
+	ctx := cli.NewContext(&cli.App{}, fs, nil)
+	ctx.Command = cmd
+	return ctx
+}
+
+func Test_writeErrorReport(t *testing.T) {
+	ctx := newQuietContext(t, "--token", "secret", "--ca-url", "https://ca.internal", "--san", "foo.internal")
+	err := errors.Wrap(errors.New("connection refused"), "error signing certificate")
+
+	var buf bytes.Buffer
+	writeErrorReport(&buf, ctx, err)
+	assert.Equal(t, `Resolved configuration:
+  token: <redacted>
+  ca-url: https://ca.internal
+  san: foo.internal
+Error chain:
+  error signing certificate: connection refused
+  connection refused
+`, buf.String())
+}
+
+func Test_startOutputCapture(t *testing.T) {
+	stderr := os.Stderr
+	t.Cleanup(func() { os.Stderr = stderr })
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	os.Stderr = w
+
+	ctx := newQuietContext(t, "--quiet-success")
+	finish, err := startOutputCapture(ctx)
+	require.NoError(t, err)
+	os.Stderr.WriteString("success output\n")
+	assert.NoError(t, finish(nil))
+
+	finish, err = startOutputCapture(ctx)
+	require.NoError(t, err)
+	os.Stderr.WriteString("failure output\n")
+	assert.EqualError(t, finish(errors.New("failed")), "failed")
+
+	require.NoError(t, w.Close())
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(r)
+	require.NoError(t, err)
+	assert.Equal(t, "failure output\n", buf.String())
+}
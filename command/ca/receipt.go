@@ -0,0 +1,165 @@
+package ca
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+	"go.step.sm/crypto/jose"
+	"go.step.sm/crypto/pemutil"
+
+	"github.com/smallstep/cli-utils/command"
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/cli-utils/ui"
+
+	"github.com/smallstep/cli/utils"
+)
+
+func receiptCommand() cli.Command {
+	return cli.Command{
+		Name:      "receipt",
+		Usage:     "manage the receipts of issued certificates",
+		UsageText: "**step ca receipt** <subcommand> [arguments] [global-flags] [subcommand-flags]",
+		Description: `**step ca receipt** command group provides facilities to manage the receipts
+written by **step ca certificate --receipt-out**.
+
+A receipt is a JWS signed by the new private key with the subject, serial
+number, and SHA-256 fingerprint of the certificate, and the time of issuance.
+
+## EXAMPLES
+
+Verify the receipt of a certificate:
+'''
+$ step ca receipt verify internal.receipt internal.crt
+'''`,
+		Subcommands: cli.Commands{
+			receiptVerifyCommand(),
+		},
+	}
+}
+
+func receiptVerifyCommand() cli.Command {
+	return cli.Command{
+		Name:      "verify",
+		Action:    command.ActionFunc(receiptVerifyAction),
+		Usage:     "verify the receipt of an issued certificate",
+		UsageText: `**step ca receipt verify** <receipt-file> <crt-file>`,
+		Description: `**step ca receipt verify** checks that the receipt is signed by the key of the
+certificate and that it refers to that certificate.
+
+## POSITIONAL ARGUMENTS
+
+<receipt-file>
+:  The receipt written by **step ca certificate --receipt-out**.
+
+<crt-file>
+:  The certificate of the receipt. If the file contains a bundle, the first
+certificate is used.
+
+## EXAMPLES
+
+Verify the receipt of a certificate:
+'''
+$ step ca receipt verify internal.receipt internal.crt
+The receipt internal.receipt is valid: internal.example.com, serial number 1234, issued at 2024-01-01T00:00:00Z.
+'''`,
+	}
+}
+
+func receiptVerifyAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 2); err != nil {
+		return err
+	}
+
+	args := ctx.Args()
+	receiptFile, crtFile := args.Get(0), args.Get(1)
+
+	b, err := utils.ReadFile(receiptFile)
+	if err != nil {
+		return err
+	}
+	leaf, err := pemutil.ReadCertificate(crtFile, pemutil.WithFirstBlock())
+	if err != nil {
+		return err
+	}
+
+	receipt, err := verifyReceipt(strings.TrimSpace(string(b)), leaf)
+	if err != nil {
+		return err
+	}
+
+	ui.Printf("The receipt %s is valid: %s, serial number %s, issued at %s.\n", receiptFile,
+		receipt.Subject, receipt.SerialNumber, time.Unix(receipt.IssuedAt, 0).UTC().Format(time.RFC3339))
+	return nil
+}
+
+// issuanceReceipt is the payload of the receipt written by the --receipt-out
+// flag of step ca certificate.
+type issuanceReceipt struct {
+	Subject      string `json:"subject"`
+	SerialNumber string `json:"serialNumber"`
+	Fingerprint  string `json:"fingerprint"`
+	IssuedAt     int64  `json:"issuedAt"`
+}
+
+// newIssuanceReceipt returns a receipt for the given certificate, issued at
+// the given time.
+func newIssuanceReceipt(leaf *x509.Certificate, now time.Time) issuanceReceipt {
+	sum := sha256.Sum256(leaf.Raw)
+	return issuanceReceipt{
+		Subject:      leaf.Subject.CommonName,
+		SerialNumber: leaf.SerialNumber.String(),
+		Fingerprint:  hex.EncodeToString(sum[:]),
+		IssuedAt:     now.Unix(),
+	}
+}
+
+// signReceipt returns a compact JWS of the receipt signed by the given key.
+// The certificate is added in the x5c header so the receipt can be verified
+// without any other file.
+func signReceipt(receipt issuanceReceipt, leaf *x509.Certificate, key crypto.PrivateKey) (string, error) {
+	so := new(jose.SignerOptions)
+	so.WithType("JWT")
+	so.WithHeader("x5c", []string{base64.StdEncoding.EncodeToString(leaf.Raw)})
+	signer, err := jose.NewSigner(jose.SigningKey{Key: key}, so)
+	if err != nil {
+		return "", errors.Wrap(err, "error creating receipt signer")
+	}
+	raw, err := jose.Signed(signer).Claims(receipt).CompactSerialize()
+	if err != nil {
+		return "", errors.Wrap(err, "error signing receipt")
+	}
+	return raw, nil
+}
+
+// verifyReceipt verifies the signature of the receipt with the key of the
+// given certificate, and checks that the receipt refers to the certificate.
+func verifyReceipt(raw string, leaf *x509.Certificate) (*issuanceReceipt, error) {
+	tok, err := jose.ParseSigned(raw)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing receipt")
+	}
+	var receipt issuanceReceipt
+	if err := jose.Verify(tok, leaf.PublicKey, &receipt); err != nil {
+		return nil, errors.Wrap(err, "error verifying receipt")
+	}
+	if want := newIssuanceReceipt(leaf, time.Time{}); receipt.Fingerprint != want.Fingerprint || receipt.SerialNumber != want.SerialNumber {
+		return nil, errors.New("error verifying receipt: the receipt does not match the certificate")
+	}
+	return &receipt, nil
+}
+
+// writeReceipt writes the receipt of the leaf certificate to the given file.
+func writeReceipt(filename string, leaf *x509.Certificate, key crypto.PrivateKey) error {
+	raw, err := signReceipt(newIssuanceReceipt(leaf, time.Now()), leaf, key)
+	if err != nil {
+		return err
+	}
+	return utils.WriteFile(filename, []byte(raw+"\n"), 0600)
+}
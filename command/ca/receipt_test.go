@@ -0,0 +1,114 @@
+package ca
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+	"go.step.sm/crypto/minica"
+)
+
+func Test_signReceipt(t *testing.T) {
+	ca, err := minica.New()
+	require.NoError(t, err)
+
+	sign := func(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey) {
+		t.Helper()
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		crt, err := ca.Sign(&x509.Certificate{
+			Subject:   pkix.Name{CommonName: "foo"},
+			DNSNames:  []string{"foo.internal"},
+			PublicKey: key.Public(),
+		})
+		require.NoError(t, err)
+		return crt, key
+	}
+
+	leaf, key := sign(t)
+	other, _ := sign(t)
+	now := time.Now()
+
+	raw, err := signReceipt(newIssuanceReceipt(leaf, now), leaf, key)
+	require.NoError(t, err)
+
+	receipt, err := verifyReceipt(raw, leaf)
+	require.NoError(t, err)
+	assert.Equal(t, "foo", receipt.Subject)
+	assert.Equal(t, leaf.SerialNumber.String(), receipt.SerialNumber)
+	assert.Equal(t, now.Unix(), receipt.IssuedAt)
+
+	// Signed by other key
+	_, err = verifyReceipt(raw, other)
+	assert.Error(t, err)
+
+	// Signed by the right key but for other certificate
+	raw, err = signReceipt(newIssuanceReceipt(other, now), leaf, key)
+	require.NoError(t, err)
+	_, err = verifyReceipt(raw, leaf)
+	assert.ErrorContains(t, err, "does not match the certificate")
+}
+
+func Test_receiptVerifyAction(t *testing.T) {
+	ca, err := minica.New()
+	require.NoError(t, err)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	leaf, err := ca.Sign(&x509.Certificate{
+		Subject:   pkix.Name{CommonName: "foo"},
+		DNSNames:  []string{"foo.internal"},
+		PublicKey: key.Public(),
+	})
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	receiptFile := filepath.Join(dir, "foo.receipt")
+	require.NoError(t, writeReceipt(receiptFile, leaf, key))
+	var chain []byte
+	for _, crt := range []*x509.Certificate{leaf, ca.Intermediate} {
+		chain = append(chain, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: crt.Raw})...)
+	}
+	crtFile := filepath.Join(dir, "foo.crt")
+	require.NoError(t, os.WriteFile(crtFile, chain, 0600))
+	otherFile := filepath.Join(dir, "other.crt")
+	require.NoError(t, os.WriteFile(otherFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Intermediate.Raw}), 0600))
+
+	newContext := func(t *testing.T, args ...string) *cli.Context {
+		t.Helper()
+		fs := flag.NewFlagSet("contrive", 0)
+		require.NoError(t, fs.Parse(args))
+		return cli.NewContext(&cli.App{}, fs, nil)
+	}
+
+	tests := []struct {
+		name    string
+		ctx     *cli.Context
+		wantErr string
+	}{
+		{"ok", newContext(t, receiptFile, crtFile), ""},
+		{"fail/arguments", newContext(t, receiptFile), "not enough positional arguments"},
+		{"fail/other-certificate", newContext(t, receiptFile, otherFile), "error verifying receipt"},
+		{"fail/receipt-not-found", newContext(t, filepath.Join(dir, "missing.receipt"), crtFile), "missing.receipt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := receiptVerifyAction(tt.ctx)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
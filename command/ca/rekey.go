@@ -34,7 +34,9 @@ func rekeyCertificateCommand() cli.Command {
 [**--expires-in**=<duration>] [**--force**] [**--exec**=<string>] [**--daemon**]
 [**--kty**=<type>] [**--curve**=<curve>] [**--size**=<size>]
 [**--expires-in**=<duration>] [**--pid**=<int>] [**--pid-file**=<file>]
-[**--signal**=<int>] [**--exec**=<string>] [**--rekey-period**=<duration>]`,
+[**--signal**=<int>] [**--exec**=<string>] [**--rekey-period**=<duration>]
+[**--no-preserve-sans**] [**--preserve-extensions**] [**--refresh-root**]
+[**--fingerprint**=<fingerprint>] [**--tls-cipher-suites**=<list>]`,
 		Description: `
 **step ca rekey** command rekeys the given certificate (with a request to the
 certificate authority) and writes the new certificate and private key
@@ -52,6 +54,13 @@ fixed period can be set with the **--rekey-period** flag.
 The **--daemon** flag can be combined with **--pid**, **--signal**, or **--exec**
 to provide certificate reloads on your services.
 
+The new certificate must have the same SANs as <crt-file>. If the CA returns a
+certificate with different SANs, the command fails without writing it. Use the
+**--no-preserve-sans** flag when the SANs are expected to change. With the
+**--preserve-extensions** flag, the custom extensions of <crt-file> are added to
+the certificate request, and the command fails if the new certificate does not
+have them.
+
 ## POSITIONAL ARGUMENTS
 
 <crt-file>
@@ -194,6 +203,11 @@ Requires the **--daemon** flag. The <duration> is a sequence of decimal numbers,
 each with optional fraction and a unit suffix, such as "300ms", "1.5h", or "2h45m".
 Valid time units are "ns", "us" (or "µs"), "ms", "s", "m", "h".`,
 			},
+			noPreserveSANsFlag,
+			preserveExtensionsFlag,
+			refreshRootFlag,
+			fingerprintFlag,
+			tlsCipherSuitesFlag,
 			flags.KTY,
 			flags.Curve,
 			flags.Size,
@@ -5,6 +5,7 @@ import (
 	cryptoRand "crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/base64"
 	"encoding/pem"
 	"log"
@@ -48,7 +49,8 @@ func renewCertificateCommand() cli.Command {
 [**--mtls**] [**--password-file**=<file>] [**--out**=<file>] [**--expires-in**=<duration>]
 [**--force**] [**--pid**=<int>] [**--pid-file**=<file>] [**--signal**=<int>]
 [**--exec**=<string>] [**--daemon**] [**--renew-period**=<duration>]
-[**--ca-url**=<uri>] [**--root**=<file>] [**--context**=<name>]`,
+[**--no-preserve-sans**] [**--preserve-extensions**] [**--refresh-root**]
+[**--fingerprint**=<fingerprint>] [**--tls-cipher-suites**=<list>] [**--ca-url**=<uri>] [**--root**=<file>] [**--context**=<name>]`,
 		Description: `
 **step ca renew** command renews the given certificate (with a request to the
 certificate authority) and writes the new certificate to disk - either overwriting
@@ -64,6 +66,12 @@ fixed period can be set with the **--renew-period** flag.
 The **--daemon** flag can be combined with **--pid**, **--signal**, or **--exec**
 to provide certificate reloads on your services.
 
+The renewed certificate must have the same SANs as <crt-file>. If the CA
+returns a certificate with different SANs, the command fails without writing
+it. Use the **--no-preserve-sans** flag when the SANs are expected to change.
+With the **--preserve-extensions** flag, the command also fails if the renewed
+certificate does not have the custom extensions of <crt-file>.
+
 By default, the renew command authenticates to step-ca using mTLS, except when
 the certificate is expired and renewal after expiry is allowed by the CA.
 
@@ -137,6 +145,15 @@ Renew the certificate and reload nginx:
 $ step ca renew --daemon --exec "nginx -s reload" internal.crt internal.key
 '''
 
+Renew the certificate in daemon mode, downloading the new root certificate with
+the given fingerprint before each renewal, so the daemon started with the old
+root keeps working when the CA moves to the new root:
+'''
+$ step ca renew --daemon --refresh-root \
+  --fingerprint 702a094e239c9eec6f0dcd0a5f65e595bf7ed6614012825c5fe3d1ae1b2fd6ee \
+  internal.crt internal.key
+'''
+
 Renew the certificate and convert it to DER:
 '''
 $ step ca renew --daemon --renew-period 16h \
@@ -202,6 +219,11 @@ periodically. By default the daemon will renew a certificate before 2/3 of the
 time to expiration has elapsed. The period can be configured using the
 **--renew-period** or **--expires-in** flags.`,
 			},
+			noPreserveSANsFlag,
+			preserveExtensionsFlag,
+			refreshRootFlag,
+			fingerprintFlag,
+			tlsCipherSuitesFlag,
 			cli.StringFlag{
 				Name: "renew-period",
 				Usage: `The period with which to schedule renewals of the certificate in daemon mode.
@@ -397,14 +419,51 @@ func runExecCmd(execCmd string) error {
 	return cmd.Run()
 }
 
+// noPreserveSANsFlag is the flag used by step ca renew and step ca rekey to
+// accept a new certificate with different SANs.
+var noPreserveSANsFlag = cli.BoolFlag{
+	Name: "no-preserve-sans",
+	Usage: `Do not require the new certificate to have the same SANs as <crt-file>. By
+default, the command fails if the SANs of the new certificate are different.`,
+}
+
+// preserveExtensionsFlag is the flag used by step ca renew and step ca rekey
+// to carry forward the custom extensions of the certificate.
+var preserveExtensionsFlag = cli.BoolFlag{
+	Name: "preserve-extensions",
+	Usage: `Carry forward the non-standard extensions in <crt-file>, the ones that are not
+standard X.509 extensions or added by the CA. The command fails if the new
+certificate does not have them. With **step ca rekey**, the extensions are added
+to the certificate request, and the command fails before sending it if one of
+them is critical.`,
+}
+
+// refreshRootFlag is the flag used by step ca renew and step ca rekey to
+// download the root certificate before each renewal in daemon mode.
+var refreshRootFlag = cli.BoolFlag{
+	Name: "refresh-root",
+	Usage: `Download the root certificate with the fingerprint in the **--fingerprint**
+flag before each renewal, and add it to the roots used to verify the connections
+to the CA. The roots trusted at startup are kept, so a daemon started with the
+old root keeps working once the CA moves to the root with the fingerprint. The
+fingerprint pins a single root, the flag does not follow any other rotation. If
+the download fails, the current roots are used and a warning is printed.
+Requires the **--daemon** flag.`,
+}
+
 type renewer struct {
-	client    cautils.CaClient
-	transport *http.Transport
-	key       crypto.PrivateKey
-	offline   bool
-	cert      tls.Certificate
-	caURL     *url.URL
-	mtls      bool
+	client       cautils.CaClient
+	transport    *http.Transport
+	key          crypto.PrivateKey
+	offline      bool
+	cert         tls.Certificate
+	caURL        *url.URL
+	mtls         bool
+	preserveSANs bool
+	// preservedExtensions are the extensions required by the
+	// --preserve-extensions flag.
+	preservedExtensions []pkix.Extension
+	fingerprint         string
 }
 
 func newRenewer(ctx *cli.Context, caURL string, cert tls.Certificate, rootFile string) (*renewer, error) {
@@ -412,6 +471,29 @@ func newRenewer(ctx *cli.Context, caURL string, cert tls.Certificate, rootFile s
 		return nil, errors.New("error loading certificate: certificate chain is empty")
 	}
 
+	var fingerprint string
+	if ctx.Bool("refresh-root") {
+		if !ctx.Bool("daemon") {
+			return nil, errs.RequiredWithFlag(ctx, "refresh-root", "daemon")
+		}
+		if ctx.Bool("offline") {
+			return nil, errs.IncompatibleFlagWithFlag(ctx, "refresh-root", "offline")
+		}
+		if fingerprint = ctx.String("fingerprint"); fingerprint == "" {
+			return nil, errs.RequiredWithFlag(ctx, "refresh-root", "fingerprint")
+		}
+	}
+
+	cipherSuites, err := cautils.ParseCipherSuitesFlag(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var preserved []pkix.Extension
+	if ctx.Bool("preserve-extensions") {
+		preserved = cautils.NonStandardExtensions(cert.Leaf)
+	}
+
 	rootCAs, err := x509util.ReadCertPool(rootFile)
 	if err != nil {
 		return nil, err
@@ -421,6 +503,7 @@ func newRenewer(ctx *cli.Context, caURL string, cert tls.Certificate, rootFile s
 		Proxy: http.ProxyFromEnvironment,
 		TLSClientConfig: &tls.Config{
 			RootCAs:                  rootCAs,
+			CipherSuites:             cipherSuites,
 			PreferServerCipherSuites: true,
 			MinVersion:               tls.VersionTLS12,
 		},
@@ -454,13 +537,16 @@ func newRenewer(ctx *cli.Context, caURL string, cert tls.Certificate, rootFile s
 	}
 
 	return &renewer{
-		client:    client,
-		transport: tr,
-		key:       cert.PrivateKey,
-		offline:   offline,
-		cert:      cert,
-		caURL:     u,
-		mtls:      ctx.Bool("mtls"),
+		client:              client,
+		transport:           tr,
+		key:                 cert.PrivateKey,
+		offline:             offline,
+		cert:                cert,
+		caURL:               u,
+		mtls:                ctx.Bool("mtls"),
+		preserveSANs:        !ctx.Bool("no-preserve-sans"),
+		preservedExtensions: preserved,
+		fingerprint:         fingerprint,
 	}, nil
 }
 
@@ -473,6 +559,9 @@ func (r *renewer) Renew(outFile string) (resp *api.SignResponse, err error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "error renewing certificate")
 	}
+	if err := r.checkPreserved(resp.ServerPEM.Certificate); err != nil {
+		return nil, err
+	}
 
 	if len(resp.CertChainPEM) == 0 {
 		resp.CertChainPEM = []api.Certificate{resp.ServerPEM, resp.CaPEM}
@@ -493,7 +582,15 @@ func (r *renewer) Renew(outFile string) (resp *api.SignResponse, err error) {
 }
 
 func (r *renewer) Rekey(priv interface{}, outCert, outKey string, writePrivateKey bool) (*api.SignResponse, error) {
-	csrBytes, err := x509.CreateCertificateRequest(cryptoRand.Reader, &x509.CertificateRequest{}, priv)
+	if err := checkPreservableExtensions(r.preservedExtensions); err != nil {
+		return nil, err
+	}
+	template := &x509.CertificateRequest{}
+	if r.preserveSANs {
+		template = preservedSANsTemplate(r.cert.Leaf)
+	}
+	template.ExtraExtensions = r.preservedExtensions
+	csrBytes, err := x509.CreateCertificateRequest(cryptoRand.Reader, template, priv)
 	if err != nil {
 		return nil, err
 	}
@@ -505,6 +602,9 @@ func (r *renewer) Rekey(priv interface{}, outCert, outKey string, writePrivateKe
 	if err != nil {
 		return nil, errors.Wrap(err, "error rekeying certificate")
 	}
+	if err := r.checkPreserved(resp.ServerPEM.Certificate); err != nil {
+		return nil, err
+	}
 	if len(resp.CertChainPEM) == 0 {
 		resp.CertChainPEM = []api.Certificate{resp.ServerPEM, resp.CaPEM}
 	}
@@ -529,12 +629,83 @@ func (r *renewer) Rekey(priv interface{}, outCert, outKey string, writePrivateKe
 	return resp, nil
 }
 
+// checkPreserved returns an error if the new certificate does not have the
+// SANs or the extensions of the current one that must be preserved.
+func (r *renewer) checkPreserved(newCert *x509.Certificate) error {
+	if r.preserveSANs {
+		if err := checkPreservedSANs(r.cert.Leaf, newCert); err != nil {
+			return err
+		}
+	}
+	if len(r.preservedExtensions) > 0 && newCert != nil {
+		return checkPreservedExtensions(newCert, r.preservedExtensions)
+	}
+	return nil
+}
+
+// preservedSANsTemplate returns a certificate request template with the
+// subject and the full set of SANs of the given certificate.
+func preservedSANsTemplate(cert *x509.Certificate) *x509.CertificateRequest {
+	return &x509.CertificateRequest{
+		Subject:        pkix.Name{CommonName: cert.Subject.CommonName},
+		DNSNames:       cert.DNSNames,
+		IPAddresses:    cert.IPAddresses,
+		EmailAddresses: cert.EmailAddresses,
+		URIs:           cert.URIs,
+	}
+}
+
+// checkPreservedSANs returns an error if the new certificate does not have
+// the same set of SANs as the old one.
+func checkPreservedSANs(oldCert, newCert *x509.Certificate) error {
+	if oldCert == nil || newCert == nil {
+		return nil
+	}
+	oldSANs, newSANs := certificateSANs(oldCert), certificateSANs(newCert)
+	want := make(map[string]bool, len(oldSANs))
+	for _, san := range oldSANs {
+		want[san] = true
+	}
+	got := make(map[string]bool, len(newSANs))
+	for _, san := range newSANs {
+		got[san] = true
+	}
+	var missing, added []string
+	for _, san := range oldSANs {
+		if !got[san] {
+			missing = append(missing, san)
+		}
+	}
+	for _, san := range newSANs {
+		if !want[san] {
+			added = append(added, san)
+		}
+	}
+	if len(missing) == 0 && len(added) == 0 {
+		return nil
+	}
+	msg := "the new certificate does not preserve the SANs of the original certificate"
+	if len(missing) > 0 {
+		msg += "; missing: " + strings.Join(missing, ", ")
+	}
+	if len(added) > 0 {
+		msg += "; added: " + strings.Join(added, ", ")
+	}
+	return errors.New(msg + "; use '--no-preserve-sans' to accept it")
+}
+
 // RenewAndPrepareNext renews the cert and prepares the cert for it's next renewal.
 // NOTE: this function logs each time the certificate is successfully renewed.
 func (r *renewer) RenewAndPrepareNext(outFile string, expiresIn, renewPeriod time.Duration) (time.Duration, error) {
 	const durationOnErrors = 1 * time.Minute
 	infoLog := log.New(os.Stdout, "INFO: ", log.LstdFlags)
 
+	if r.fingerprint != "" {
+		if err := r.RefreshRoot(); err != nil {
+			log.New(os.Stderr, "WARNING: ", log.LstdFlags).Printf("%v, using the previous root certificate", err)
+		}
+	}
+
 	resp, err := r.Renew(outFile)
 	if err != nil {
 		return durationOnErrors, err
@@ -603,6 +774,33 @@ func (r *renewer) Daemon(outFile string, next, expiresIn, renewPeriod time.Durat
 	}
 }
 
+// RefreshRoot downloads the root certificate with the fingerprint of the
+// --fingerprint flag, and adds it to the roots used to verify the next
+// connections to the CA. The current roots are kept if there is an error.
+func (r *renewer) RefreshRoot() error {
+	client, err := ca.NewClient(r.caURL.String(), ca.WithTransport(r.transport))
+	if err != nil {
+		return errors.Wrap(err, "error refreshing root certificate")
+	}
+	// The download uses an insecure connection and checks the fingerprint of
+	// the returned root.
+	resp, err := client.Root(r.fingerprint)
+	if err != nil {
+		return errors.Wrap(err, "error refreshing root certificate")
+	}
+
+	// The root is added to a copy of the current pool, the pool in use is not
+	// modified and the roots used before are still trusted.
+	rootCAs := x509.NewCertPool()
+	if current := r.transport.TLSClientConfig.RootCAs; current != nil {
+		rootCAs = current.Clone()
+	}
+	rootCAs.AddCert(resp.RootPEM.Certificate)
+	r.transport.TLSClientConfig.RootCAs = rootCAs
+	r.transport.CloseIdleConnections()
+	return nil
+}
+
 // RenewWithToken creates an authorization token with the given certificate and
 // attempts to renew the given certificate. It can be used to renew expired
 // certificates.
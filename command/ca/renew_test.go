@@ -0,0 +1,226 @@
+package ca
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/crypto/minica"
+	"go.step.sm/crypto/pemutil"
+
+	"github.com/smallstep/certificates/api"
+
+	"github.com/smallstep/cli/utils/cautils"
+)
+
+// renewClient is a CA client that returns the same certificate on each
+// renewal.
+type renewClient struct {
+	cautils.CaClient
+	resp *api.SignResponse
+}
+
+func (c *renewClient) Renew(http.RoundTripper) (*api.SignResponse, error) {
+	return c.resp, nil
+}
+
+func Test_renewer_Renew_preserveSANs(t *testing.T) {
+	ca, err := minica.New()
+	require.NoError(t, err)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	sign := func(t *testing.T, sans ...string) *x509.Certificate {
+		t.Helper()
+		template := &x509.CertificateRequest{Subject: pkix.Name{CommonName: "foo"}}
+		for _, san := range sans {
+			if ip := net.ParseIP(san); ip != nil {
+				template.IPAddresses = append(template.IPAddresses, ip)
+			} else {
+				template.DNSNames = append(template.DNSNames, san)
+			}
+		}
+		der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+		require.NoError(t, err)
+		csr, err := x509.ParseCertificateRequest(der)
+		require.NoError(t, err)
+		crt, err := ca.SignCSR(csr)
+		require.NoError(t, err)
+		return crt
+	}
+
+	original := sign(t, "foo.internal", "bar.internal", "10.0.0.1")
+	cert := tls.Certificate{
+		Certificate: [][]byte{original.Raw, ca.Intermediate.Raw},
+		PrivateKey:  key,
+		Leaf:        original,
+	}
+
+	tests := []struct {
+		name         string
+		renewed      *x509.Certificate
+		preserveSANs bool
+		wantErr      bool
+	}{
+		{"ok", sign(t, "foo.internal", "bar.internal", "10.0.0.1"), true, false},
+		{"ok/order", sign(t, "10.0.0.1", "bar.internal", "foo.internal"), true, false},
+		{"ok/no-preserve-sans", sign(t, "foo.internal"), false, false},
+		{"fail/missing", sign(t, "foo.internal", "10.0.0.1"), true, true},
+		{"fail/added", sign(t, "foo.internal", "bar.internal", "zar.internal", "10.0.0.1"), true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outFile := filepath.Join(t.TempDir(), "renewed.crt")
+			r := &renewer{
+				client: &renewClient{resp: &api.SignResponse{
+					ServerPEM: api.Certificate{Certificate: tt.renewed},
+					CaPEM:     api.Certificate{Certificate: ca.Intermediate},
+				}},
+				transport:    &http.Transport{},
+				key:          key,
+				cert:         cert,
+				mtls:         true,
+				preserveSANs: tt.preserveSANs,
+			}
+
+			_, err := r.Renew(outFile)
+			if tt.wantErr {
+				assert.ErrorContains(t, err, "--no-preserve-sans")
+				assert.NoFileExists(t, outFile)
+				return
+			}
+			require.NoError(t, err)
+
+			certs, err := pemutil.ReadCertificateBundle(outFile)
+			require.NoError(t, err)
+			if tt.preserveSANs {
+				assert.ElementsMatch(t, certificateSANs(original), certificateSANs(certs[0]))
+			} else {
+				assert.Equal(t, tt.renewed.Raw, certs[0].Raw)
+			}
+		})
+	}
+}
+
+func Test_renewer_checkPreserved(t *testing.T) {
+	foo := pkix.Extension{Id: asn1.ObjectIdentifier{1, 2, 3}, Value: []byte{5, 0}}
+	bar := pkix.Extension{Id: asn1.ObjectIdentifier{1, 2, 4}, Value: []byte{5, 0}}
+	leaf := &x509.Certificate{DNSNames: []string{"foo.internal"}, Extensions: []pkix.Extension{foo, bar}}
+
+	tests := []struct {
+		name      string
+		preserved []pkix.Extension
+		newCert   *x509.Certificate
+		wantErr   bool
+	}{
+		{"ok", []pkix.Extension{foo, bar}, &x509.Certificate{DNSNames: []string{"foo.internal"}, Extensions: []pkix.Extension{foo, bar}}, false},
+		{"ok/not-preserved", nil, &x509.Certificate{DNSNames: []string{"foo.internal"}}, false},
+		{"fail/missing", []pkix.Extension{foo, bar}, &x509.Certificate{DNSNames: []string{"foo.internal"}, Extensions: []pkix.Extension{foo}}, true},
+		{"fail/sans", nil, &x509.Certificate{DNSNames: []string{"bar.internal"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &renewer{
+				cert:                tls.Certificate{Leaf: leaf},
+				preserveSANs:        true,
+				preservedExtensions: tt.preserved,
+			}
+			err := r.checkPreserved(tt.newCert)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_preservedSANsTemplate(t *testing.T) {
+	ca, err := minica.New()
+	require.NoError(t, err)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	crt, err := ca.Sign(&x509.Certificate{
+		Subject:        pkix.Name{CommonName: "foo"},
+		DNSNames:       []string{"foo.internal"},
+		IPAddresses:    []net.IP{net.ParseIP("10.0.0.1")},
+		EmailAddresses: []string{"foo@example.com"},
+		PublicKey:      key.Public(),
+	})
+	require.NoError(t, err)
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, preservedSANsTemplate(crt), key)
+	require.NoError(t, err)
+	csr, err := x509.ParseCertificateRequest(der)
+	require.NoError(t, err)
+	assert.Equal(t, "foo", csr.Subject.CommonName)
+	assert.Equal(t, crt.DNSNames, csr.DNSNames)
+	assert.Equal(t, crt.EmailAddresses, csr.EmailAddresses)
+	assert.True(t, crt.IPAddresses[0].Equal(csr.IPAddresses[0]))
+}
+
+func Test_renewer_RefreshRoot(t *testing.T) {
+	ca, err := minica.New()
+	require.NoError(t, err)
+	sum := sha256.Sum256(ca.Root.Raw)
+	fingerprint := hex.EncodeToString(sum[:])
+
+	// The server always returns the same root.
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(api.RootResponse{RootPEM: api.NewCertificate(ca.Root)})
+	}))
+	defer srv.Close()
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	old, err := minica.New()
+	require.NoError(t, err)
+
+	newRenewer := func(fingerprint string) (*renewer, *x509.CertPool) {
+		previous := x509.NewCertPool()
+		previous.AddCert(old.Root)
+		return &renewer{
+			transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: previous, MinVersion: tls.VersionTLS12},
+			},
+			caURL:       u,
+			fingerprint: fingerprint,
+		}, previous
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		r, previous := newRenewer(fingerprint)
+		require.NoError(t, r.RefreshRoot())
+		require.NoError(t, r.RefreshRoot())
+		want := x509.NewCertPool()
+		want.AddCert(old.Root)
+		want.AddCert(ca.Root)
+		assert.True(t, want.Equal(r.transport.TLSClientConfig.RootCAs))
+		// The pool in use is not modified.
+		want = x509.NewCertPool()
+		want.AddCert(old.Root)
+		assert.True(t, want.Equal(previous))
+	})
+
+	t.Run("fail/fingerprint", func(t *testing.T) {
+		other := sha256.Sum256(ca.Intermediate.Raw)
+		r, previous := newRenewer(hex.EncodeToString(other[:]))
+		assert.Error(t, r.RefreshRoot())
+		assert.Same(t, previous, r.transport.TLSClientConfig.RootCAs)
+	})
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/smallstep/cli-utils/command"
 	"github.com/smallstep/cli-utils/errs"
 	"github.com/smallstep/cli-utils/ui"
+	"github.com/smallstep/truststore"
 	"go.step.sm/crypto/pemutil"
 
 	"github.com/smallstep/cli/flags"
@@ -23,7 +24,8 @@ func rootCommand() cli.Command {
 		Action: command.ActionFunc(rootAction),
 		Usage:  "download and validate the root certificate",
 		UsageText: `**step ca root** [<root-file>]
-[**--ca-url**=<uri>] [**--fingerprint**=<fingerprint>] [**--context**=<name>]`,
+[**--ca-url**=<uri>] [**--fingerprint**=<fingerprint>] [**--install**]
+[**--context**=<name>]`,
 		Description: `**step ca root** downloads and validates the root certificate from the
 certificate authority.
 
@@ -53,6 +55,13 @@ $ step ca root root_ca.crt \
   --fingerprint 0d7d3834cf187726cf331c40a31aa7ef6b29ba4df601416c9788f6ee01058cf3
 '''
 
+Download the root certificate and install it into the system's default trust
+store, this operation might require administrator privileges:
+'''
+$ step ca root root_ca.crt --install \
+  --fingerprint 0d7d3834cf187726cf331c40a31aa7ef6b29ba4df601416c9788f6ee01058cf3
+'''
+
 Print the root certificate using the flags set by <step ca bootstrap>:
 '''
 $ step ca root
@@ -60,6 +69,11 @@ $ step ca root
 		Flags: []cli.Flag{
 			flags.Force,
 			fingerprintFlag,
+			cli.BoolFlag{
+				Name: "install",
+				Usage: `Install the root certificate into the system's default trust store. This
+operation might require administrator privileges.`,
+			},
 			flags.CaURL,
 			flags.Context,
 		},
@@ -104,5 +118,14 @@ func rootAction(ctx *cli.Context) error {
 		}
 		fmt.Print(string(pem.EncodeToMemory(block)))
 	}
+
+	if ctx.Bool("install") {
+		ui.Printf("Installing the root certificate %q in the system truststore... ", resp.RootPEM.Subject.CommonName)
+		if err := truststore.Install(resp.RootPEM.Certificate); err != nil {
+			ui.Println()
+			return errors.Wrap(err, "error installing the root certificate")
+		}
+		ui.Println("done.")
+	}
 	return nil
 }
@@ -0,0 +1,54 @@
+package ca
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/smallstep/certificates/authority/policy"
+
+	"github.com/smallstep/cli/utils"
+)
+
+// checkSANPolicy returns an error if any of the SANs is not allowed by the
+// X.509 policy in the file used in the --san-policy-file flag. The file uses
+// the format of the x509 policy of a provisioner in the CA configuration,
+// e.g. {"allow": {"dns": ["*.corp.example.com"]}}.
+func checkSANPolicy(filename string, sans []string) error {
+	b, err := utils.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	var opts policy.X509PolicyOptions
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&opts); err != nil {
+		return errors.Wrapf(err, "error parsing %s", filename)
+	}
+	engine, err := policy.NewX509PolicyEngine(&opts)
+	if err != nil {
+		return errors.Wrapf(err, "error parsing %s", filename)
+	}
+	if engine == nil {
+		return errors.Errorf("error parsing %s: the policy does not allow or deny any name", filename)
+	}
+
+	if err := engine.AreSANsAllowed(sans); err != nil {
+		return errors.Wrapf(err, "the requested SANs are not allowed by the policy in %s", filename)
+	}
+	return nil
+}
+
+// checkCSRSANPolicy returns an error if any of the SANs in the certificate
+// request is not allowed by the X.509 policy in filename. If the request does
+// not have SANs, the common name is checked.
+func checkCSRSANPolicy(filename string, csr *x509.CertificateRequest) error {
+	sans := mergeSans(nil, csr)
+	if len(sans) == 0 && csr.Subject.CommonName != "" {
+		sans = []string{csr.Subject.CommonName}
+	}
+	return checkSANPolicy(filename, sans)
+}
@@ -0,0 +1,107 @@
+package ca
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_checkSANPolicy(t *testing.T) {
+	dir := t.TempDir()
+	write := func(t *testing.T, name, data string) string {
+		t.Helper()
+		filename := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(filename, []byte(data), 0600))
+		return filename
+	}
+
+	allow := write(t, "allow.json", `{"allow": {"dns": ["*.corp.example.com"], "ip": ["10.0.0.0/8"]}}`)
+	deny := write(t, "deny.json", `{"deny": {"dns": ["*.internal"]}}`)
+	empty := write(t, "empty.json", `{}`)
+	unknown := write(t, "unknown.json", `{"allowed": {"dns": ["*.corp.example.com"]}}`)
+	invalid := write(t, "invalid.json", `{"allow": {"dns": ["*.corp.example.com"]`)
+
+	tests := []struct {
+		name     string
+		filename string
+		sans     []string
+		wantErr  bool
+	}{
+		{"ok/allow", allow, []string{"www.corp.example.com", "10.1.2.3"}, false},
+		{"ok/deny", deny, []string{"www.corp.example.com"}, false},
+		{"fail/allow", allow, []string{"www.corp.example.com", "www.example.com"}, true},
+		{"fail/allow-ip", allow, []string{"192.168.0.1"}, true},
+		{"fail/deny", deny, []string{"foo.internal"}, true},
+		{"fail/empty", empty, []string{"www.corp.example.com"}, true},
+		{"fail/unknown", unknown, []string{"www.corp.example.com"}, true},
+		{"fail/invalid", invalid, []string{"www.corp.example.com"}, true},
+		{"fail/missing", filepath.Join(dir, "missing.json"), []string{"www.corp.example.com"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSANPolicy(tt.filename, tt.sans)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_checkCSRSANPolicy(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "policy.json")
+	require.NoError(t, os.WriteFile(filename, []byte(`{"allow": {"dns": ["*.corp.example.com"], "ip": ["10.0.0.0/8"]}}`), 0600))
+
+	tests := []struct {
+		name    string
+		csr     *x509.CertificateRequest
+		wantErr bool
+	}{
+		{"ok", &x509.CertificateRequest{
+			Subject:     pkix.Name{CommonName: "www.corp.example.com"},
+			DNSNames:    []string{"www.corp.example.com"},
+			IPAddresses: []net.IP{net.ParseIP("10.1.2.3")},
+		}, false},
+		{"ok/common-name", &x509.CertificateRequest{
+			Subject: pkix.Name{CommonName: "www.corp.example.com"},
+		}, false},
+		{"fail/dns", &x509.CertificateRequest{
+			Subject:  pkix.Name{CommonName: "www.corp.example.com"},
+			DNSNames: []string{"www.corp.example.com", "www.example.com"},
+		}, true},
+		{"fail/ip", &x509.CertificateRequest{
+			Subject:     pkix.Name{CommonName: "www.corp.example.com"},
+			DNSNames:    []string{"www.corp.example.com"},
+			IPAddresses: []net.IP{net.ParseIP("192.168.0.1")},
+		}, true},
+		{"fail/email", &x509.CertificateRequest{
+			Subject:        pkix.Name{CommonName: "www.corp.example.com"},
+			EmailAddresses: []string{"jane@example.com"},
+		}, true},
+		{"fail/uri", &x509.CertificateRequest{
+			Subject: pkix.Name{CommonName: "www.corp.example.com"},
+			URIs:    []*url.URL{{Scheme: "spiffe", Host: "example.com", Path: "/foo"}},
+		}, true},
+		{"fail/common-name", &x509.CertificateRequest{
+			Subject: pkix.Name{CommonName: "www.example.com"},
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkCSRSANPolicy(filename, tt.csr)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
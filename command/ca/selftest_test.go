@@ -0,0 +1,108 @@
+package ca
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+	"go.step.sm/crypto/minica"
+)
+
+func Test_verifySelftestChain(t *testing.T) {
+	ca, err := minica.New()
+	require.NoError(t, err)
+	other, err := minica.New()
+	require.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	leaf, err := ca.Sign(&x509.Certificate{
+		Subject:   pkix.Name{CommonName: "step-ca-selftest"},
+		DNSNames:  []string{"step-ca-selftest"},
+		PublicKey: key.Public(),
+	})
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	writeCerts := func(t *testing.T, name string, certs ...*x509.Certificate) string {
+		t.Helper()
+		var b []byte
+		for _, crt := range certs {
+			b = append(b, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: crt.Raw})...)
+		}
+		filename := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(filename, b, 0600))
+		return filename
+	}
+	chainFile := writeCerts(t, "chain.crt", leaf, ca.Intermediate)
+	leafFile := writeCerts(t, "leaf.crt", leaf)
+	rootFile := writeCerts(t, "root.crt", ca.Root)
+	rootsFile := writeCerts(t, "roots.crt", other.Root, ca.Root)
+	otherFile := writeCerts(t, "other.crt", other.Root)
+
+	newContext := func(t *testing.T, root string) *cli.Context {
+		t.Helper()
+		fs := flag.NewFlagSet("contrive", 0)
+		_ = fs.String("root", root, "")
+		return cli.NewContext(&cli.App{}, fs, nil)
+	}
+
+	tests := []struct {
+		name    string
+		ctx     *cli.Context
+		crtFile string
+		wantErr bool
+	}{
+		{"ok", newContext(t, rootFile), chainFile, false},
+		{"ok/root-bundle", newContext(t, rootsFile), chainFile, false},
+		{"fail/other-root", newContext(t, otherFile), chainFile, true},
+		{"fail/no-intermediate", newContext(t, rootFile), leafFile, true},
+		{"fail/root-not-found", newContext(t, filepath.Join(dir, "missing.crt")), chainFile, true},
+		{"fail/crt-not-found", newContext(t, rootFile), filepath.Join(dir, "missing.crt"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifySelftestChain(tt.ctx, tt.crtFile)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_selftestAction(t *testing.T) {
+	newContext := func(t *testing.T, tok, passwordFile string, args ...string) *cli.Context {
+		t.Helper()
+		fs := flag.NewFlagSet("contrive", 0)
+		_ = fs.String("token", tok, "")
+		_ = fs.String("provisioner-password-file", passwordFile, "")
+		require.NoError(t, fs.Parse(args))
+		return cli.NewContext(&cli.App{}, fs, nil)
+	}
+
+	tests := []struct {
+		name    string
+		ctx     *cli.Context
+		wantErr string
+	}{
+		{"fail/too-many-arguments", newContext(t, "", "", "foo", "bar"), "too many positional arguments"},
+		{"fail/token-and-password-file", newContext(t, "token", "password.txt"), "flag '--token' is incompatible with '--provisioner-password-file'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorContains(t, selftestAction(tt.ctx), tt.wantErr)
+		})
+	}
+}
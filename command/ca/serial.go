@@ -0,0 +1,51 @@
+package ca
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/smallstep/cli-utils/errs"
+
+	"github.com/smallstep/cli/utils"
+)
+
+// fileLockTimeout is the maximum time to wait for another process that is
+// updating a shared file, like the serial file or the ledger.
+const fileLockTimeout = 30 * time.Second
+
+// updateSerialFile sets the serial number of the certificate issued for the
+// subject in the JSON object in filename, replacing the previous serial
+// number of the subject. The file is created if it does not exist. The file
+// is locked while it's updated, so concurrent commands do not lose updates.
+func updateSerialFile(filename, subject, serial string) error {
+	unlock, err := utils.AcquireLockFile(filename+".lock", fileLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	serials := make(map[string]string)
+	b, err := os.ReadFile(filename)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return errs.FileError(err, filename)
+	case len(bytes.TrimSpace(b)) > 0:
+		if err := json.Unmarshal(b, &serials); err != nil {
+			return errors.Wrapf(err, "error parsing %s", filename)
+		}
+	}
+
+	serials[subject] = serial
+	b, err = json.MarshalIndent(serials, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "error marshaling serial numbers")
+	}
+
+	// The file is replaced without asking, it's updated on every issuance.
+	return utils.ReplaceFile(filename, append(b, '\n'), 0600)
+}
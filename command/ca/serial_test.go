@@ -0,0 +1,54 @@
+package ca
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_updateSerialFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "serials.json")
+
+	require.NoError(t, updateSerialFile(filename, "foo.internal", "1"))
+	require.NoError(t, updateSerialFile(filename, "bar.internal", "2"))
+	require.NoError(t, updateSerialFile(filename, "foo.internal", "3"))
+
+	b, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"foo.internal":"3","bar.internal":"2"}`, string(b))
+
+	require.NoError(t, os.WriteFile(filename, []byte("not json"), 0600))
+	assert.Error(t, updateSerialFile(filename, "foo.internal", "4"))
+}
+
+func Test_updateSerialFile_concurrent(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "serials.json")
+
+	var wg sync.WaitGroup
+	errc := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errc <- updateSerialFile(filename, fmt.Sprintf("host-%d.internal", i), strconv.Itoa(i))
+		}(i)
+	}
+	wg.Wait()
+	close(errc)
+	for err := range errc {
+		require.NoError(t, err)
+	}
+
+	b, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	var serials map[string]string
+	require.NoError(t, json.Unmarshal(b, &serials))
+	assert.Len(t, serials, 10)
+}
@@ -0,0 +1,60 @@
+package ca
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/smallstep/cli-utils/ui"
+	"go.step.sm/crypto/pemutil"
+)
+
+// showCertificateChain prints a summary of the leaf certificate and the PEM of
+// the certificate chain in crtFile, used by the --show flag. The output is
+// highlighted using the ui templates, so it respects the --no-color flag.
+func showCertificateChain(crtFile string) error {
+	certs, err := pemutil.ReadCertificateBundle(crtFile)
+	if err != nil {
+		return err
+	}
+	return ui.Print(certificateChainTemplate(certs))
+}
+
+// certificateChainTemplate returns the ui template used to print the
+// certificates. All the values are added as quoted strings so they are never
+// interpreted as templates.
+func certificateChainTemplate(certs []*x509.Certificate) string {
+	var sb strings.Builder
+	field := func(name, value string) {
+		fmt.Fprintf(&sb, "{{ %s | bold }} {{ %s }}\n", strconv.Quote(name+":"), strconv.Quote(value))
+	}
+
+	for _, crt := range certs {
+		if crt.IsCA {
+			continue
+		}
+		field("Subject", crt.Subject.String())
+		field("SANs", strings.Join(certificateSANs(crt), ", "))
+		field("Serial Number", crt.SerialNumber.String())
+		field("Not Before", crt.NotBefore.UTC().Format(time.RFC3339))
+		field("Not After", crt.NotAfter.UTC().Format(time.RFC3339))
+		field("Key Type", publicKeyType(crt.PublicKey))
+		field("Issuer", crt.Issuer.String())
+		break
+	}
+
+	for _, crt := range certs {
+		block := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: crt.Raw})
+		for _, line := range strings.Split(strings.TrimSpace(string(block)), "\n") {
+			if strings.HasPrefix(line, "-----") {
+				fmt.Fprintf(&sb, "{{ %s | cyan }}\n", strconv.Quote(line))
+			} else {
+				fmt.Fprintf(&sb, "{{ %s | faint }}\n", strconv.Quote(line))
+			}
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,41 @@
+package ca
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+	"text/template"
+
+	"github.com/manifoldco/promptui"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/crypto/minica"
+)
+
+func Test_certificateChainTemplate(t *testing.T) {
+	ca, err := minica.New()
+	require.NoError(t, err)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	leaf, err := ca.Sign(&x509.Certificate{
+		Subject:   pkix.Name{CommonName: "{{ .Injected }}"},
+		DNSNames:  []string{"foo.internal"},
+		PublicKey: key.Public(),
+	})
+	require.NoError(t, err)
+
+	tmpl, err := template.New("Print").Funcs(promptui.FuncMap).Parse(certificateChainTemplate([]*x509.Certificate{leaf, ca.Intermediate}))
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, tmpl.Execute(&buf, nil))
+
+	out := buf.String()
+	assert.Contains(t, out, "CN={{ .Injected }}")
+	assert.Contains(t, out, "foo.internal")
+	assert.Contains(t, out, leaf.SerialNumber.String())
+	assert.Equal(t, 2, bytes.Count(buf.Bytes(), []byte("BEGIN CERTIFICATE")))
+}
@@ -3,6 +3,7 @@ package ca
 import (
 	"crypto/x509"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/urfave/cli"
@@ -15,6 +16,7 @@ import (
 
 	"github.com/smallstep/cli/flags"
 	"github.com/smallstep/cli/token"
+	"github.com/smallstep/cli/utils"
 	"github.com/smallstep/cli/utils/cautils"
 )
 
@@ -24,9 +26,17 @@ func signCertificateCommand() cli.Command {
 		Action: command.ActionFunc(signCertificateAction),
 		Usage:  "generate a new certificate from signing a certificate request",
 		UsageText: `**step ca sign** <csr-file> <crt-file>
-[**--token**=<token>] [**--issuer**=<name>] [**--provisioner-password-file=<file>]
+[**--token**=<token>] [**--issuer**=<name>] [**--provisioner-password-file**=<file>]
 [**--not-before**=<time|duration>] [**--not-after**=<time|duration>]
-[**--set**=<key=value>] [**--set-file**=<file>]
+[**--set**=<key=value>] [**--set-file**=<file>] [**--no-eku**]
+[**--strict**] [**--strict-sans**] [**--require-chain**] [**--warn-expiry-fatal**]
+[**--on-clamp**=<policy>] [**--chain-order**=<order>] [**--line-ending**=<ending>]
+[**--clock-skew**=<duration>] [**--allowed-signature-algs**=<list>] [**--show**]
+[**--preflight**] [**--syslog**] [**--syslog-facility**=<facility>] [**--syslog-priority**=<priority>]
+[**--min-key-strength**=<bits>] [**--validate-only**] [**--tls-cipher-suites**=<list>]
+[**--cross-signed**] [**--max-response-size**=<size>] [**--root-out**=<file>]
+[**--ski**=<method>] [**--deadline-from-token**] [**--issuer-hint**=<substring>]
+[**--verify-token**] [**--san-policy-file**=<file>]
 [**--acme**=<uri>] [**--standalone**] [**--webroot**=<file>]
 [**--contact**=<email>] [**--http-listen**=<address>] [**--console**]
 [**--x5c-cert**=<file>] [**--x5c-key**=<file>] [**--k8ssa-token-path**=<file>]
@@ -37,7 +47,8 @@ func signCertificateCommand() cli.Command {
 ## POSITIONAL ARGUMENTS
 
 <csr-file>
-:  File with the certificate signing request (PEM format)
+:  File with the certificate signing request (PEM format). Use a hyphen ("-")
+to read the certificate request from STDIN.
 
 <crt-file>
 :  File to write the certificate (PEM format)
@@ -56,6 +67,20 @@ $ TOKEN=$(step ca token internal.example.com)
 $ step ca sign --token $TOKEN --not-after=1h internal.csr internal.crt
 '''
 
+Sign a new certificate on a host without the root certificate, using a token
+generated on another host. The CA URL and the fingerprint of the root
+certificate are read from the token, and the root certificate is downloaded
+from the CA and checked against the fingerprint:
+'''
+$ step ca sign --token $TOKEN internal.csr internal.crt
+'''
+
+Validate a certificate request without contacting the CA:
+'''
+$ step ca sign --validate-only --min-key-strength 128 --token $TOKEN internal.csr internal.crt
+The certificate request internal.csr is valid.
+'''
+
 Sign a new certificate using the offline mode, requires the configuration
 files, certificates, and keys created with **step ca init**:
 '''
@@ -90,6 +115,19 @@ $ cat path/to/data.json
 $ step ca sign foo.csr foo.crt --set-file path/to/data.json
 '''
 
+Sign a CSR verifying the signature of the token before sending it to the CA:
+'''
+$ TOKEN=$(step ca token internal.example.com)
+$ step ca sign --token $TOKEN --verify-token internal.csr internal.crt
+'''
+
+Sign a CSR with a literal subject key identifier, using a provisioner with a
+template that sets the **subjectKeyId** field to
+**{{ toJson .Insecure.User.subjectKeyId }}**:
+'''
+$ step ca sign foo.csr foo.crt --ski 0a:1b:2c:3d:4e:5f
+'''
+
 **step CA ACME** - In order to use the step CA ACME protocol you must add a
 ACME provisioner to the step CA config. See **step ca provisioner add -h**.
 
@@ -123,6 +161,39 @@ $ step ca sign foo.csr foo.crt \
 			flags.NotAfter,
 			flags.TemplateSet,
 			flags.TemplateSetFile,
+			noEKUFlag,
+			strictFlag,
+			strictSANsFlag,
+			requireChainFlag,
+			warnExpiryFatalFlag,
+			onClampFlag,
+			chainOrderFlag,
+			lineEndingFlag,
+			clockSkewFlag,
+			allowedSignatureAlgsFlag,
+			showFlag,
+			preflightFlag,
+			syslogFlag,
+			syslogFacilityFlag,
+			syslogPriorityFlag,
+			minKeyStrengthFlag,
+			tlsCipherSuitesFlag,
+			maxResponseSizeFlag,
+			rootOutFlag,
+			skiFlag,
+			deadlineFromTokenFlag,
+			issuerHintFlag,
+			verifyTokenFlag,
+			crossSignedFlag,
+			sanPolicyFileFlag,
+			cli.BoolFlag{
+				Name: "validate-only",
+				Usage: `Validate the certificate request and exit without contacting the CA. It
+checks the signature of the certificate request, the strength of the key using
+the **--min-key-strength** flag, the syntax of the SANs, and if the **--token**
+flag is used, that the subject of the token matches the certificate request. All
+the problems found are reported.`,
+			},
 			flags.Force,
 			flags.Offline,
 			flags.PasswordFile,
@@ -158,7 +229,16 @@ func signCertificateAction(ctx *cli.Context) error {
 	tok := ctx.String("token")
 	offline := ctx.Bool("offline")
 
-	csrInt, err := pemutil.Read(csrFile)
+	if csrFile == "-" {
+		if err := checkSingleStdin(ctx, "<csr-file>"); err != nil {
+			return err
+		}
+	}
+	b, err := utils.ReadFile(csrFile)
+	if err != nil {
+		return err
+	}
+	csrInt, err := pemutil.Parse(b, pemutil.WithFilename(csrFile))
 	if err != nil {
 		return err
 	}
@@ -166,9 +246,30 @@ func signCertificateAction(ctx *cli.Context) error {
 	if !ok {
 		return errors.Errorf("error parsing %s: file is not a certificate request", csrFile)
 	}
+
+	if ctx.Bool("validate-only") {
+		return validateOnly(ctx, csrFile, csr, tok)
+	}
+
 	if err = csr.CheckSignature(); err != nil {
 		return errors.Wrapf(err, "csr has invalid signature")
 	}
+	if minStrength := ctx.Int("min-key-strength"); minStrength > 0 {
+		if err := checkKeyStrength(csr.PublicKey, minStrength); err != nil {
+			return err
+		}
+	}
+	if policyFile := ctx.String("san-policy-file"); policyFile != "" {
+		if err := checkCSRSANPolicy(policyFile, csr); err != nil {
+			return err
+		}
+	}
+
+	if ctx.Bool("syslog") {
+		if err := checkSyslog(ctx); err != nil {
+			return err
+		}
+	}
 
 	// offline and token are incompatible because the token is generated before
 	// the start of the offline CA.
@@ -176,6 +277,11 @@ func signCertificateAction(ctx *cli.Context) error {
 		return errs.IncompatibleFlagWithFlag(ctx, "offline", "token")
 	}
 
+	// The provisioner password is only used to generate a new token.
+	if tok != "" && ctx.String("provisioner-password-file") != "" {
+		return errs.IncompatibleFlagWithFlag(ctx, "token", "provisioner-password-file")
+	}
+
 	// certificate flow unifies online and offline flows on a single api
 	flow, err := cautils.NewCertificateFlow(ctx, cautils.WithCertificateRequest(csr))
 	if err != nil {
@@ -189,6 +295,11 @@ func signCertificateAction(ctx *cli.Context) error {
 		}
 		sans := ctx.StringSlice("san")
 		sans = mergeSans(sans, csr)
+		if ctx.Bool("preflight") && !offline {
+			if err := preflightCheck(ctx); err != nil {
+				return err
+			}
+		}
 		if tok, err = flow.GenerateToken(ctx, csr.Subject.CommonName, sans); err != nil {
 			var acmeTokenErr *cautils.ACMETokenError
 			if errors.As(err, &acmeTokenErr) {
@@ -203,6 +314,55 @@ func signCertificateAction(ctx *cli.Context) error {
 	if err != nil {
 		return errors.Wrap(err, "error parsing flag '--token'")
 	}
+	if err := checkTokenTime(ctx, jwt); err != nil {
+		return err
+	}
+	if err := checkTokenSubject(jwt, csr); err != nil {
+		return err
+	}
+
+	// Sign. Without --root, the client is configured with the CA URL and the
+	// root fingerprint in the token if it has them.
+	if err := flow.Sign(ctx, tok, api.NewCertificateRequest(csr), crtFile); err != nil {
+		return err
+	}
+
+	if ctx.Bool("show") {
+		if err := showCertificateChain(crtFile); err != nil {
+			return err
+		}
+	}
+
+	if ctx.Bool("syslog") {
+		leaf, err := readLeafCertificate(ctx, crtFile)
+		if err != nil {
+			return err
+		}
+		if err := logIssuance(ctx, leaf); err != nil {
+			return err
+		}
+	}
+
+	ui.PrintSelected("Certificate", crtFile)
+	return nil
+}
+
+// checkTokenTime prints a warning if the token is expired or not yet valid,
+// allowing the skew in the --clock-skew flag. With --strict it returns the
+// error instead.
+func checkTokenTime(ctx *cli.Context, jwt *token.JSONWebToken) error {
+	if err := jwt.Payload.ValidateTime(time.Now(), ctx.Duration("clock-skew")); err != nil {
+		if ctx.Bool("strict") {
+			return err
+		}
+		ui.Printf(`{{ "warning:" | yellow }} %s, the CA will likely reject it`+"\n", err)
+	}
+	return nil
+}
+
+// checkTokenSubject returns an error if the subject of the token does not
+// match the common name of the certificate request.
+func checkTokenSubject(jwt *token.JSONWebToken, csr *x509.CertificateRequest) error {
 	switch jwt.Payload.Type() {
 	case token.OIDC, token.AWS, token.GCP, token.Azure, token.K8sSA:
 		// Common name will be validated on the server side, it depends on
@@ -212,13 +372,6 @@ func signCertificateAction(ctx *cli.Context) error {
 			return errors.Errorf("token subject '%s' and CSR CommonName '%s' do not match", jwt.Payload.Subject, csr.Subject.CommonName)
 		}
 	}
-
-	// Sign
-	if err := flow.Sign(ctx, tok, api.NewCertificateRequest(csr), crtFile); err != nil {
-		return err
-	}
-
-	ui.PrintSelected("Certificate", crtFile)
 	return nil
 }
 
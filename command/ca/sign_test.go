@@ -2,11 +2,18 @@ package ca
 
 import (
 	"crypto/x509"
+	"flag"
 	"net"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+	"go.step.sm/crypto/jose"
+
+	"github.com/smallstep/cli/token"
 )
 
 func mustParseURI(t *testing.T, u string) (parsed *url.URL) {
@@ -85,3 +92,43 @@ func Test_mergeSans(t *testing.T) {
 		})
 	}
 }
+
+func Test_checkTokenTime(t *testing.T) {
+	now := time.Now()
+	newToken := func(nbf, exp time.Time) *token.JSONWebToken {
+		return &token.JSONWebToken{Payload: token.Payload{Claims: jose.Claims{
+			NotBefore: jose.NewNumericDate(nbf),
+			Expiry:    jose.NewNumericDate(exp),
+		}}}
+	}
+	newContext := func(strict bool) *cli.Context {
+		fs := flag.NewFlagSet("contrive", 0)
+		_ = fs.Bool("strict", strict, "")
+		_ = fs.Duration("clock-skew", time.Minute, "")
+		return cli.NewContext(&cli.App{}, fs, nil)
+	}
+
+	tests := []struct {
+		name    string
+		jwt     *token.JSONWebToken
+		strict  bool
+		wantErr bool
+	}{
+		{"ok", newToken(now, now.Add(5*time.Minute)), true, false},
+		{"ok/skew", newToken(now.Add(30*time.Second), now.Add(5*time.Minute)), true, false},
+		{"ok/expired", newToken(now.Add(-time.Hour), now.Add(-10*time.Minute)), false, false},
+		{"ok/not-yet-valid", newToken(now.Add(10*time.Minute), now.Add(time.Hour)), false, false},
+		{"fail/expired", newToken(now.Add(-time.Hour), now.Add(-10*time.Minute)), true, true},
+		{"fail/not-yet-valid", newToken(now.Add(10*time.Minute), now.Add(time.Hour)), true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkTokenTime(newContext(tt.strict), tt.jwt)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
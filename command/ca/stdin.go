@@ -0,0 +1,21 @@
+package ca
+
+import (
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+)
+
+// stdinFileFlags are the file flags that can use a hyphen ("-") to read from
+// STDIN.
+var stdinFileFlags = []string{"set-file", "provisioner-password-file", "password-file"}
+
+// checkSingleStdin returns an error if the given input and any of the file
+// flags are read from STDIN, STDIN can only be read once.
+func checkSingleStdin(ctx *cli.Context, input string) error {
+	for _, name := range stdinFileFlags {
+		if ctx.String(name) == "-" {
+			return errors.Errorf("cannot read %s and '--%s' from STDIN at the same time", input, name)
+		}
+	}
+	return nil
+}
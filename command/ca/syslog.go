@@ -0,0 +1,80 @@
+package ca
+
+import (
+	"crypto/x509"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+)
+
+// syslogFacilities are the names of the facilities supported by the
+// --syslog-facility flag, in the order of their syslog codes.
+var syslogFacilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "", "", "", "",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// syslogSeverities are the names of the severities supported by the
+// --syslog-priority flag, in the order of their syslog codes.
+var syslogSeverities = []string{
+	"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug",
+}
+
+// parseSyslogPriority returns the syslog priority code for the facility and
+// severity in the --syslog-facility and --syslog-priority flags.
+func parseSyslogPriority(ctx *cli.Context) (int, error) {
+	facility, severity := ctx.String("syslog-facility"), ctx.String("syslog-priority")
+	f := indexOf(syslogFacilities, strings.ToLower(facility))
+	if f < 0 {
+		return 0, errs.InvalidFlagValue(ctx, "syslog-facility", facility, "kern, user, mail, daemon, auth, syslog, lpr, news, uucp, cron, authpriv, ftp, local0-local7")
+	}
+	s := indexOf(syslogSeverities, strings.ToLower(severity))
+	if s < 0 {
+		return 0, errs.InvalidFlagValue(ctx, "syslog-priority", severity, strings.Join(syslogSeverities, ", "))
+	}
+	return f<<3 | s, nil
+}
+
+// syslogRecord returns the message sent to syslog for the given certificate.
+func syslogRecord(cert *x509.Certificate) string {
+	return fmt.Sprintf("issued certificate subject=%q serial=%s not-after=%s",
+		cert.Subject.CommonName, cert.SerialNumber.String(), cert.NotAfter.UTC().Format(time.RFC3339))
+}
+
+func indexOf(list []string, s string) int {
+	if s == "" {
+		return -1
+	}
+	for i, v := range list {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkSyslog returns an error if the --syslog flag cannot be used, because
+// the platform does not support syslog, or the priority flags are not valid.
+func checkSyslog(ctx *cli.Context) error {
+	if err := checkSyslogSupported(); err != nil {
+		return err
+	}
+	_, err := parseSyslogPriority(ctx)
+	return err
+}
+
+// logIssuance sends the issuance record of the certificate to the local
+// syslog, using the priority in the --syslog-facility and --syslog-priority
+// flags.
+func logIssuance(ctx *cli.Context, cert *x509.Certificate) error {
+	priority, err := parseSyslogPriority(ctx)
+	if err != nil {
+		return err
+	}
+	return writeSyslog(priority, syslogRecord(cert))
+}
@@ -0,0 +1,18 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package ca
+
+import (
+	"runtime"
+
+	"github.com/pkg/errors"
+)
+
+func checkSyslogSupported() error {
+	return errors.Errorf("flag '--syslog' is not supported on %s", runtime.GOOS)
+}
+
+func writeSyslog(int, string) error {
+	return checkSyslogSupported()
+}
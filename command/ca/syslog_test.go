@@ -0,0 +1,56 @@
+package ca
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"flag"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+)
+
+func Test_parseSyslogPriority(t *testing.T) {
+	tests := []struct {
+		name     string
+		facility string
+		priority string
+		want     int
+		wantErr  bool
+	}{
+		{"ok/user-info", "user", "info", 14, false},
+		{"ok/local0-err", "LOCAL0", "err", 131, false},
+		{"ok/kern-emerg", "kern", "emerg", 0, false},
+		{"fail/facility", "foo", "info", 0, true},
+		{"fail/reserved-facility", "", "info", 0, true},
+		{"fail/priority", "user", "error", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := flag.NewFlagSet("contrive", 0)
+			fs.String("syslog-facility", tt.facility, "")
+			fs.String("syslog-priority", tt.priority, "")
+			ctx := cli.NewContext(&cli.App{}, fs, nil)
+
+			got, err := parseSyslogPriority(ctx)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_syslogRecord(t *testing.T) {
+	cert := &x509.Certificate{
+		Subject:      pkix.Name{CommonName: "foo\nbar"},
+		SerialNumber: big.NewInt(1234),
+		NotAfter:     time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	assert.Equal(t, `issued certificate subject="foo\nbar" serial=1234 not-after=2030-01-02T03:04:05Z`, syslogRecord(cert))
+}
@@ -0,0 +1,26 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package ca
+
+import (
+	"log/syslog"
+
+	"github.com/pkg/errors"
+)
+
+func checkSyslogSupported() error {
+	return nil
+}
+
+func writeSyslog(priority int, msg string) error {
+	w, err := syslog.New(syslog.Priority(priority), "step")
+	if err != nil {
+		return errors.Wrap(err, "error connecting to syslog")
+	}
+	defer w.Close()
+	if _, err := w.Write([]byte(msg)); err != nil {
+		return errors.Wrap(err, "error writing to syslog")
+	}
+	return nil
+}
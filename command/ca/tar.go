@@ -0,0 +1,150 @@
+package ca
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/certificates/pki"
+	"go.step.sm/crypto/pemutil"
+
+	"github.com/smallstep/cli/utils"
+	"github.com/smallstep/cli/utils/cautils"
+)
+
+// tarEntry is a file in the tarball written by the --tar-out flag.
+type tarEntry struct {
+	Name string
+	Mode int64
+	Data []byte
+}
+
+// writeTarBundle writes the leaf certificate, the intermediates, the private
+// key, and the root certificate that validates the chain in the tarball
+// filename. The tarball is compressed with gzip if the filename ends with
+// .tar.gz or .tgz, and the PEM files use CRLF line endings if crlf is true.
+func writeTarBundle(ctx *cli.Context, filename, crtFile string, pk crypto.PrivateKey, roots []*x509.Certificate, crlf bool) error {
+	certs, err := readCertificateChain(ctx, crtFile)
+	if err != nil {
+		return err
+	}
+	root, err := bundleRoot(roots, certs)
+	if err != nil {
+		return err
+	}
+
+	keyBlock, err := pemutil.Serialize(pk)
+	if err != nil {
+		return err
+	}
+
+	entries := []tarEntry{
+		{"cert.pem", 0644, cautils.FormatLineEnding(encodeCertificates(certs[:1]), crlf)},
+		{"chain.pem", 0644, cautils.FormatLineEnding(encodeCertificates(certs[1:]), crlf)},
+		{"key.pem", 0600, cautils.FormatLineEnding(pem.EncodeToMemory(keyBlock), crlf)},
+		{"root.pem", 0644, cautils.FormatLineEnding(encodeCertificates([]*x509.Certificate{root}), crlf)},
+	}
+
+	var buf bytes.Buffer
+	if err := writeTar(&buf, entries, isGzipFilename(filename)); err != nil {
+		return errors.Wrap(err, "error creating tarball")
+	}
+	return utils.WriteFile(filename, buf.Bytes(), 0600)
+}
+
+// readCertificateChain reads the certificate chain in crtFile, written using
+// the order in the --chain-order flag, and returns it with the leaf first.
+func readCertificateChain(ctx *cli.Context, crtFile string) ([]*x509.Certificate, error) {
+	certs, err := pemutil.ReadCertificateBundle(crtFile)
+	if err != nil {
+		return nil, err
+	}
+	if ctx.String("chain-order") == "leaf-last" {
+		for i, j := 0, len(certs)-1; i < j; i, j = i+1, j-1 {
+			certs[i], certs[j] = certs[j], certs[i]
+		}
+	}
+	return certs, nil
+}
+
+// readBundleRoots reads the root certificates for the bundle written by the
+// given flag, from the --root flag or the default location. They are read
+// before requesting the certificate, so a missing root does not waste it.
+func readBundleRoots(ctx *cli.Context, flagName string) ([]*x509.Certificate, error) {
+	rootFile := ctx.String("root")
+	if rootFile == "" {
+		rootFile = pki.GetRootCAPath()
+	}
+	roots, err := pemutil.ReadCertificateBundle(rootFile)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading the root certificate required by '--%s'", flagName)
+	}
+	return roots, nil
+}
+
+// bundleRoot returns the root certificate in roots that validates the chain,
+// with the leaf first.
+func bundleRoot(roots, chain []*x509.Certificate) (*x509.Certificate, error) {
+	pool := x509.NewCertPool()
+	for _, crt := range roots {
+		pool.AddCert(crt)
+	}
+	return cautils.EffectiveRoot(pool, chain)
+}
+
+// writeTar writes the entries as a tar archive in w.
+func writeTar(w io.Writer, entries []tarEntry, gz bool) error {
+	var zw *gzip.Writer
+	if gz {
+		zw = gzip.NewWriter(w)
+		w = zw
+	}
+
+	tw := tar.NewWriter(w)
+	now := time.Now()
+	for _, e := range entries {
+		if err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     e.Name,
+			Mode:     e.Mode,
+			Size:     int64(len(e.Data)),
+			ModTime:  now,
+		}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(e.Data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if zw != nil {
+		return zw.Close()
+	}
+	return nil
+}
+
+func isGzipFilename(filename string) bool {
+	return strings.HasSuffix(filename, ".tar.gz") || strings.HasSuffix(filename, ".tgz")
+}
+
+func encodeCertificates(certs []*x509.Certificate) []byte {
+	var data []byte
+	for _, crt := range certs {
+		data = append(data, pem.EncodeToMemory(&pem.Block{
+			Type:  "CERTIFICATE",
+			Bytes: crt.Raw,
+		})...)
+	}
+	return data
+}
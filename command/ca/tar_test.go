@@ -0,0 +1,81 @@
+package ca
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/crypto/minica"
+)
+
+func Test_writeTar(t *testing.T) {
+	entries := []tarEntry{
+		{"cert.pem", 0644, []byte("cert")},
+		{"key.pem", 0600, []byte("key")},
+	}
+
+	for _, gz := range []bool{false, true} {
+		var buf bytes.Buffer
+		require.NoError(t, writeTar(&buf, entries, gz))
+
+		var r io.Reader = &buf
+		if gz {
+			zr, err := gzip.NewReader(&buf)
+			require.NoError(t, err)
+			r = zr
+		}
+
+		var got []tarEntry
+		tr := tar.NewReader(r)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			data, err := io.ReadAll(tr)
+			require.NoError(t, err)
+			got = append(got, tarEntry{hdr.Name, hdr.Mode, data})
+		}
+		assert.Equal(t, entries, got)
+	}
+}
+
+func Test_isGzipFilename(t *testing.T) {
+	assert.True(t, isGzipFilename("bundle.tar.gz"))
+	assert.True(t, isGzipFilename("bundle.tgz"))
+	assert.False(t, isGzipFilename("bundle.tar"))
+}
+
+func Test_bundleRoot(t *testing.T) {
+	ca, err := minica.New()
+	require.NoError(t, err)
+	other, err := minica.New()
+	require.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	leaf, err := ca.Sign(&x509.Certificate{
+		Subject:   pkix.Name{CommonName: "foo"},
+		DNSNames:  []string{"foo.internal"},
+		PublicKey: key.Public(),
+	})
+	require.NoError(t, err)
+	chain := []*x509.Certificate{leaf, ca.Intermediate}
+
+	root, err := bundleRoot([]*x509.Certificate{other.Root, ca.Root}, chain)
+	require.NoError(t, err)
+	assert.Equal(t, ca.Root, root)
+
+	_, err = bundleRoot([]*x509.Certificate{other.Root}, chain)
+	assert.Error(t, err)
+}
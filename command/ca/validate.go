@@ -0,0 +1,87 @@
+package ca
+
+import (
+	"crypto/x509"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli/token"
+)
+
+// validateOnly runs the checks of the --validate-only flag of step ca sign
+// and returns an error with all the problems found.
+func validateOnly(ctx *cli.Context, csrFile string, csr *x509.CertificateRequest, tok string) error {
+	var jwt *token.JSONWebToken
+	if tok != "" {
+		var err error
+		if jwt, err = token.ParseInsecure(tok); err != nil {
+			return errors.Wrap(err, "error parsing flag '--token'")
+		}
+	}
+
+	problems := validateCertificateRequest(csr, jwt, ctx.Int("min-key-strength"))
+	if len(problems) > 0 {
+		return errors.Errorf("the certificate request %s is not valid:\n  - %s", csrFile, strings.Join(problems, "\n  - "))
+	}
+
+	fmt.Printf("The certificate request %s is valid.\n", csrFile)
+	return nil
+}
+
+// validateCertificateRequest returns the list of problems found in the
+// certificate request. The token checks are skipped if jwt is nil.
+func validateCertificateRequest(csr *x509.CertificateRequest, jwt *token.JSONWebToken, minStrength int) []string {
+	var problems []string
+	if err := csr.CheckSignature(); err != nil {
+		problems = append(problems, "invalid signature: "+err.Error())
+	}
+	if err := checkKeyStrength(csr.PublicKey, minStrength); err != nil {
+		problems = append(problems, err.Error())
+	}
+	for _, name := range csr.DNSNames {
+		if !isValidDNSName(name) {
+			problems = append(problems, fmt.Sprintf("invalid DNS name %q", name))
+		}
+	}
+	for _, email := range csr.EmailAddresses {
+		if i := strings.LastIndex(email, "@"); i <= 0 || !isValidDNSName(email[i+1:]) {
+			problems = append(problems, fmt.Sprintf("invalid email address %q", email))
+		}
+	}
+	for _, u := range csr.URIs {
+		if u.Scheme == "" || (u.Host == "" && u.Opaque == "" && u.Path == "") {
+			problems = append(problems, fmt.Sprintf("invalid URI %q", u.String()))
+		}
+	}
+	if jwt != nil {
+		if err := checkTokenSubject(jwt, csr); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	return problems
+}
+
+// isValidDNSName returns true if name is a valid hostname, optionally with a
+// wildcard as the first label.
+func isValidDNSName(name string) bool {
+	name = strings.TrimPrefix(strings.TrimSuffix(name, "."), "*.")
+	if name == "" || len(name) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			switch {
+			case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_':
+			default:
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package ca
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/crypto/jose"
+
+	"github.com/smallstep/cli/token"
+)
+
+func Test_validateCertificateRequest(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	newCSR := func(t *testing.T, template *x509.CertificateRequest) *x509.CertificateRequest {
+		t.Helper()
+		der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+		require.NoError(t, err)
+		csr, err := x509.ParseCertificateRequest(der)
+		require.NoError(t, err)
+		return csr
+	}
+	newToken := func(t *testing.T, subject string) *token.JSONWebToken {
+		t.Helper()
+		claims, err := token.NewClaims(token.WithSubject(subject), token.WithSHA("a-sha"))
+		require.NoError(t, err)
+		tok, err := claims.Sign(jose.ES256, key)
+		require.NoError(t, err)
+		jwt, err := token.ParseInsecure(tok)
+		require.NoError(t, err)
+		return jwt
+	}
+
+	valid := newCSR(t, &x509.CertificateRequest{
+		Subject:        pkix.Name{CommonName: "foo.internal"},
+		DNSNames:       []string{"foo.internal", "*.foo.internal"},
+		EmailAddresses: []string{"jane@example.com"},
+		URIs:           []*url.URL{{Scheme: "spiffe", Host: "example.com", Path: "/foo"}},
+	})
+	invalid := newCSR(t, &x509.CertificateRequest{
+		Subject:        pkix.Name{CommonName: "foo.internal"},
+		DNSNames:       []string{"foo..internal", "-foo.internal"},
+		EmailAddresses: []string{"jane@"},
+	})
+	badSignature := newCSR(t, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "foo.internal"},
+	})
+	badSignature.Signature[len(badSignature.Signature)-1] ^= 0xff
+
+	tests := []struct {
+		name        string
+		csr         *x509.CertificateRequest
+		jwt         *token.JSONWebToken
+		minStrength int
+		want        int
+	}{
+		{"ok", valid, nil, 0, 0},
+		{"ok/token", valid, newToken(t, "foo.internal"), 128, 0},
+		{"fail/strength", valid, nil, 192, 1},
+		{"fail/token", valid, newToken(t, "bar.internal"), 0, 1},
+		{"fail/sans", invalid, nil, 0, 3},
+		{"fail/signature", badSignature, nil, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := validateCertificateRequest(tt.csr, tt.jwt, tt.minStrength)
+			assert.Len(t, got, tt.want, got)
+		})
+	}
+}
+
+func Test_isValidDNSName(t *testing.T) {
+	for _, name := range []string{"foo", "foo.internal", "*.foo.internal", "foo-bar.internal.", "_acme.foo.internal"} {
+		assert.True(t, isValidDNSName(name), name)
+	}
+	for _, name := range []string{"", "*", "foo..internal", "-foo.internal", "foo-.internal", "foo bar.internal", "foo.*.internal"} {
+		assert.False(t, isValidDNSName(name), name)
+	}
+}
@@ -0,0 +1,59 @@
+package ca
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+
+	"github.com/pkg/errors"
+	"go.step.sm/crypto/pemutil"
+)
+
+// verifyKeyPair checks that the serialized private key in keyPEM can sign
+// a random nonce, and that the signature verifies with the public key of the
+// leaf certificate.
+func verifyKeyPair(leaf *x509.Certificate, keyPEM []byte) error {
+	key, err := pemutil.ParseKey(keyPEM)
+	if err != nil {
+		return errors.Wrap(err, "error verifying private key: the serialized key cannot be parsed")
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return errors.Errorf("error verifying private key: %T is not a signer", key)
+	}
+
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return errors.Wrap(err, "error generating nonce")
+	}
+
+	var opts crypto.SignerOpts = crypto.SHA256
+	digest := sha256.Sum256(nonce)
+	msg := digest[:]
+	if _, ok := signer.Public().(ed25519.PublicKey); ok {
+		opts, msg = crypto.Hash(0), nonce
+	}
+	sig, err := signer.Sign(rand.Reader, msg, opts)
+	if err != nil {
+		return errors.Wrap(err, "error verifying private key: error signing nonce")
+	}
+
+	switch pub := leaf.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		ok = ecdsa.VerifyASN1(pub, msg, sig)
+	case *rsa.PublicKey:
+		ok = rsa.VerifyPKCS1v15(pub, crypto.SHA256, msg, sig) == nil
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(pub, msg, sig)
+	default:
+		return errors.Errorf("error verifying private key: unsupported public key type %T", pub)
+	}
+	if !ok {
+		return errors.New("error verifying private key: the signature does not verify with the public key of the certificate")
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package ca
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/command"
+	"github.com/smallstep/cli-utils/errs"
+	"go.step.sm/crypto/pemutil"
+)
+
+func whoamiCommand() cli.Command {
+	return cli.Command{
+		Name:      "whoami",
+		Action:    command.ActionFunc(whoamiAction),
+		Usage:     "show the identity of a certificate",
+		UsageText: `**step ca whoami** **--cert**=<crt-file> [**--json**]`,
+		Description: `**step ca whoami** prints the identity asserted by a certificate: the subject,
+the SANs, the SPIFFE ID if the certificate has one, the validity, and the
+issuer. If <crt-file> contains a certificate chain, the identity of the leaf
+certificate is printed. Use **step certificate inspect** to see all the details
+of the certificate.
+
+## EXAMPLES
+
+Show the identity of a certificate:
+'''
+$ step ca whoami --cert internal.crt
+Subject:    CN=internal.example.com
+SANs:       internal.example.com, 10.0.0.1
+Not Before: 2024-05-01T10:00:00Z
+Not After:  2024-05-02T10:00:00Z
+Issuer:     CN=Smallstep Intermediate CA,O=Smallstep
+'''
+
+Show the identity of a certificate in JSON:
+'''
+$ step ca whoami --cert internal.crt --json
+'''`,
+		Flags: []cli.Flag{
+			cli.StringFlag{
+				Name:  "cert",
+				Usage: `The <crt-file> with the certificate or the certificate chain.`,
+			},
+			cli.BoolFlag{
+				Name:  "json",
+				Usage: `Print the identity in JSON format.`,
+			},
+		},
+	}
+}
+
+// certificateIdentity is the identity of a certificate printed by
+// step ca whoami.
+type certificateIdentity struct {
+	Subject   string    `json:"subject"`
+	SANs      []string  `json:"sans"`
+	SPIFFEID  string    `json:"spiffeID,omitempty"`
+	NotBefore time.Time `json:"notBefore"`
+	NotAfter  time.Time `json:"notAfter"`
+	Issuer    string    `json:"issuer"`
+}
+
+func whoamiAction(ctx *cli.Context) error {
+	if err := errs.NumberOfArguments(ctx, 0); err != nil {
+		return err
+	}
+	crtFile := ctx.String("cert")
+	if crtFile == "" {
+		return errs.RequiredFlag(ctx, "cert")
+	}
+
+	certs, err := pemutil.ReadCertificateBundle(crtFile)
+	if err != nil {
+		return err
+	}
+	id := newCertificateIdentity(leafCertificate(certs))
+
+	if ctx.Bool("json") {
+		b, err := json.MarshalIndent(id, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "error marshaling identity")
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+	writeCertificateIdentity(os.Stdout, id)
+	return nil
+}
+
+// leafCertificate returns the first certificate that is not a CA in the
+// chain, or the first certificate if all of them are CAs.
+func leafCertificate(certs []*x509.Certificate) *x509.Certificate {
+	for _, crt := range certs {
+		if !crt.IsCA {
+			return crt
+		}
+	}
+	return certs[0]
+}
+
+func newCertificateIdentity(crt *x509.Certificate) certificateIdentity {
+	id := certificateIdentity{
+		Subject:   crt.Subject.String(),
+		SANs:      certificateSANs(crt),
+		NotBefore: crt.NotBefore.UTC(),
+		NotAfter:  crt.NotAfter.UTC(),
+		Issuer:    crt.Issuer.String(),
+	}
+	for _, u := range crt.URIs {
+		if strings.EqualFold(u.Scheme, "spiffe") {
+			id.SPIFFEID = u.String()
+			break
+		}
+	}
+	return id
+}
+
+func writeCertificateIdentity(w io.Writer, id certificateIdentity) {
+	fmt.Fprintf(w, "Subject:    %s\n", id.Subject)
+	fmt.Fprintf(w, "SANs:       %s\n", strings.Join(id.SANs, ", "))
+	if id.SPIFFEID != "" {
+		fmt.Fprintf(w, "SPIFFE ID:  %s\n", id.SPIFFEID)
+	}
+	fmt.Fprintf(w, "Not Before: %s\n", id.NotBefore.Format(time.RFC3339))
+	fmt.Fprintf(w, "Not After:  %s\n", id.NotAfter.Format(time.RFC3339))
+	fmt.Fprintf(w, "Issuer:     %s\n", id.Issuer)
+}
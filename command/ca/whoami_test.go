@@ -0,0 +1,55 @@
+package ca
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/crypto/minica"
+)
+
+func Test_newCertificateIdentity(t *testing.T) {
+	ca, err := minica.New(minica.WithName("Test"))
+	require.NoError(t, err)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	notBefore := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	crt, err := ca.Sign(&x509.Certificate{
+		Subject:   pkix.Name{CommonName: "web"},
+		DNSNames:  []string{"web.internal"},
+		URIs:      []*url.URL{{Scheme: "https", Host: "example.org"}, {Scheme: "spiffe", Host: "example.org", Path: "/web"}},
+		PublicKey: key.Public(),
+		NotBefore: notBefore,
+		NotAfter:  notBefore.Add(24 * time.Hour),
+	})
+	require.NoError(t, err)
+
+	id := newCertificateIdentity(leafCertificate([]*x509.Certificate{ca.Intermediate, crt}))
+	assert.Equal(t, certificateIdentity{
+		Subject:   "CN=web",
+		SANs:      []string{"web.internal", "https://example.org", "spiffe://example.org/web"},
+		SPIFFEID:  "spiffe://example.org/web",
+		NotBefore: notBefore,
+		NotAfter:  notBefore.Add(24 * time.Hour),
+		Issuer:    "CN=Test Intermediate CA",
+	}, id)
+
+	var buf bytes.Buffer
+	writeCertificateIdentity(&buf, id)
+	assert.Equal(t, `Subject:    CN=web
+SANs:       web.internal, https://example.org, spiffe://example.org/web
+SPIFFE ID:  spiffe://example.org/web
+Not Before: 2024-05-01T10:00:00Z
+Not After:  2024-05-02T10:00:00Z
+Issuer:     CN=Test Intermediate CA
+`, buf.String())
+}
@@ -51,6 +51,7 @@ func createCommand() cli.Command {
 [**--ca-key**=<issuer-key>] [**--ca-password-file**=<file>]
 [**--kms**=<uri>] [**--key**=<file>] [**--password-file**=<file>]
 [**--bundle**] [**--skip-csr-signature**]
+[**--csr-prepare**] [**--csr-assemble**=<file>]
 [**--no-password**] [**--subtle**] [**--insecure**]`,
 		Description: `**step certificate create** generates a certificate or a
 certificate signing request (CSR) that can be signed later using 'step
@@ -148,6 +149,15 @@ Create a CSR using an existing encrypted private key:
 $ step certificate create --csr --key key.priv --password-file key.pass foo foo.csr
 '''
 
+Create a CSR in two phases for a key in an external signer, using the public key
+and signing the to-be-signed data with the external signer:
+'''
+$ step certificate create --csr --csr-prepare --key pub.pem foo foo.tbs
+The data to sign with ECDSA-SHA256 has been saved in foo.tbs.
+$ openssl dgst -sha256 -sign key.pem -out foo.sig foo.tbs
+$ step certificate create --csr --csr-assemble foo.sig --key pub.pem foo foo.csr
+'''
+
 Create a CSR and key with custom Subject Alternative Names:
 
 '''
@@ -385,6 +395,22 @@ $ step certificate create \
 				Name:  "csr",
 				Usage: `Generate a certificate signing request (CSR) instead of a certificate.`,
 			},
+			cli.BoolFlag{
+				Name: "csr-prepare",
+				Usage: `Write the DER encoded to-be-signed data of the certificate request to
+<crt-file> instead of the certificate request, so it can be signed with an
+external signer, e.g. a cloud KMS. Requires the **--csr** flag and a public key
+in the **--key** flag. The signature algorithm to use is printed.`,
+			},
+			cli.StringFlag{
+				Name: "csr-assemble",
+				Usage: `Add the external signature in the <file> to the to-be-signed data created with
+the **--csr-prepare** flag, and write the signed certificate request to
+<crt-file>. The <subject>, the SANs, the template, and the public key must be
+the same used with **--csr-prepare**. The command fails if the signature does
+not verify with the public key. ECDSA signatures can be ASN.1 encoded or the
+concatenation of r and s.`,
+			},
 			cli.StringFlag{
 				Name:  "profile",
 				Value: profileLeaf,
@@ -532,6 +558,27 @@ func createAction(ctx *cli.Context) error {
 		return errs.IncompatibleFlagWithFlag(ctx, "csr", "skip-csr-signature")
 	}
 
+	// Two-phase CSR with an external signer.
+	csrPrepare := ctx.Bool("csr-prepare")
+	csrAssemble := ctx.String("csr-assemble")
+	if csrPrepare || csrAssemble != "" {
+		name := "csr-prepare"
+		if csrAssemble != "" {
+			name = "csr-assemble"
+		}
+		switch {
+		case csrPrepare && csrAssemble != "":
+			return errs.IncompatibleFlagWithFlag(ctx, "csr-prepare", "csr-assemble")
+		case !ctx.Bool("csr"):
+			return errs.RequiredWithFlag(ctx, name, "csr")
+		case ctx.String("key") == "":
+			return errs.RequiredWithFlag(ctx, name, "key")
+		}
+		if err := errs.NumberOfArguments(ctx, 2); err != nil {
+			return err
+		}
+	}
+
 	// Read template if passed
 	var template string
 	if templateFile != "" {
@@ -557,7 +604,12 @@ func createAction(ctx *cli.Context) error {
 
 	// Create certificate request
 	if ctx.Bool("csr") {
-		if priv == nil {
+		switch {
+		case csrPrepare || csrAssemble != "":
+			if priv != nil {
+				return errors.New("invalid value for flag --key: a public key is required with --csr-prepare or --csr-assemble")
+			}
+		case priv == nil:
 			return errors.New("invalid value for flag --key: a private key is required")
 		}
 		if bundle {
@@ -595,6 +647,9 @@ func createAction(ctx *cli.Context) error {
 		// Create certificate request
 		data := x509util.CreateTemplateData(subject, sans)
 		data.SetUserData(userData)
+		if csrPrepare || csrAssemble != "" {
+			return createExternalCertificateRequest(crtFile, csrAssemble, pub, x509util.WithTemplate(template, data))
+		}
 		csr, err := x509util.NewCertificateRequest(priv, x509util.WithTemplate(template, data))
 		if err != nil {
 			return err
@@ -759,6 +814,41 @@ func createAction(ctx *cli.Context) error {
 	return nil
 }
 
+// createExternalCertificateRequest writes the to-be-signed data of the
+// certificate request for the public key if sigFile is empty, or the
+// certificate request signed with the signature in sigFile.
+func createExternalCertificateRequest(crtFile, sigFile string, pub crypto.PublicKey, opts ...x509util.Option) error {
+	cr, err := createUnsignedCertificateRequest(pub, opts...)
+	if err != nil {
+		return err
+	}
+
+	if sigFile == "" {
+		if err := utils.WriteFile(crtFile, cr.RawTBSCertificateRequest, 0600); err != nil {
+			return errs.FileError(err, crtFile)
+		}
+		ui.Printf("The data to sign with %s has been saved in %s.\n", cr.SignatureAlgorithm, crtFile)
+		return nil
+	}
+
+	sig, err := utils.ReadFile(sigFile)
+	if err != nil {
+		return err
+	}
+	if cr, err = assembleCertificateRequest(cr, sig); err != nil {
+		return err
+	}
+	block, err := pemutil.Serialize(cr)
+	if err != nil {
+		return err
+	}
+	if err := utils.WriteFile(crtFile, pem.EncodeToMemory(block), 0600); err != nil {
+		return errs.FileError(err, crtFile)
+	}
+	ui.Printf("Your certificate signing request has been saved in %s.\n", crtFile)
+	return nil
+}
+
 func parseOrCreateKey(ctx *cli.Context) (crypto.PublicKey, crypto.Signer, error) {
 	var (
 		kms     = ctx.String("kms")
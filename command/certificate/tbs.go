@@ -0,0 +1,146 @@
+package certificate
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+
+	"github.com/pkg/errors"
+	"go.step.sm/crypto/x509util"
+)
+
+// certificateRequestASN1 is the ASN.1 structure of a certificate request, as
+// defined in RFC 2986.
+type certificateRequestASN1 struct {
+	TBSCSR             asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	SignatureValue     asn1.BitString
+}
+
+// tbsCertificateRequestASN1 is the ASN.1 structure of the to-be-signed data of
+// a certificate request, as defined in RFC 2986.
+type tbsCertificateRequestASN1 struct {
+	Version       int
+	Subject       asn1.RawValue
+	PublicKeyInfo asn1.RawValue
+	RawAttributes asn1.RawValue
+}
+
+// createUnsignedCertificateRequest returns a certificate request for the
+// public key with an invalid signature. The certificate request created from
+// the same template and public key is always the same, so the to-be-signed
+// bytes in the --csr-prepare and --csr-assemble flags match.
+//
+// The standard library verifies the signature of new certificate requests,
+// the request is signed with a temporary key of the same type, and its public
+// key is replaced with the given one.
+func createUnsignedCertificateRequest(pub crypto.PublicKey, opts ...x509util.Option) (*x509.CertificateRequest, error) {
+	signer, err := temporarySigner(pub)
+	if err != nil {
+		return nil, err
+	}
+	csr, err := x509util.NewCertificateRequest(signer, opts...)
+	if err != nil {
+		return nil, err
+	}
+	cr, err := csr.GetCertificateRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	var req certificateRequestASN1
+	if _, err := asn1.Unmarshal(cr.Raw, &req); err != nil {
+		return nil, errors.Wrap(err, "error parsing certificate request")
+	}
+	var tbs tbsCertificateRequestASN1
+	if _, err := asn1.Unmarshal(req.TBSCSR.FullBytes, &tbs); err != nil {
+		return nil, errors.Wrap(err, "error parsing certificate request")
+	}
+	spki, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling public key")
+	}
+	tbs.PublicKeyInfo = asn1.RawValue{FullBytes: spki}
+	if req.TBSCSR.FullBytes, err = asn1.Marshal(tbs); err != nil {
+		return nil, errors.Wrap(err, "error creating certificate request")
+	}
+	req.SignatureValue = asn1.BitString{}
+
+	der, err := asn1.Marshal(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating certificate request")
+	}
+	return x509.ParseCertificateRequest(der)
+}
+
+// temporarySigner returns a new key of the same type as pub, it is used to
+// sign a certificate request with the same signature algorithm.
+func temporarySigner(pub crypto.PublicKey) (crypto.Signer, error) {
+	switch k := pub.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.GenerateKey(k.Curve, rand.Reader)
+	case *rsa.PublicKey:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case ed25519.PublicKey:
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		return priv, err
+	default:
+		return nil, errors.Errorf("unsupported public key type %T", pub)
+	}
+}
+
+// assembleCertificateRequest adds the external signature to the unsigned
+// certificate request, and returns the signed certificate request after
+// verifying the signature with the public key. ECDSA signatures can be in
+// ASN.1 format, or the concatenation of r and s used by PKCS #11.
+func assembleCertificateRequest(unsigned *x509.CertificateRequest, signature []byte) (*x509.CertificateRequest, error) {
+	var req certificateRequestASN1
+	if _, err := asn1.Unmarshal(unsigned.Raw, &req); err != nil {
+		return nil, errors.Wrap(err, "error parsing certificate request")
+	}
+
+	if pub, ok := unsigned.PublicKey.(*ecdsa.PublicKey); ok {
+		signature = ecdsaSignatureToASN1(pub, signature)
+	}
+	req.SignatureValue = asn1.BitString{Bytes: signature, BitLength: len(signature) * 8}
+
+	der, err := asn1.Marshal(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating certificate request")
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing certificate request")
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, errors.Wrap(err, "error verifying certificate request: the signature does not match the public key")
+	}
+	return csr, nil
+}
+
+// ecdsaSignatureToASN1 converts a signature with the concatenation of r and s
+// to ASN.1. Signatures in ASN.1 are returned unmodified.
+func ecdsaSignatureToASN1(pub *ecdsa.PublicKey, signature []byte) []byte {
+	size := (pub.Curve.Params().BitSize + 7) / 8
+	if len(signature) != 2*size {
+		return signature
+	}
+	var sig struct{ R, S *big.Int }
+	if rest, err := asn1.Unmarshal(signature, &sig); err == nil && len(rest) == 0 {
+		return signature
+	}
+	b, err := asn1.Marshal(struct{ R, S *big.Int }{
+		R: new(big.Int).SetBytes(signature[:size]),
+		S: new(big.Int).SetBytes(signature[size:]),
+	})
+	if err != nil {
+		return signature
+	}
+	return b
+}
@@ -0,0 +1,86 @@
+package certificate
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/crypto/x509util"
+)
+
+func Test_assembleCertificateRequest(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	sha256Sign := func(key crypto.Signer) func([]byte) []byte {
+		return func(tbs []byte) []byte {
+			sum := sha256.Sum256(tbs)
+			sig, err := key.Sign(rand.Reader, sum[:], crypto.SHA256)
+			require.NoError(t, err)
+			return sig
+		}
+	}
+	rawECDSASign := func(tbs []byte) []byte {
+		sum := sha256.Sum256(tbs)
+		r, s, err := ecdsa.Sign(rand.Reader, ecKey, sum[:])
+		require.NoError(t, err)
+		sig := make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+		return sig
+	}
+
+	tests := []struct {
+		name    string
+		key     crypto.Signer
+		sign    func([]byte) []byte
+		wantErr bool
+	}{
+		{"ok/ecdsa", ecKey, sha256Sign(ecKey), false},
+		{"ok/ecdsa-raw", ecKey, rawECDSASign, false},
+		{"ok/rsa", rsaKey, sha256Sign(rsaKey), false},
+		{"ok/ed25519", edKey, func(tbs []byte) []byte {
+			sig, err := edKey.Sign(rand.Reader, tbs, crypto.Hash(0))
+			require.NoError(t, err)
+			return sig
+		}, false},
+		{"fail/other-data", ecKey, func([]byte) []byte {
+			return sha256Sign(ecKey)([]byte("other data"))
+		}, true},
+		{"fail/empty", ecKey, func([]byte) []byte { return nil }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := x509util.CreateTemplateData("foo", []string{"foo.internal"})
+			opt := x509util.WithTemplate(x509util.DefaultCertificateRequestTemplate, data)
+
+			// The to-be-signed data is the same in both phases.
+			prepared, err := createUnsignedCertificateRequest(tt.key.Public(), opt)
+			require.NoError(t, err)
+			unsigned, err := createUnsignedCertificateRequest(tt.key.Public(), opt)
+			require.NoError(t, err)
+			require.Equal(t, prepared.RawTBSCertificateRequest, unsigned.RawTBSCertificateRequest)
+
+			csr, err := assembleCertificateRequest(unsigned, tt.sign(prepared.RawTBSCertificateRequest))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "foo", csr.Subject.CommonName)
+			assert.Equal(t, []string{"foo.internal"}, csr.DNSNames)
+			assert.NoError(t, csr.CheckSignature())
+		})
+	}
+}
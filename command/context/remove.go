@@ -15,6 +15,7 @@ import (
 	"github.com/smallstep/cli-utils/ui"
 
 	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/utils"
 )
 
 func removeCommand() cli.Command {
@@ -109,7 +110,12 @@ func removeAction(ctx *cli.Context) error {
 			return err
 		}
 	}
-	if err := cs.Remove(name); err != nil {
+	unlock, err := utils.LockStepPath()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := utils.RemoveContext(name); err != nil {
 		return err
 	}
 
@@ -5,10 +5,10 @@ import (
 
 	"github.com/smallstep/cli-utils/command"
 	"github.com/smallstep/cli-utils/errs"
-	"github.com/smallstep/cli-utils/step"
 	"github.com/smallstep/cli-utils/ui"
 
 	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/utils"
 )
 
 func selectCommand() cli.Command {
@@ -36,7 +36,12 @@ func selectAction(ctx *cli.Context) error {
 		return err
 	}
 	name := ctx.Args().Get(0)
-	if err := step.Contexts().SaveCurrent(name); err != nil {
+	unlock, err := utils.LockStepPath()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := utils.SaveCurrentContext(name); err != nil {
 		return err
 	}
 	ui.PrintSelected("Context", name)
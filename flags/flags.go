@@ -462,6 +462,14 @@ flag exists so it can be configured in $STEPPATH/config/defaults.json.`,
 		Usage: "An ACME EAB Key ID.",
 	}
 
+	// EABKeyFile is a cli.Flag that points to the file with the HMAC key of an
+	// ACME EAB
+	EABKeyFile = cli.StringFlag{
+		Name: "eab-key-file",
+		Usage: `The <file> with the base64url encoded HMAC key of the ACME external account
+binding (EAB). Requires the **--eab-key-id** flag.`,
+	}
+
 	// EABReference is a cli.Flag that points to an ACME EAB Key Reference
 	EABReference = cli.StringFlag{
 		Name:  "eab-key-reference",
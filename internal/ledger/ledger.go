@@ -0,0 +1,160 @@
+// Package ledger implements an append-only, tamper-evident record of the
+// certificates issued by the step CLI. Each entry is stored as a JSON line and
+// includes the hash of the previous entry, so any modification of the file
+// breaks the chain.
+package ledger
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/cli-utils/step"
+
+	"github.com/smallstep/cli/utils"
+)
+
+// DefaultFile is the name of the ledger file in the step path.
+const DefaultFile = "ledger.json"
+
+// lockTimeout is the maximum time to wait for another process appending an
+// entry to the same ledger.
+const lockTimeout = 30 * time.Second
+
+// DefaultPath returns the default location of the ledger,
+// $(step path)/ledger.json.
+func DefaultPath() string {
+	return filepath.Join(step.Path(), DefaultFile)
+}
+
+// Entry is a record in the ledger.
+type Entry struct {
+	Serial    string    `json:"serial"`
+	Subject   string    `json:"subject"`
+	Timestamp time.Time `json:"timestamp"`
+	Previous  string    `json:"previous"`
+	Hash      string    `json:"hash"`
+}
+
+// Sum returns the hash of the entry. The hash is computed over all the fields
+// except the hash itself.
+func (e *Entry) Sum() (string, error) {
+	c := *e
+	c.Hash = ""
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", errors.Wrap(err, "error marshaling ledger entry")
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Append adds a new entry for the given certificate at the end of the ledger
+// file. The file is created if it does not exist. The ledger is locked while
+// the entry is added, so concurrent appends are chained one after the other.
+func Append(filename string, cert *x509.Certificate) (*Entry, error) {
+	unlock, err := utils.AcquireLockFile(filename+".lock", lockTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	entries, err := Read(filename)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	e := &Entry{
+		Serial:    cert.SerialNumber.String(),
+		Subject:   cert.Subject.String(),
+		Timestamp: time.Now().UTC(),
+	}
+	if n := len(entries); n > 0 {
+		e.Previous = entries[n-1].Hash
+	}
+	if e.Hash, err = e.Sum(); err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling ledger entry")
+	}
+
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, errs.FileError(err, filename)
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return nil, errs.FileError(err, filename)
+	}
+	if err := f.Close(); err != nil {
+		return nil, errs.FileError(err, filename)
+	}
+	return e, nil
+}
+
+// Read returns all the entries in the ledger file without verifying them.
+func Read(filename string) ([]*Entry, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		e := new(Entry)
+		if err := json.Unmarshal(scanner.Bytes(), e); err != nil {
+			return nil, errors.Wrapf(err, "error parsing %s: line %d", filename, line)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errs.FileError(err, filename)
+	}
+	return entries, nil
+}
+
+// Verify reads the ledger file and checks that the hash of each entry is
+// valid and that it is chained to the previous one. It returns the entries
+// of the ledger if the chain is valid.
+func Verify(filename string) ([]*Entry, error) {
+	entries, err := Read(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, errs.FileError(err, filename)
+		}
+		return nil, err
+	}
+
+	var previous string
+	for i, e := range entries {
+		sum, err := e.Sum()
+		if err != nil {
+			return nil, err
+		}
+		if sum != e.Hash {
+			return nil, fmt.Errorf("ledger entry %d (serial %s) has an invalid hash", i+1, e.Serial)
+		}
+		if e.Previous != previous {
+			return nil, fmt.Errorf("ledger entry %d (serial %s) is not chained to the previous entry", i+1, e.Serial)
+		}
+		previous = e.Hash
+	}
+	return entries, nil
+}
@@ -0,0 +1,113 @@
+package ledger
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCertificate(serial int64, cn string) *x509.Certificate {
+	return &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn},
+	}
+}
+
+func TestAppendAndVerify(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), DefaultFile)
+
+	e1, err := Append(filename, newCertificate(1, "foo"))
+	require.NoError(t, err)
+	assert.Equal(t, "1", e1.Serial)
+	assert.Equal(t, "CN=foo", e1.Subject)
+	assert.Empty(t, e1.Previous)
+
+	e2, err := Append(filename, newCertificate(2, "bar"))
+	require.NoError(t, err)
+	assert.Equal(t, e1.Hash, e2.Previous)
+
+	entries, err := Verify(filename)
+	require.NoError(t, err)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, e1.Hash, entries[0].Hash)
+		assert.Equal(t, e2.Hash, entries[1].Hash)
+	}
+}
+
+func TestAppend_concurrent(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), DefaultFile)
+
+	var wg sync.WaitGroup
+	errc := make(chan error, 10)
+	for i := int64(1); i <= 10; i++ {
+		wg.Add(1)
+		go func(i int64) {
+			defer wg.Done()
+			_, err := Append(filename, newCertificate(i, "foo"))
+			errc <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errc)
+	for err := range errc {
+		require.NoError(t, err)
+	}
+
+	entries, err := Verify(filename)
+	require.NoError(t, err)
+	assert.Len(t, entries, 10)
+}
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+	newLedger := func(t *testing.T, name string) string {
+		filename := filepath.Join(dir, name)
+		for i := int64(1); i <= 3; i++ {
+			_, err := Append(filename, newCertificate(i, "foo"))
+			require.NoError(t, err)
+		}
+		return filename
+	}
+
+	valid := newLedger(t, "valid.json")
+
+	modified := newLedger(t, "modified.json")
+	b, err := os.ReadFile(modified)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(modified, bytes.Replace(b, []byte(`"serial":"2"`), []byte(`"serial":"4"`), 1), 0600))
+
+	removed := newLedger(t, "removed.json")
+	b, err = os.ReadFile(removed)
+	require.NoError(t, err)
+	lines := bytes.SplitAfter(b, []byte("\n"))
+	require.NoError(t, os.WriteFile(removed, append(lines[0], lines[2]...), 0600))
+
+	tests := []struct {
+		name     string
+		filename string
+		wantErr  bool
+	}{
+		{"ok", valid, false},
+		{"fail/modified", modified, true},
+		{"fail/removed", removed, true},
+		{"fail/missing", filepath.Join(dir, "missing.json"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Verify(tt.filename)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
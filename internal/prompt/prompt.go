@@ -0,0 +1,221 @@
+// Package prompt implements the prompts used to ask the user for values. By
+// default, the prompts use the terminal, but they can be answered by another
+// program using a file descriptor with the --prompt-fd flag.
+package prompt
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/smallstep/cli-utils/ui"
+)
+
+// Prompter is the interface that asks the user for values.
+type Prompter interface {
+	Prompt(label string, opts ...ui.Option) (string, error)
+	PromptPassword(label string, opts ...ui.Option) ([]byte, error)
+	Select(label string, items interface{}, opts ...ui.Option) (int, string, error)
+}
+
+var current Prompter = Terminal()
+
+// Set sets the prompter used by the package functions.
+func Set(p Prompter) {
+	current = p
+}
+
+// Prompt asks for a value with the current prompter.
+func Prompt(label string, opts ...ui.Option) (string, error) {
+	return current.Prompt(label, opts...)
+}
+
+// PromptPassword asks for a password with the current prompter.
+func PromptPassword(label string, opts ...ui.Option) ([]byte, error) {
+	return current.PromptPassword(label, opts...)
+}
+
+// Select asks to select one of the items with the current prompter.
+func Select(label string, items interface{}, opts ...ui.Option) (int, string, error) {
+	return current.Select(label, items, opts...)
+}
+
+type terminal struct{}
+
+// Terminal returns the prompter that uses the terminal.
+func Terminal() Prompter {
+	return terminal{}
+}
+
+func (terminal) Prompt(label string, opts ...ui.Option) (string, error) {
+	return ui.Prompt(label, opts...)
+}
+
+func (terminal) PromptPassword(label string, opts ...ui.Option) ([]byte, error) {
+	return ui.PromptPassword(label, opts...)
+}
+
+func (terminal) Select(label string, items interface{}, opts ...ui.Option) (int, string, error) {
+	return ui.Select(label, items, opts...)
+}
+
+// request is a prompt sent to the file descriptor.
+type request struct {
+	Type  string   `json:"type"`
+	Label string   `json:"label"`
+	Items []string `json:"items,omitempty"`
+}
+
+// response is the answer to a request.
+type response struct {
+	Value string `json:"value"`
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+// fdPrompter is a Prompter that writes the requests as JSON lines to w, and
+// reads the responses as JSON lines from r.
+type fdPrompter struct {
+	mu  sync.Mutex
+	r   *bufio.Reader
+	w   io.Writer
+	enc *json.Encoder
+}
+
+// New returns a prompter that writes each prompt as a JSON object in a line
+// to w, and reads the answer as a JSON object in a line from r.
+//
+// The requests have the form {"type":"prompt","label":"..."}, where the type
+// is "prompt", "password", or "select"; select requests also have the list of
+// "items". The responses have the form {"value":"..."} for prompts and
+// passwords, {"index":n} for select requests, or {"error":"..."} to cancel
+// the prompt. The values are validated like the ones entered in the terminal,
+// and they cannot be empty.
+func New(r io.Reader, w io.Writer) Prompter {
+	return &fdPrompter{
+		r:   bufio.NewReader(r),
+		w:   w,
+		enc: json.NewEncoder(w),
+	}
+}
+
+// ParseFD returns the prompter for the value of the --prompt-fd flag. The
+// value is a file descriptor used to write the requests and read the
+// responses, e.g. one end of a socket pair, or two comma separated file
+// descriptors, the first one to read the responses and the second one to
+// write the requests.
+func ParseFD(s string) (Prompter, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) > 2 {
+		return nil, errors.Errorf("invalid file descriptor %q", s)
+	}
+	files := make([]*os.File, len(parts))
+	for i, p := range parts {
+		p = strings.TrimSpace(p)
+		fd, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return nil, errors.Errorf("invalid file descriptor %q", s)
+		}
+		files[i] = os.NewFile(uintptr(fd), "prompt-fd-"+p)
+		if files[i] == nil {
+			return nil, errors.Errorf("invalid file descriptor %q", s)
+		}
+	}
+	return New(files[0], files[len(files)-1]), nil
+}
+
+func (p *fdPrompter) Prompt(label string, opts ...ui.Option) (string, error) {
+	resp, err := p.do(request{Type: "prompt", Label: label})
+	if err != nil {
+		return "", err
+	}
+	// The prompt returns the value without asking if it is set, but the
+	// value is validated.
+	return ui.Prompt(label, append(opts, ui.WithValue(resp.Value))...)
+}
+
+func (p *fdPrompter) PromptPassword(label string, opts ...ui.Option) ([]byte, error) {
+	resp, err := p.do(request{Type: "password", Label: label})
+	if err != nil {
+		return nil, err
+	}
+	return ui.PromptPassword(label, append(opts, ui.WithValue(resp.Value))...)
+}
+
+func (p *fdPrompter) Select(label string, items interface{}, _ ...ui.Option) (int, string, error) {
+	names, err := itemNames(items)
+	if err != nil {
+		return 0, "", err
+	}
+	resp, err := p.do(request{Type: "select", Label: label, Items: names})
+	if err != nil {
+		return 0, "", err
+	}
+	if resp.Index < 0 || resp.Index >= len(names) {
+		return 0, "", errors.Errorf("error running prompt: index %d is out of range", resp.Index)
+	}
+	return resp.Index, names[resp.Index], nil
+}
+
+func (p *fdPrompter) do(req request) (*response, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.enc.Encode(req); err != nil {
+		return nil, errors.Wrap(err, "error writing prompt")
+	}
+	line, err := p.r.ReadBytes('\n')
+	if err != nil && (err != io.EOF || len(line) == 0) {
+		return nil, errors.Wrap(err, "error reading prompt response")
+	}
+	var resp response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return nil, errors.Wrap(err, "error parsing prompt response")
+	}
+	if resp.Error != "" {
+		return nil, errors.Errorf("error running prompt: %s", resp.Error)
+	}
+	// The terminal would be used to ask for an empty value, and the value
+	// could not be validated.
+	if req.Type != "select" && resp.Value == "" {
+		return nil, errors.New("error running prompt: the value cannot be empty")
+	}
+	return &resp, nil
+}
+
+// itemNames returns the names of the items in a select prompt. The name of an
+// item is the result of its String method, the value of its Name field, or
+// its default format.
+func itemNames(items interface{}) ([]string, error) {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, errors.Errorf("unsupported select items %T", items)
+	}
+	names := make([]string, v.Len())
+	for i := range names {
+		item := v.Index(i)
+		if s, ok := item.Interface().(fmt.Stringer); ok {
+			names[i] = s.String()
+			continue
+		}
+		for item.Kind() == reflect.Ptr || item.Kind() == reflect.Interface {
+			item = item.Elem()
+		}
+		if item.Kind() == reflect.Struct {
+			if f := item.FieldByName("Name"); f.IsValid() && f.Kind() == reflect.String {
+				names[i] = f.String()
+				continue
+			}
+		}
+		names[i] = fmt.Sprint(v.Index(i).Interface())
+	}
+	return names, nil
+}
@@ -0,0 +1,85 @@
+package prompt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smallstep/cli-utils/ui"
+)
+
+func TestNew(t *testing.T) {
+	type item struct {
+		Name  string
+		Value int
+	}
+
+	tests := []struct {
+		name      string
+		responses string
+		run       func(p Prompter) (interface{}, error)
+		want      interface{}
+		wantReq   string
+		wantErr   bool
+	}{
+		{"ok/prompt", `{"value":"foo.internal"}`, func(p Prompter) (interface{}, error) {
+			return p.Prompt("What DNS names?", ui.WithValidateNotEmpty())
+		}, "foo.internal", `{"type":"prompt","label":"What DNS names?"}`, false},
+		{"ok/password", `{"value":"pass"}`, func(p Prompter) (interface{}, error) {
+			return p.PromptPassword("Password")
+		}, []byte("pass"), `{"type":"password","label":"Password"}`, false},
+		{"ok/select", `{"index":1}`, func(p Prompter) (interface{}, error) {
+			i, _, err := p.Select("Select", []*item{{"foo", 1}, {"bar", 2}})
+			return i, err
+		}, 1, `{"type":"select","label":"Select","items":["foo","bar"]}`, false},
+		{"fail/empty", `{}`, func(p Prompter) (interface{}, error) {
+			return p.Prompt("Value", ui.WithValidateNotEmpty())
+		}, nil, `{"type":"prompt","label":"Value"}`, true},
+		{"fail/empty-password", `{"value":""}`, func(p Prompter) (interface{}, error) {
+			return p.PromptPassword("Password", ui.WithValidateNotEmpty())
+		}, nil, `{"type":"password","label":"Password"}`, true},
+		{"fail/validate", `{"value":"maybe"}`, func(p Prompter) (interface{}, error) {
+			return p.Prompt("Yes or no?", ui.WithValidateYesNo())
+		}, nil, `{"type":"prompt","label":"Yes or no?"}`, true},
+		{"fail/error", `{"error":"canceled"}`, func(p Prompter) (interface{}, error) {
+			return p.Prompt("Value")
+		}, nil, `{"type":"prompt","label":"Value"}`, true},
+		{"fail/out-of-range", `{"index":2}`, func(p Prompter) (interface{}, error) {
+			i, _, err := p.Select("Select", []string{"foo", "bar"})
+			return i, err
+		}, nil, `{"type":"select","label":"Select","items":["foo","bar"]}`, true},
+		{"fail/eof", ``, func(p Prompter) (interface{}, error) {
+			return p.Prompt("Value")
+		}, nil, `{"type":"prompt","label":"Value"}`, true},
+		{"fail/json", `not json`, func(p Prompter) (interface{}, error) {
+			return p.Prompt("Value")
+		}, nil, `{"type":"prompt","label":"Value"}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w bytes.Buffer
+			got, err := tt.run(New(strings.NewReader(tt.responses+"\n"), &w))
+			assert.Equal(t, tt.wantReq+"\n", w.String())
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseFD(t *testing.T) {
+	for _, s := range []string{"3", "3,4", " 3, 4"} {
+		_, err := ParseFD(s)
+		assert.NoError(t, err, s)
+	}
+	for _, s := range []string{"", "foo", "-1", "3,4,5"} {
+		_, err := ParseFD(s)
+		assert.Error(t, err, s)
+	}
+}
@@ -0,0 +1,41 @@
+package store
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/smallstep/cli/utils"
+)
+
+func init() {
+	Register("file", fileStore{})
+}
+
+// fileStore writes the data in a local file, a URI like file:///etc/foo.crt,
+// it asks before overwriting an existing file unless --force is used.
+type fileStore struct{}
+
+func (fileStore) Write(_ context.Context, u *url.URL, data []byte, perm os.FileMode) error {
+	filename, ok := FilePath(u)
+	if !ok || filename == "" {
+		return errors.New("uri must have the format file://<path>")
+	}
+	return utils.WriteFile(filename, data, perm)
+}
+
+// FilePath returns the local path in a file URI, e.g. /etc/foo.crt in
+// file:///etc/foo.crt. It returns false if the URI does not use the file
+// scheme.
+func FilePath(u *url.URL) (string, bool) {
+	if !strings.EqualFold(u.Scheme, "file") {
+		return "", false
+	}
+	if u.Opaque != "" {
+		return u.Opaque, true
+	}
+	return u.Path, true
+}
@@ -0,0 +1,113 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	Register("k8s", k8sStore{})
+}
+
+// Location of the service account credentials mounted in a Kubernetes pod,
+// they are variables so they can be changed in tests.
+var (
+	k8sTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	k8sCAFile    = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+	k8sAPIURL    = func() string {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return ""
+		}
+		return "https://" + net.JoinHostPort(host, port)
+	}
+)
+
+// k8sStore writes the data in a key of a Kubernetes secret, a URI like
+// k8s://<namespace>/<secret>#<key>. It uses the credentials of the service
+// account of the pod. Other keys in the secret are preserved.
+type k8sStore struct{}
+
+func (k8sStore) Write(ctx context.Context, u *url.URL, data []byte, _ os.FileMode) error {
+	namespace, name, key, err := fieldFromURI(u)
+	if err != nil {
+		return err
+	}
+	apiURL := k8sAPIURL()
+	if apiURL == "" {
+		return errors.New("environment variables KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT are required")
+	}
+	token, err := os.ReadFile(k8sTokenFile)
+	if err != nil {
+		return errors.Wrap(err, "error reading service account token")
+	}
+	client, err := k8sClient()
+	if err != nil {
+		return err
+	}
+
+	value := map[string]string{key: base64.StdEncoding.EncodeToString(data)}
+	do := func(method, endpoint, contentType string, v any) (int, error) {
+		body, err := json.Marshal(v)
+		if err != nil {
+			return 0, err
+		}
+		req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+		req.Header.Set("Content-Type", contentType)
+		return doRequest(client, req)
+	}
+
+	// Patch the existing secret to preserve other keys, and create it if it
+	// does not exist.
+	secrets := fmt.Sprintf("%s/api/v1/namespaces/%s/secrets", apiURL, url.PathEscape(namespace))
+	status, err := do(http.MethodPatch, secrets+"/"+url.PathEscape(name), "application/merge-patch+json", map[string]any{
+		"data": value,
+	})
+	if status == http.StatusNotFound {
+		_, err = do(http.MethodPost, secrets, "application/json", map[string]any{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"type":       "Opaque",
+			"metadata":   map[string]string{"name": name, "namespace": namespace},
+			"data":       value,
+		})
+	}
+	return err
+}
+
+// k8sClient returns an http client that trusts the cluster CA.
+func k8sClient() (*http.Client, error) {
+	b, err := os.ReadFile(k8sCAFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading cluster CA")
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, errors.Errorf("error reading cluster CA: %s does not contain any certificate", k8sCAFile)
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{
+				RootCAs:    pool,
+				MinVersion: tls.VersionTLS12,
+			},
+		},
+	}, nil
+}
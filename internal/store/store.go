@@ -0,0 +1,99 @@
+// Package store implements the backends where certificates and keys can be
+// written. A backend is selected using the scheme of a URI, e.g.
+// file:///etc/ssl/foo.crt, vault://secret/foo#certificate, or
+// k8s://default/foo-tls#tls.crt.
+package store
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Store is the interface implemented by the backends.
+type Store interface {
+	// Write stores the data in the location described by the given URI.
+	Write(ctx context.Context, u *url.URL, data []byte, perm os.FileMode) error
+}
+
+var (
+	mu     sync.RWMutex
+	stores = map[string]Store{}
+)
+
+// Register adds a backend for the given URI scheme. It replaces any backend
+// previously registered with the same scheme.
+func Register(scheme string, s Store) {
+	mu.Lock()
+	defer mu.Unlock()
+	stores[strings.ToLower(scheme)] = s
+}
+
+func lookup(scheme string) (Store, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	s, ok := stores[strings.ToLower(scheme)]
+	return s, ok
+}
+
+// Parse parses the given URI and checks that there is a backend for it.
+func Parse(rawuri string) (*url.URL, error) {
+	u, err := url.Parse(rawuri)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error parsing %s", rawuri)
+	}
+	if u.Scheme == "" {
+		return nil, errors.Errorf("error parsing %s: uri must have a scheme", rawuri)
+	}
+	if _, ok := lookup(u.Scheme); !ok {
+		return nil, errors.Errorf("error parsing %s: unsupported scheme %q", rawuri, u.Scheme)
+	}
+	return u, nil
+}
+
+// Write writes the data in the location described by the given URI.
+func Write(ctx context.Context, rawuri string, data []byte, perm os.FileMode) error {
+	u, err := Parse(rawuri)
+	if err != nil {
+		return err
+	}
+	s, _ := lookup(u.Scheme)
+	if err := s.Write(ctx, u, data, perm); err != nil {
+		return errors.Wrapf(err, "error writing %s", rawuri)
+	}
+	return nil
+}
+
+// fieldFromURI returns the host, the path without the leading slash, and the
+// fragment of the given URI. All of them are required.
+func fieldFromURI(u *url.URL) (host, path, field string, err error) {
+	host = u.Host
+	path = strings.Trim(u.Path, "/")
+	field = u.Fragment
+	if host == "" || path == "" || field == "" {
+		return "", "", "", errors.Errorf("uri must have the format %s://<name>/<path>#<field>", u.Scheme)
+	}
+	return host, path, field, nil
+}
+
+// doRequest sends the request and returns the status code, and an error if
+// the response is not successful.
+func doRequest(client *http.Client, req *http.Request) (int, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode >= 400 {
+		return resp.StatusCode, errors.Errorf("%s %s failed with status code %d: %s",
+			req.Method, req.URL.Path, resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	return resp.StatusCode, nil
+}
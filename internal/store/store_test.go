@@ -0,0 +1,179 @@
+package store
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		uri     string
+		wantErr bool
+	}{
+		{"ok/file", "file:///tmp/foo.crt", false},
+		{"ok/vault", "vault://secret/foo#certificate", false},
+		{"ok/k8s", "k8s://default/foo-tls#tls.crt", false},
+		{"fail/no-scheme", "/tmp/foo.crt", true},
+		{"fail/unknown", "s3://bucket/foo.crt", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.uri)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+func TestWrite_file(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "foo.crt")
+	require.NoError(t, Write(context.Background(), "file://"+filename, []byte("foo"), 0600))
+	b, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("foo"), b)
+}
+
+func TestFilePath(t *testing.T) {
+	tests := []struct {
+		name   string
+		uri    string
+		want   string
+		wantOK bool
+	}{
+		{"ok", "file:///etc/foo.crt", "/etc/foo.crt", true},
+		{"ok/opaque", "file:foo.crt", "foo.crt", true},
+		{"ok/upper", "FILE:///etc/foo.crt", "/etc/foo.crt", true},
+		{"fail/vault", "vault://secret/foo#certificate", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.uri)
+			require.NoError(t, err)
+			got, ok := FilePath(u)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWrite_vault(t *testing.T) {
+	secrets := map[string]map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "the-token" {
+			http.Error(w, "permission denied", http.StatusForbidden)
+			return
+		}
+		var body struct {
+			Data map[string]string `json:"data"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		secret, ok := secrets[r.URL.Path]
+		switch {
+		case r.Method == http.MethodPatch && !ok:
+			http.NotFound(w, r)
+			return
+		case r.Method == http.MethodPost:
+			secret = map[string]string{}
+		}
+		for k, v := range body.Data {
+			secret[k] = v
+		}
+		secrets[r.URL.Path] = secret
+	}))
+	defer srv.Close()
+
+	t.Setenv("VAULT_ADDR", srv.URL)
+	t.Setenv("VAULT_TOKEN", "the-token")
+
+	ctx := context.Background()
+	require.NoError(t, Write(ctx, "vault://secret/foo#certificate", []byte("crt"), 0600))
+	require.NoError(t, Write(ctx, "vault://secret/foo#key", []byte("key"), 0600))
+	assert.Equal(t, map[string]map[string]string{
+		"/v1/secret/data/foo": {"certificate": "crt", "key": "key"},
+	}, secrets)
+
+	assert.Error(t, Write(ctx, "vault://secret/foo", []byte("crt"), 0600))
+
+	t.Setenv("VAULT_TOKEN", "bad-token")
+	assert.Error(t, Write(ctx, "vault://secret/foo#certificate", []byte("crt"), 0600))
+}
+
+func TestWrite_k8s(t *testing.T) {
+	secrets := map[string]map[string]string{}
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer the-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var body struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Data map[string]string `json:"data"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		path := r.URL.Path
+		if r.Method == http.MethodPost {
+			path += "/" + body.Metadata.Name
+		}
+		secret, ok := secrets[path]
+		switch {
+		case r.Method == http.MethodPatch && !ok:
+			http.NotFound(w, r)
+			return
+		case r.Method == http.MethodPost:
+			secret = map[string]string{}
+		}
+		for k, v := range body.Data {
+			secret[k] = v
+		}
+		secrets[path] = secret
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	caFile := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("the-token\n"), 0600))
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{
+		Type: "CERTIFICATE", Bytes: srv.Certificate().Raw,
+	}), 0600))
+
+	t.Cleanup(func(tf, cf string, fn func() string) func() {
+		return func() {
+			k8sTokenFile, k8sCAFile, k8sAPIURL = tf, cf, fn
+		}
+	}(k8sTokenFile, k8sCAFile, k8sAPIURL))
+	k8sTokenFile, k8sCAFile = tokenFile, caFile
+	k8sAPIURL = func() string { return srv.URL }
+
+	ctx := context.Background()
+	require.NoError(t, Write(ctx, "k8s://default/foo-tls#tls.crt", []byte("crt"), 0600))
+	require.NoError(t, Write(ctx, "k8s://default/foo-tls#tls.key", []byte("key"), 0600))
+	assert.Equal(t, map[string]map[string]string{
+		"/api/v1/namespaces/default/secrets/foo-tls": {
+			"tls.crt": base64.StdEncoding.EncodeToString([]byte("crt")),
+			"tls.key": base64.StdEncoding.EncodeToString([]byte("key")),
+		},
+	}, secrets)
+
+	k8sAPIURL = func() string { return "" }
+	assert.Error(t, Write(ctx, "k8s://default/foo-tls#tls.crt", []byte("crt"), 0600))
+}
@@ -0,0 +1,61 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	Register("vault", vaultStore{})
+}
+
+// vaultStore writes the data in a field of a secret in a HashiCorp Vault KV
+// version 2 secrets engine, a URI like vault://<mount>/<path>#<field>. The
+// address and the token of the server are read from the VAULT_ADDR and
+// VAULT_TOKEN environment variables. Other fields in the secret are preserved.
+type vaultStore struct{}
+
+func (vaultStore) Write(ctx context.Context, u *url.URL, data []byte, _ os.FileMode) error {
+	mount, path, field, err := fieldFromURI(u)
+	if err != nil {
+		return err
+	}
+	addr, token := os.Getenv("VAULT_ADDR"), os.Getenv("VAULT_TOKEN")
+	if addr == "" || token == "" {
+		return errors.New("environment variables VAULT_ADDR and VAULT_TOKEN are required")
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"data": map[string]string{field: string(data)},
+	})
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/%s/data/%s", strings.TrimRight(addr, "/"), mount, path)
+	do := func(method, contentType string) (int, error) {
+		req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Set("X-Vault-Token", token)
+		req.Header.Set("Content-Type", contentType)
+		return doRequest(http.DefaultClient, req)
+	}
+
+	// Patch the existing secret to preserve other fields, and create it if it
+	// does not exist.
+	status, err := do(http.MethodPatch, "application/merge-patch+json")
+	if status == http.StatusNotFound {
+		_, err = do(http.MethodPost, "application/json")
+	}
+	return err
+}
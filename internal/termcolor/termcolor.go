@@ -0,0 +1,60 @@
+package termcolor
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/manifoldco/promptui"
+	"golang.org/x/term"
+
+	"github.com/smallstep/cli-utils/ui"
+)
+
+var (
+	enabled = true
+	ansiRe  = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+)
+
+// ShouldDisable returns true if the colors must be disabled, because the
+// NO_COLOR environment variable is set, or because the ui output, written to
+// stderr, is not a terminal.
+func ShouldDisable() bool {
+	return os.Getenv("NO_COLOR") != "" || !term.IsTerminal(int(os.Stderr.Fd()))
+}
+
+// Disable removes the ANSI color codes from the output of the ui package and
+// from the messages returned by Error.
+func Disable() {
+	enabled = false
+	for name := range promptui.FuncMap {
+		if _, ok := promptui.FuncMap[name].(func(interface{}) string); ok {
+			promptui.FuncMap[name] = func(v interface{}) string {
+				return fmt.Sprint(v)
+			}
+		}
+	}
+	ui.IconInitial = Strip(ui.IconInitial)
+	ui.IconGood = Strip(ui.IconGood)
+	ui.IconWarn = Strip(ui.IconWarn)
+	ui.IconBad = Strip(ui.IconBad)
+	ui.IconSelect = Strip(ui.IconSelect)
+}
+
+// Enabled returns true if the colors are enabled.
+func Enabled() bool {
+	return enabled
+}
+
+// Error returns the given message in red if colors are enabled.
+func Error(msg string) string {
+	if !enabled {
+		return msg
+	}
+	return promptui.Styler(promptui.FGRed)(msg)
+}
+
+// Strip removes the ANSI color codes from the given string.
+func Strip(s string) string {
+	return ansiRe.ReplaceAllString(s, "")
+}
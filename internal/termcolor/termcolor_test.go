@@ -0,0 +1,29 @@
+package termcolor
+
+import (
+	"testing"
+
+	"github.com/manifoldco/promptui"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/smallstep/cli-utils/ui"
+)
+
+func TestDisable(t *testing.T) {
+	assert.True(t, Enabled())
+	assert.NotEqual(t, "foo", Error("foo"))
+
+	Disable()
+	assert.False(t, Enabled())
+	assert.Equal(t, "foo", Error("foo"))
+	assert.Equal(t, "✔", ui.IconGood)
+	assert.Equal(t, "✗", ui.IconBad)
+
+	red := promptui.FuncMap["red"].(func(interface{}) string)
+	assert.Equal(t, "foo", red("foo"))
+}
+
+func TestStrip(t *testing.T) {
+	assert.Equal(t, "foo", Strip("foo"))
+	assert.Equal(t, "✔ foo: bar", Strip(promptui.Styler(promptui.FGGreen)("✔")+" "+promptui.Styler(promptui.FGBold)("foo:")+" bar"))
+}
@@ -0,0 +1,192 @@
+// Package testca implements an in-memory certificate authority that serves
+// the sign endpoint of step-ca, so the certificate commands can be tested
+// end-to-end without a real CA. It is only meant to be used in tests.
+package testca
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/smallstep/certificates/api"
+	"go.step.sm/crypto/jose"
+	"go.step.sm/crypto/minica"
+
+	"github.com/smallstep/cli/token"
+)
+
+// CA is a certificate authority with an ephemeral root and intermediate that
+// serves the sign, root, roots, and health endpoints of step-ca.
+type CA struct {
+	*minica.CA
+	// URL is the address of the CA, it can be used in the --ca-url flag.
+	URL string
+	// RootFile is the file with the root certificate, it can be used in the
+	// --root flag.
+	RootFile string
+
+	srv      *httptest.Server
+	key      *ecdsa.PrivateKey
+	mu       sync.Mutex
+	requests []*x509.CertificateRequest
+}
+
+// New starts a new CA, the server is closed when the test ends.
+func New(t testing.TB) *CA {
+	t.Helper()
+
+	m, err := minica.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverCert, err := m.Sign(&x509.Certificate{
+		DNSNames:    []string{"localhost"},
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		PublicKey:   serverKey.Public(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &CA{CA: m, key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /sign", c.sign)
+	mux.HandleFunc("POST /1.0/sign", c.sign)
+	mux.HandleFunc("GET /root/{sha}", c.root)
+	mux.HandleFunc("GET /roots", c.roots)
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, api.HealthResponse{Status: "ok"})
+	})
+
+	c.srv = httptest.NewUnstartedServer(mux)
+	c.srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{{
+			Certificate: [][]byte{serverCert.Raw, m.Intermediate.Raw},
+			PrivateKey:  serverKey,
+		}},
+		MinVersion: tls.VersionTLS12,
+	}
+	c.srv.StartTLS()
+	t.Cleanup(c.srv.Close)
+	c.URL = c.srv.URL
+
+	c.RootFile = filepath.Join(t.TempDir(), "root_ca.crt")
+	if err := os.WriteFile(c.RootFile, pem.EncodeToMemory(&pem.Block{
+		Type: "CERTIFICATE", Bytes: m.Root.Raw,
+	}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// Token returns a JWK provisioner token for the given subject and SANs. The
+// CA does not validate the token, but the token has the claims expected by
+// the commands.
+func (c *CA) Token(t testing.TB, subject string, sans ...string) string {
+	t.Helper()
+	if len(sans) == 0 {
+		sans = []string{subject}
+	}
+	sum := sha256.Sum256(c.Root.Raw)
+	now := time.Now()
+	claims, err := token.NewClaims(
+		token.WithIssuer("testca"),
+		token.WithSubject(subject),
+		token.WithAudience(c.URL+"/1.0/sign"),
+		token.WithSHA(hex.EncodeToString(sum[:])),
+		token.WithSANS(sans),
+		token.WithValidity(now, now.Add(5*time.Minute)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok, err := claims.Sign(jose.ES256, c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tok
+}
+
+// Requests returns the certificate requests signed by the CA.
+func (c *CA) Requests() []*x509.CertificateRequest {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*x509.CertificateRequest{}, c.requests...)
+}
+
+func (c *CA) sign(w http.ResponseWriter, r *http.Request) {
+	var body api.SignRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.CsrPEM.CertificateRequest == nil {
+		http.Error(w, `{"status":400,"message":"bad request"}`, http.StatusBadRequest)
+		return
+	}
+	if body.OTT == "" {
+		http.Error(w, `{"status":401,"message":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+	csr := body.CsrPEM.CertificateRequest
+	crt, err := c.SignCSR(csr, minica.WithModifyFunc(func(crt *x509.Certificate) error {
+		if !body.NotBefore.IsZero() {
+			crt.NotBefore = body.NotBefore.Time()
+		}
+		if !body.NotAfter.IsZero() {
+			crt.NotAfter = body.NotAfter.Time()
+		}
+		return nil
+	}))
+	if err != nil {
+		http.Error(w, `{"status":400,"message":"bad request"}`, http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	c.requests = append(c.requests, csr)
+	c.mu.Unlock()
+
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, api.SignResponse{
+		ServerPEM:    api.NewCertificate(crt),
+		CaPEM:        api.NewCertificate(c.Intermediate),
+		CertChainPEM: []api.Certificate{api.NewCertificate(crt), api.NewCertificate(c.Intermediate)},
+	})
+}
+
+func (c *CA) root(w http.ResponseWriter, r *http.Request) {
+	sum := sha256.Sum256(c.Root.Raw)
+	if r.PathValue("sha") != hex.EncodeToString(sum[:]) {
+		http.Error(w, `{"status":404,"message":"not found"}`, http.StatusNotFound)
+		return
+	}
+	writeJSON(w, api.RootResponse{RootPEM: api.NewCertificate(c.Root)})
+}
+
+func (c *CA) roots(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, api.RootsResponse{Certificates: []api.Certificate{api.NewCertificate(c.Root)}})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,258 @@
+// Package tracing implements a minimal tracer that records the phases of a
+// command as spans and exports them to an OpenTelemetry collector using the
+// OTLP/HTTP protocol with JSON encoding.
+//
+// A nil *Tracer and a nil *Span are valid and do nothing, so commands can
+// call them unconditionally without any overhead when tracing is disabled.
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ServiceName is the service.name resource attribute of the exported spans.
+const ServiceName = "step-cli"
+
+// exportTimeout is the maximum time allowed to export the spans.
+const exportTimeout = 5 * time.Second
+
+// Tracer records the spans of a single trace. The root span is started with
+// the tracer and ended on Flush.
+type Tracer struct {
+	endpoint string
+	client   *http.Client
+	traceID  [16]byte
+	root     *Span
+	mu       sync.Mutex
+	spans    []*Span
+}
+
+// Span is a timed phase of a trace.
+type Span struct {
+	tracer   *Tracer
+	name     string
+	id       [8]byte
+	parentID [8]byte
+	start    time.Time
+	end      time.Time
+	attrs    map[string]string
+	err      error
+}
+
+// New returns a tracer that exports the spans to the given OTLP/HTTP
+// endpoint, e.g. "http://localhost:4318". The path "/v1/traces" is used if the
+// endpoint does not have one. It returns a nil tracer if the endpoint is
+// empty.
+func New(endpoint, name string) (*Tracer, error) {
+	if endpoint == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.Errorf("invalid OTLP endpoint %q: it must be an http or https URL", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+	}
+
+	t := &Tracer{
+		endpoint: u.String(),
+		client:   &http.Client{Timeout: exportTimeout},
+	}
+	if _, err := rand.Read(t.traceID[:]); err != nil {
+		return nil, errors.Wrap(err, "error generating trace id")
+	}
+	t.root = t.newSpan(name, [8]byte{})
+	return t, nil
+}
+
+// Start starts a new child span of the root span.
+func (t *Tracer) Start(name string) *Span {
+	if t == nil {
+		return nil
+	}
+	return t.newSpan(name, t.root.id)
+}
+
+func (t *Tracer) newSpan(name string, parentID [8]byte) *Span {
+	s := &Span{
+		tracer:   t,
+		name:     name,
+		parentID: parentID,
+		start:    time.Now(),
+	}
+	// An all-zero span id is invalid, but the chances of getting one, or of
+	// rand.Read failing, are negligible.
+	_, _ = rand.Read(s.id[:])
+	return s
+}
+
+// SetAttribute sets a string attribute in the span.
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	if s.attrs == nil {
+		s.attrs = make(map[string]string)
+	}
+	s.attrs[key] = value
+}
+
+// End ends the span, the span status is set to error if err is not nil.
+func (s *Span) End(err error) {
+	if s == nil || !s.end.IsZero() {
+		return
+	}
+	s.end = time.Now()
+	s.err = err
+	s.tracer.mu.Lock()
+	s.tracer.spans = append(s.tracer.spans, s)
+	s.tracer.mu.Unlock()
+}
+
+// Flush ends the root span with the given error and exports all the ended
+// spans.
+func (t *Tracer) Flush(err error) error {
+	if t == nil {
+		return nil
+	}
+	t.root.End(err)
+
+	t.mu.Lock()
+	body, err := json.Marshal(t.exportRequest())
+	t.mu.Unlock()
+	if err != nil {
+		return errors.Wrap(err, "error marshaling spans")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrapf(err, "error exporting spans to %s", t.endpoint)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "error exporting spans to %s", t.endpoint)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 300 {
+		return errors.Errorf("error exporting spans to %s: status code %d", t.endpoint, resp.StatusCode)
+	}
+	return nil
+}
+
+// The following types are the subset of the OTLP JSON encoding used to export
+// the spans.
+
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []attribute `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope      `json:"scope"`
+	Spans []spanJSON `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type spanJSON struct {
+	TraceID           string      `json:"traceId"`
+	SpanID            string      `json:"spanId"`
+	ParentSpanID      string      `json:"parentSpanId,omitempty"`
+	Name              string      `json:"name"`
+	Kind              int         `json:"kind"`
+	StartTimeUnixNano string      `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string      `json:"endTimeUnixNano"`
+	Attributes        []attribute `json:"attributes,omitempty"`
+	Status            *status     `json:"status,omitempty"`
+}
+
+type attribute struct {
+	Key   string         `json:"key"`
+	Value attributeValue `json:"value"`
+}
+
+type attributeValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// OTLP span kind and status codes.
+const (
+	spanKindInternal = 1
+	statusCodeError  = 2
+)
+
+// scopeName is the instrumentation scope of the exported spans.
+const scopeName = "github.com/smallstep/cli"
+
+func (t *Tracer) exportRequest() *exportRequest {
+	spans := make([]spanJSON, 0, len(t.spans))
+	for _, s := range t.spans {
+		sj := spanJSON{
+			TraceID:           hex.EncodeToString(t.traceID[:]),
+			SpanID:            hex.EncodeToString(s.id[:]),
+			Name:              s.name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		}
+		if s.parentID != [8]byte{} {
+			sj.ParentSpanID = hex.EncodeToString(s.parentID[:])
+		}
+		keys := make([]string, 0, len(s.attrs))
+		for k := range s.attrs {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			sj.Attributes = append(sj.Attributes, attribute{k, attributeValue{s.attrs[k]}})
+		}
+		if s.err != nil {
+			sj.Status = &status{Code: statusCodeError, Message: s.err.Error()}
+		}
+		spans = append(spans, sj)
+	}
+	return &exportRequest{
+		ResourceSpans: []resourceSpans{{
+			Resource: resource{
+				Attributes: []attribute{{"service.name", attributeValue{ServiceName}}},
+			},
+			ScopeSpans: []scopeSpans{{
+				Scope: scope{Name: scopeName},
+				Spans: spans,
+			}},
+		}},
+	}
+}
@@ -0,0 +1,97 @@
+package tracing
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	tr, err := New("", "test")
+	assert.NoError(t, err)
+	assert.Nil(t, tr)
+
+	for _, endpoint := range []string{"localhost:4318", "ftp://localhost", "http://"} {
+		_, err := New(endpoint, "test")
+		assert.Error(t, err, endpoint)
+	}
+
+	tr, err = New("http://localhost:4318", "test")
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:4318/v1/traces", tr.endpoint)
+
+	tr, err = New("https://collector.example.com/custom/traces", "test")
+	require.NoError(t, err)
+	assert.Equal(t, "https://collector.example.com/custom/traces", tr.endpoint)
+}
+
+func TestTracer_nil(t *testing.T) {
+	var tr *Tracer
+	span := tr.Start("phase")
+	assert.Nil(t, span)
+	span.SetAttribute("key", "value")
+	span.End(nil)
+	assert.NoError(t, tr.Flush(nil))
+}
+
+func TestTracer_Flush(t *testing.T) {
+	var got exportRequest
+	code := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(code)
+	}))
+	defer srv.Close()
+
+	tr, err := New(srv.URL, "step ca certificate")
+	require.NoError(t, err)
+	span := tr.Start("token generation")
+	span.End(nil)
+	span = tr.Start("sign")
+	span.SetAttribute("ca.url", "https://ca.example.com")
+	span.End(errors.New("sign failed"))
+	span.End(nil) // no-op
+	require.NoError(t, tr.Flush(errors.New("sign failed")))
+
+	require.Len(t, got.ResourceSpans, 1)
+	assert.Equal(t, []attribute{{"service.name", attributeValue{ServiceName}}}, got.ResourceSpans[0].Resource.Attributes)
+	require.Len(t, got.ResourceSpans[0].ScopeSpans, 1)
+	spans := got.ResourceSpans[0].ScopeSpans[0].Spans
+	require.Len(t, spans, 3)
+
+	root := spans[2]
+	assert.Equal(t, "step ca certificate", root.Name)
+	assert.Empty(t, root.ParentSpanID)
+	for _, s := range spans {
+		assert.Equal(t, root.TraceID, s.TraceID)
+		assert.Len(t, s.TraceID, 32)
+		assert.Len(t, s.SpanID, 16)
+		start, err := strconv.ParseInt(s.StartTimeUnixNano, 10, 64)
+		require.NoError(t, err)
+		end, err := strconv.ParseInt(s.EndTimeUnixNano, 10, 64)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, start, end)
+	}
+
+	assert.Equal(t, "token generation", spans[0].Name)
+	assert.Equal(t, root.SpanID, spans[0].ParentSpanID)
+	assert.Nil(t, spans[0].Status)
+
+	assert.Equal(t, "sign", spans[1].Name)
+	assert.Equal(t, root.SpanID, spans[1].ParentSpanID)
+	assert.Equal(t, []attribute{{"ca.url", attributeValue{"https://ca.example.com"}}}, spans[1].Attributes)
+	assert.Equal(t, &status{Code: statusCodeError, Message: "sign failed"}, spans[1].Status)
+
+	code = http.StatusBadRequest
+	tr, err = New(srv.URL, "step ca certificate")
+	require.NoError(t, err)
+	assert.ErrorContains(t, tr.Flush(nil), "status code 400")
+}
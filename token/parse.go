@@ -81,6 +81,19 @@ func (p Payload) Type() Type {
 	}
 }
 
+// ValidateTime returns an error if the token is expired or not yet valid at
+// the given time. The skew is the tolerance allowed for the differences
+// between the clock of this host and the clock of the CA.
+func (p Payload) ValidateTime(now time.Time, skew time.Duration) error {
+	if p.Expiry != nil && now.Add(-skew).After(p.Expiry.Time()) {
+		return errors.Errorf("token expired at %s", p.Expiry.Time().UTC().Format(time.RFC3339))
+	}
+	if p.NotBefore != nil && now.Add(skew).Before(p.NotBefore.Time()) {
+		return errors.Errorf("token is not valid until %s", p.NotBefore.Time().UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
 // GCPGooglePayload represents the Google payload in GCP.
 type GCPGooglePayload struct {
 	ComputeEngine GCPComputeEnginePayload `json:"compute_engine"`
@@ -323,3 +323,34 @@ func TestPayload_Type(t *testing.T) {
 		})
 	}
 }
+
+func TestPayload_ValidateTime(t *testing.T) {
+	now := time.Now()
+	newPayload := func(nbf, exp time.Time) Payload {
+		return Payload{Claims: jose.Claims{
+			NotBefore: jose.NewNumericDate(nbf),
+			Expiry:    jose.NewNumericDate(exp),
+		}}
+	}
+	tests := []struct {
+		name    string
+		payload Payload
+		skew    time.Duration
+		wantErr bool
+	}{
+		{"ok", newPayload(now.Add(-time.Minute), now.Add(time.Minute)), 0, false},
+		{"ok/no-claims", Payload{}, 0, false},
+		{"ok/expired-within-skew", newPayload(now.Add(-5*time.Minute), now.Add(-30*time.Second)), time.Minute, false},
+		{"ok/not-before-within-skew", newPayload(now.Add(30*time.Second), now.Add(5*time.Minute)), time.Minute, false},
+		{"fail/expired", newPayload(now.Add(-5*time.Minute), now.Add(-2*time.Minute)), time.Minute, true},
+		{"fail/not-before", newPayload(now.Add(2*time.Minute), now.Add(5*time.Minute)), time.Minute, true},
+		{"fail/no-skew", newPayload(now.Add(-5*time.Minute), now.Add(-30*time.Second)), 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.payload.ValidateTime(now, tt.skew); (err != nil) != tt.wantErr {
+				t.Errorf("Payload.ValidateTime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package cautils
 
 import (
+	"bytes"
 	"context"
 	"crypto"
 	"crypto/ecdsa"
@@ -9,13 +10,13 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
-	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/base64"
 	"encoding/json"
 	"encoding/pem"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"os"
@@ -619,6 +620,8 @@ type acmeFlow struct {
 	sans            []string
 	acmeDir         string
 	tpmSigner       crypto.Signer
+	eabKeyID        string
+	eabKey          []byte
 }
 
 func newACMEFlow(ctx *cli.Context, ops ...acmeFlowOp) (*acmeFlow, error) {
@@ -638,6 +641,23 @@ func newACMEFlow(ctx *cli.Context, ops ...acmeFlowOp) (*acmeFlow, error) {
 	}
 
 	af := new(acmeFlow)
+
+	// Both the key identifier and the HMAC key are required for the external
+	// account binding.
+	if kid, keyFile := ctx.String("eab-key-id"), ctx.String("eab-key-file"); kid != "" || keyFile != "" {
+		switch {
+		case kid == "":
+			return nil, errs.RequiredWithFlag(ctx, "eab-key-file", "eab-key-id")
+		case keyFile == "":
+			return nil, errs.RequiredWithFlag(ctx, "eab-key-id", "eab-key-file")
+		}
+		key, err := readEABKey(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		af.eabKeyID, af.eabKey = kid, key
+	}
+
 	for _, op := range ops {
 		if err := op(af); err != nil {
 			return nil, err
@@ -664,7 +684,7 @@ func newACMEFlow(ctx *cli.Context, ops ...acmeFlowOp) (*acmeFlow, error) {
 	return af, nil
 }
 
-func (af *acmeFlow) getClientTruststoreOption(mergeRootCAs bool) (ca.ClientOption, error) {
+func (af *acmeFlow) getClientTransport(mergeRootCAs bool) (http.RoundTripper, error) {
 	root := ""
 	if af.ctx.IsSet("root") {
 		root = af.ctx.String("root")
@@ -673,37 +693,174 @@ func (af *acmeFlow) getClientTruststoreOption(mergeRootCAs bool) (ca.ClientOptio
 		root = pki.GetRootCAPath()
 	}
 
-	// 1. Merge local RootCA with system store
-	if mergeRootCAs && root != "" {
-		rootCAs, err := x509.SystemCertPool()
-		if err != nil || rootCAs == nil {
-			rootCAs = x509.NewCertPool()
-		}
+	// Use system store only
+	if root == "" {
+		return http.DefaultTransport, nil
+	}
 
-		cert, err := os.ReadFile(root)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to read local root ca")
+	// Merge local RootCA with system store, or use local Root CA only
+	rootCAs := x509.NewCertPool()
+	if mergeRootCAs {
+		if pool, err := x509.SystemCertPool(); err == nil && pool != nil {
+			rootCAs = pool
 		}
+	}
+
+	cert, err := os.ReadFile(root)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read local root ca")
+	}
+
+	if ok := rootCAs.AppendCertsFromPEM(cert); !ok {
+		return nil, errors.New("failed to append local root ca to cert pool")
+	}
 
-		if ok := rootCAs.AppendCertsFromPEM(cert); !ok {
-			return nil, errors.New("failed to append local root ca to system cert pool")
+	return NewTransport(rootCAs, nil), nil
+}
+
+// readEABKey reads the base64url encoded HMAC key of an external account
+// binding.
+func readEABKey(filename string) ([]byte, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, errs.FileError(err, filename)
+	}
+	s := strings.TrimRight(strings.TrimSpace(string(b)), "=")
+	key, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.Errorf("error decoding %s: the EAB key must be base64url encoded", filename)
+	}
+	if len(key) == 0 {
+		return nil, errors.Errorf("error decoding %s: the EAB key is empty", filename)
+	}
+	return key, nil
+}
+
+// eabTransport is an http.RoundTripper that adds an external account binding
+// to the new account request sent by ca.NewACMEClient. The client generates
+// its own account key, so the request is signed again with the key of the
+// transport, and that key must be set as the key of the client once created.
+type eabTransport struct {
+	http.RoundTripper
+	kid     string
+	hmacKey []byte
+	key     *jose.JSONWebKey
+	done    bool
+}
+
+func newEABTransport(rt http.RoundTripper, kid string, hmacKey []byte) (*eabTransport, error) {
+	key, err := jose.GenerateJWK("EC", "P-256", "ES256", "sig", "", 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "error generating ACME account key")
+	}
+	return &eabTransport{
+		RoundTripper: rt,
+		kid:          kid,
+		hmacKey:      hmacKey,
+		key:          key,
+	}, nil
+}
+
+// RoundTrip implements http.RoundTripper. The new account request is the only
+// one signed with a jwk header instead of a kid.
+func (t *eabTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.done || req.Method != http.MethodPost || req.Body == nil {
+		return t.RoundTripper.RoundTrip(req)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading ACME request")
+	}
+
+	var jws acmeAPI.ExternalAccountBinding
+	var header struct {
+		Nonce string          `json:"nonce"`
+		URL   string          `json:"url"`
+		JWK   json.RawMessage `json:"jwk"`
+	}
+	if err := json.Unmarshal(body, &jws); err == nil {
+		if b, err := base64.RawURLEncoding.DecodeString(jws.Protected); err == nil {
+			_ = json.Unmarshal(b, &header)
 		}
+	}
+	if len(header.JWK) > 0 {
+		if body, err = t.newAccountRequest(jws.Payload, header.Nonce, header.URL); err != nil {
+			return nil, err
+		}
+		t.done = true
+	}
+
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	return t.RoundTripper.RoundTrip(req)
+}
 
-		return ca.WithTransport(&http.Transport{
-			TLSClientConfig: &tls.Config{
-				RootCAs:    rootCAs,
-				MinVersion: tls.VersionTLS12,
-			},
-		}), nil
+// newAccountRequest returns the new account request with the given payload
+// and the external account binding, signed with the key of the transport.
+func (t *eabTransport) newAccountRequest(payload, nonce, url string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "error decoding new account request")
+	}
+	var nar acmeAPI.NewAccountRequest
+	if err := json.Unmarshal(b, &nar); err != nil {
+		return nil, errors.Wrap(err, "error decoding new account request")
 	}
 
-	// Use local Root CA only
-	if root != "" {
-		return ca.WithRootFile(root), nil
+	pub, err := json.Marshal(t.key.Public())
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling ACME account key")
+	}
+	so := new(jose.SignerOptions)
+	so.WithHeader("kid", t.kid)
+	so.WithHeader("url", url)
+	eab, err := signACMEPayload(jose.SigningKey{Algorithm: jose.HS256, Key: t.hmacKey}, so, pub)
+	if err != nil {
+		return nil, errors.Wrap(err, "error signing external account binding")
+	}
+	nar.ExternalAccountBinding = eab
+	if b, err = json.Marshal(nar); err != nil {
+		return nil, errors.Wrap(err, "error marshaling new account request")
 	}
 
-	// Use system store only
-	return ca.WithTransport(http.DefaultTransport), nil
+	so = new(jose.SignerOptions)
+	so.WithHeader("nonce", nonce)
+	so.WithHeader("url", url)
+	so.WithHeader("jwk", t.key.Public())
+	jws, err := signACMEPayload(jose.SigningKey{
+		Algorithm: jose.SignatureAlgorithm(t.key.Algorithm),
+		Key:       t.key.Key,
+	}, so, b)
+	if err != nil {
+		return nil, errors.Wrap(err, "error signing new account request")
+	}
+	return json.Marshal(jws)
+}
+
+// signACMEPayload signs the payload and returns the JWS in the flattened JSON
+// serialization used by ACME.
+func signACMEPayload(key jose.SigningKey, so *jose.SignerOptions, payload []byte) (*acmeAPI.ExternalAccountBinding, error) {
+	signer, err := jose.NewSigner(key, so)
+	if err != nil {
+		return nil, err
+	}
+	signed, err := signer.Sign(payload)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := signed.CompactSerialize()
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(raw, ".")
+	return &acmeAPI.ExternalAccountBinding{
+		Protected: parts[0],
+		Payload:   parts[1],
+		Sig:       parts[2],
+	}, nil
 }
 
 func (af *acmeFlow) GetCertificate() ([]*x509.Certificate, error) {
@@ -743,15 +900,28 @@ func (af *acmeFlow) GetCertificate() ([]*x509.Certificate, error) {
 		return nil, errors.Wrap(err, "error marshaling order request")
 	}
 
-	ops, err := af.getClientTruststoreOption(af.ctx.IsSet("acme"))
+	tr, err := af.getClientTransport(af.ctx.IsSet("acme"))
 	if err != nil {
 		return nil, err
 	}
 
-	ac, err := ca.NewACMEClient(af.acmeDir, af.ctx.StringSlice("contact"), ops)
+	// The external account binding is added to the new account request by
+	// the transport, and the account is registered with its key.
+	var eab *eabTransport
+	if af.eabKeyID != "" {
+		if eab, err = newEABTransport(tr, af.eabKeyID, af.eabKey); err != nil {
+			return nil, err
+		}
+		tr = eab
+	}
+
+	ac, err := ca.NewACMEClient(af.acmeDir, af.ctx.StringSlice("contact"), ca.WithTransport(tr))
 	if err != nil {
 		return nil, errors.Wrapf(err, "error initializing ACME client with server %s", af.acmeDir)
 	}
+	if eab != nil {
+		ac.Key = eab.key
+	}
 
 	o, err := ac.NewOrder(orderPayload)
 	if err != nil {
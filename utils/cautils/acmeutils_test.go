@@ -0,0 +1,171 @@
+package cautils
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+	"go.step.sm/crypto/jose"
+
+	acmeAPI "github.com/smallstep/certificates/acme/api"
+	"github.com/smallstep/certificates/ca"
+)
+
+func Test_readEABKey(t *testing.T) {
+	dir := t.TempDir()
+	write := func(t *testing.T, name, content string) string {
+		t.Helper()
+		fn := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(fn, []byte(content), 0600))
+		return fn
+	}
+
+	tests := []struct {
+		name     string
+		filename string
+		want     []byte
+		wantErr  bool
+	}{
+		{"ok/raw", write(t, "raw", "c2VjcmV0LWtleQ"), []byte("secret-key"), false},
+		{"ok/padded", write(t, "padded", "c2VjcmV0LWtleQ==\n"), []byte("secret-key"), false},
+		{"ok/url", write(t, "url", "-_8"), []byte{0xfb, 0xff}, false},
+		{"fail/std", write(t, "std", "+/8"), nil, true},
+		{"fail/empty", write(t, "empty", "\n"), nil, true},
+		{"fail/missing", filepath.Join(dir, "missing"), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readEABKey(tt.filename)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_eabTransport(t *testing.T) {
+	hmacKey := []byte("secret-key")
+
+	// The fake ACME server checks the external account binding of the new
+	// account request, and that the order is signed with the account key.
+	var accountKey *jose.JSONWebKey
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	mux.HandleFunc("/directory", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(acmeAPI.Directory{
+			NewNonce:   srv.URL + "/nonce",
+			NewAccount: srv.URL + "/account",
+			NewOrder:   srv.URL + "/order",
+		})
+	})
+	mux.HandleFunc("/nonce", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Replay-Nonce", "nonce")
+	})
+	mux.HandleFunc("/account", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		jws, err := jose.ParseJWS(string(body))
+		require.NoError(t, err)
+		header := jws.Signatures[0].Protected
+		require.NotNil(t, header.JSONWebKey)
+		assert.Equal(t, "nonce", header.Nonce)
+		payload, err := jws.Verify(header.JSONWebKey)
+		require.NoError(t, err)
+
+		var nar acmeAPI.NewAccountRequest
+		require.NoError(t, json.Unmarshal(payload, &nar))
+		assert.Equal(t, []string{"mailto:jane@example.com"}, nar.Contact)
+		require.NotNil(t, nar.ExternalAccountBinding)
+		b, err := json.Marshal(nar.ExternalAccountBinding)
+		require.NoError(t, err)
+		eab, err := jose.ParseJWS(string(b))
+		require.NoError(t, err)
+		assert.Equal(t, "kid-1", eab.Signatures[0].Protected.KeyID)
+		assert.Equal(t, srv.URL+"/account", eab.Signatures[0].Protected.ExtraHeaders["url"])
+		eabPayload, err := eab.Verify(hmacKey)
+		require.NoError(t, err)
+		pub, err := json.Marshal(header.JSONWebKey)
+		require.NoError(t, err)
+		assert.JSONEq(t, string(pub), string(eabPayload))
+
+		accountKey = header.JSONWebKey
+		w.Header().Set("Location", srv.URL+"/account/1")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"status":"valid"}`))
+	})
+	mux.HandleFunc("/order", func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		jws, err := jose.ParseJWS(string(body))
+		require.NoError(t, err)
+		assert.Equal(t, srv.URL+"/account/1", jws.Signatures[0].Protected.KeyID)
+		require.NotNil(t, accountKey)
+		_, err = jws.Verify(accountKey)
+		require.NoError(t, err)
+		w.Header().Set("Location", srv.URL+"/order/1")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"status":"pending"}`))
+	})
+
+	eab, err := newEABTransport(http.DefaultTransport, "kid-1", hmacKey)
+	require.NoError(t, err)
+	ac, err := ca.NewACMEClient(srv.URL+"/directory", []string{"mailto:jane@example.com"}, ca.WithTransport(eab))
+	require.NoError(t, err)
+	assert.True(t, eab.done)
+	ac.Key = eab.key
+
+	_, err = ac.NewOrder([]byte(`{"identifiers":[{"type":"dns","value":"foo.internal"}]}`))
+	assert.NoError(t, err)
+}
+
+func Test_newACMEFlow_eab(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "eab.key")
+	require.NoError(t, os.WriteFile(keyFile, []byte("c2VjcmV0LWtleQ"), 0600))
+
+	newContext := func(t *testing.T, kid, keyFile string) *cli.Context {
+		t.Helper()
+		fs := flag.NewFlagSet("contrive", 0)
+		_ = fs.Bool("standalone", true, "")
+		_ = fs.String("acme", "https://acme.example.com/directory", "")
+		_ = fs.String("eab-key-id", kid, "")
+		_ = fs.String("eab-key-file", keyFile, "")
+		return cli.NewContext(&cli.App{}, fs, nil)
+	}
+
+	tests := []struct {
+		name    string
+		ctx     *cli.Context
+		wantKID string
+		wantKey []byte
+		wantErr string
+	}{
+		{"ok", newContext(t, "kid-1", keyFile), "kid-1", []byte("secret-key"), ""},
+		{"ok/no-eab", newContext(t, "", ""), "", nil, ""},
+		{"fail/no-key-file", newContext(t, "kid-1", ""), "", nil, "flag '--eab-key-id' requires the '--eab-key-file' flag"},
+		{"fail/no-key-id", newContext(t, "", keyFile), "", nil, "flag '--eab-key-file' requires the '--eab-key-id' flag"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			af, err := newACMEFlow(tt.ctx, withSubjectSANs("foo.internal", nil))
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantKID, af.eabKeyID)
+			assert.Equal(t, tt.wantKey, af.eabKey)
+		})
+	}
+}
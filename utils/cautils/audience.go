@@ -0,0 +1,42 @@
+package cautils
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// selectAudience returns the CA URL in the audiences of a token whose host
+// contains the hint in the --issuer-hint flag. The comparison is
+// case-insensitive, and only the audiences with an http or https URL are
+// considered. Audiences with the same scheme and host are the same CA, and
+// the first one is returned. It fails if no CA or more than one CA matches
+// the hint.
+func selectAudience(audiences []string, hint string) (string, error) {
+	lowerHint := strings.ToLower(hint)
+	var matches []string
+	seen := make(map[string]bool)
+	for _, aud := range audiences {
+		u, err := url.Parse(aud)
+		if err != nil {
+			continue
+		}
+		scheme, host := strings.ToLower(u.Scheme), strings.ToLower(u.Host)
+		if (scheme != "http" && scheme != "https") || !strings.Contains(host, lowerHint) {
+			continue
+		}
+		if key := scheme + "://" + host; !seen[key] {
+			seen[key] = true
+			matches = append(matches, aud)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", errors.Errorf("error using --issuer-hint: no audience in the token matches %q", hint)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", errors.Errorf("error using --issuer-hint: more than one audience in the token matches %q: %s", hint, strings.Join(matches, ", "))
+	}
+}
@@ -0,0 +1,50 @@
+package cautils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_selectAudience(t *testing.T) {
+	audiences := []string{
+		"https://ca.us.example.com/1.0/sign",
+		"https://ca.eu.example.com/1.0/sign",
+		"https://backup.eu.example.com/1.0/sign",
+		"https://backup.eu.example.com/sign",
+		"https://ca.ap.example.com/sign",
+		"HTTPS://CA.AP.EXAMPLE.COM/1.0/sign",
+		"ftp://ca.sa.example.com/sign",
+		"https://ca.in.example.com/signing-ca/sign",
+		"step-ca-eu",
+	}
+
+	tests := []struct {
+		name    string
+		hint    string
+		want    string
+		wantErr bool
+	}{
+		{"ok", "ca.us", "https://ca.us.example.com/1.0/sign", false},
+		{"ok/case", "CA.EU", "https://ca.eu.example.com/1.0/sign", false},
+		{"ok/backup", "backup", "https://backup.eu.example.com/1.0/sign", false},
+		{"ok/same-host", "ca.ap", "https://ca.ap.example.com/sign", false},
+		{"fail/none", "ca.af", "", true},
+		{"fail/scheme", "ca.sa", "", true},
+		{"fail/path", "signing-ca", "", true},
+		{"fail/not-url", "step-ca", "", true},
+		{"fail/multiple", "eu", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectAudience(audiences, tt.hint)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package cautils
 
 import (
 	"encoding/json"
+	"encoding/pem"
 	"io"
 	"net"
 	"net/http"
@@ -93,6 +94,7 @@ func bootstrap(ctx *cli.Context, caURL, fingerprint string, opts ...bootstrapOpt
 		return errors.Wrap(err, "error downloading root certificate")
 	}
 
+	var newContext *step.Context
 	if UseContext(ctx) {
 		ctxName := ctx.String("context")
 		if ctxName == "" {
@@ -106,25 +108,51 @@ func bootstrap(ctx *cli.Context, caURL, fingerprint string, opts ...bootstrapOpt
 		if ctxProfile == "" {
 			ctxProfile = ctxName
 		}
-		if err := step.Contexts().Add(&step.Context{
+		newContext = &step.Context{
 			Name:      ctxName,
 			Profile:   ctxProfile,
 			Authority: ctxAuthority,
-		}); err != nil {
-			return errors.Wrapf(err, "error adding context: '%s' - {authority: '%s', profile: '%s'}",
-				ctxName, ctxAuthority, ctxProfile)
 		}
-		if err := step.Contexts().SaveCurrent(ctxName); err != nil {
-			return errors.Wrap(err, "error storing new default context")
+	}
+
+	// The files are written in the path of the new context. The overwrite
+	// prompts are shown before taking the lock, so a process waiting for the
+	// user does not block the other ones.
+	rootFile := pki.GetRootCAPath()
+	configFile := step.DefaultsFile()
+	if newContext != nil {
+		rel, err := filepath.Rel(step.Path(), rootFile)
+		if err != nil {
+			return errors.Wrap(err, "error getting root certificate path")
 		}
-		if err := step.Contexts().SetCurrent(ctxName); err != nil {
-			return errors.Wrap(err, "error setting context '%s'")
+		rootFile = filepath.Join(newContext.Path(), rel)
+		configFile = newContext.DefaultsFile()
+	}
+	for _, name := range []string{rootFile, configFile} {
+		if err := utils.ConfirmOverwrite(name); err != nil {
+			return err
+		}
+	}
+
+	// Serialize the writes with other step processes bootstrapping at the
+	// same time.
+	unlock, err := utils.LockStepPath()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if newContext != nil {
+		if err := utils.SaveContext(newContext); err != nil {
+			return errors.Wrapf(err, "error adding context: '%s' - {authority: '%s', profile: '%s'}",
+				newContext.Name, newContext.Authority, newContext.Profile)
+		}
+		if err := step.Contexts().SetCurrent(newContext.Name); err != nil {
+			return errors.Wrapf(err, "error setting context '%s'", newContext.Name)
 		}
 	} else {
 		WarnContext()
 	}
-	rootFile := pki.GetRootCAPath()
-	configFile := step.DefaultsFile()
 
 	if err = os.MkdirAll(filepath.Dir(rootFile), 0700); err != nil {
 		return errs.FileError(err, rootFile)
@@ -135,10 +163,13 @@ func bootstrap(ctx *cli.Context, caURL, fingerprint string, opts ...bootstrapOpt
 	}
 
 	// Serialize root
-	_, err = pemutil.Serialize(resp.RootPEM.Certificate, pemutil.ToFile(rootFile, 0600))
+	block, err := pemutil.Serialize(resp.RootPEM.Certificate)
 	if err != nil {
 		return err
 	}
+	if err := utils.ReplaceFile(rootFile, pem.EncodeToMemory(block), 0600); err != nil {
+		return err
+	}
 	ui.Printf("The root certificate has been saved in %s.\n", rootFile)
 
 	// make sure to store the url with https
@@ -151,7 +182,7 @@ func bootstrap(ctx *cli.Context, caURL, fingerprint string, opts ...bootstrapOpt
 	b, err := json.MarshalIndent(bootstrapConfig{
 		CA:          caURL,
 		Fingerprint: fingerprint,
-		Root:        pki.GetRootCAPath(),
+		Root:        rootFile,
 		Redirect:    bc.redirectURL,
 	}, "", "  ")
 	if err != nil {
@@ -162,7 +193,7 @@ func bootstrap(ctx *cli.Context, caURL, fingerprint string, opts ...bootstrapOpt
 	ctx.Set("fingerprint", fingerprint)
 	ctx.Set("root", rootFile)
 
-	if err := utils.WriteFile(configFile, b, 0644); err != nil {
+	if err := utils.ReplaceFile(configFile, b, 0644); err != nil {
 		return err
 	}
 
@@ -176,8 +207,8 @@ func bootstrap(ctx *cli.Context, caURL, fingerprint string, opts ...bootstrapOpt
 		}
 
 		if _, err := os.Stat(profileDefaultsFile); os.IsNotExist(err) {
-			if err := os.WriteFile(profileDefaultsFile, []byte("{}"), 0600); err != nil {
-				return errs.FileError(err, profileDefaultsFile)
+			if err := utils.ReplaceFile(profileDefaultsFile, []byte("{}"), 0600); err != nil {
+				return err
 			}
 			ui.Printf("The profile configuration has been saved in %s.\n", profileDefaultsFile)
 		}
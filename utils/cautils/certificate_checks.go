@@ -0,0 +1,224 @@
+package cautils
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/certificates/api"
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/cli-utils/ui"
+)
+
+// clampTolerance is the difference allowed between the requested and the
+// issued not after before considering that the CA has clamped the validity.
+const clampTolerance = time.Minute
+
+// defaultSignatureAlgorithms are the signature algorithms allowed in the
+// certificate chain if the --allowed-signature-algs flag is not used.
+var defaultSignatureAlgorithms = []x509.SignatureAlgorithm{
+	x509.SHA256WithRSA, x509.SHA384WithRSA, x509.SHA512WithRSA,
+	x509.SHA256WithRSAPSS, x509.SHA384WithRSAPSS, x509.SHA512WithRSAPSS,
+	x509.ECDSAWithSHA256, x509.ECDSAWithSHA384, x509.ECDSAWithSHA512,
+	x509.PureEd25519,
+}
+
+// checkSignResponse runs the post-issuance checks enabled by the --strict,
+// --strict-sans, --require-chain, --warn-expiry-fatal, and --on-clamp flags on the
+// certificate chain returned by the CA, and the signature algorithm check of
+// the --allowed-signature-algs flag. It runs before any file is written.
+func checkSignResponse(ctx *cli.Context, client CaClient, csr *x509.CertificateRequest, notAfter api.TimeDuration, chain []*x509.Certificate) error {
+	if len(chain) == 0 {
+		return errors.New("error validating step-ca API response: certificate chain is empty")
+	}
+
+	strict := ctx.Bool("strict")
+	leaf := chain[0]
+
+	if err := checkSignatureAlgorithms(chain, ctx.StringSlice("allowed-signature-algs")); err != nil {
+		return err
+	}
+
+	if strict || ctx.Bool("strict-sans") {
+		if err := checkSANs(csr, leaf); err != nil {
+			return err
+		}
+	}
+
+	if strict || ctx.Bool("require-chain") {
+		if err := checkChain(client.GetRootCAs(), chain); err != nil {
+			return err
+		}
+	}
+
+	if !notAfter.IsZero() {
+		if want := notAfter.Time(); leaf.NotAfter.Before(want.Add(-clampTolerance)) {
+			onClamp, err := parseOnClamp(ctx)
+			if err != nil {
+				return err
+			}
+			switch onClamp {
+			case "fail":
+				return errors.Errorf("the CA has reduced the certificate validity: requested not after %s, got %s",
+					want.UTC().Format(time.RFC3339), leaf.NotAfter.UTC().Format(time.RFC3339))
+			case "warn":
+				ui.Printf(`{{ "warning:" | yellow }} the CA has reduced the certificate validity: requested not after %s, got %s`+"\n",
+					want.UTC().Format(time.RFC3339), leaf.NotAfter.UTC().Format(time.RFC3339))
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseOnClamp returns the policy applied when the CA reduces the requested
+// validity: accept, warn, or fail. The --strict and --warn-expiry-fatal flags
+// change the default policy to fail, and --warn-expiry-fatal cannot be used
+// with a different policy.
+func parseOnClamp(ctx *cli.Context) (string, error) {
+	v := ctx.String("on-clamp")
+	switch v {
+	case "", "accept", "warn", "fail":
+	default:
+		return "", errs.InvalidFlagValue(ctx, "on-clamp", v, "accept, warn, fail")
+	}
+	if ctx.Bool("warn-expiry-fatal") {
+		if ctx.IsSet("on-clamp") && v != "fail" {
+			return "", errs.IncompatibleFlagValue(ctx, "warn-expiry-fatal", "on-clamp", v)
+		}
+		return "fail", nil
+	}
+	if ctx.IsSet("on-clamp") {
+		return v, nil
+	}
+	if ctx.Bool("strict") {
+		return "fail", nil
+	}
+	if v == "" {
+		return "warn", nil
+	}
+	return v, nil
+}
+
+// checkSignatureAlgorithms returns an error if a certificate in the chain is
+// signed with an algorithm not in the allowed list. The names in the list are
+// case-insensitive, and they can be separated by commas. If the list is empty
+// the default algorithms are allowed.
+func checkSignatureAlgorithms(chain []*x509.Certificate, allowed []string) error {
+	names := make(map[string]bool)
+	for _, name := range splitSignatureAlgorithms(allowed) {
+		names[name] = true
+	}
+	if len(names) == 0 {
+		for _, alg := range defaultSignatureAlgorithms {
+			names[strings.ToLower(alg.String())] = true
+		}
+	}
+
+	for _, crt := range chain {
+		if !names[strings.ToLower(crt.SignatureAlgorithm.String())] {
+			return errors.Errorf("the certificate %q is signed with the signature algorithm %s, which is not allowed",
+				crt.Subject.String(), crt.SignatureAlgorithm)
+		}
+	}
+	return nil
+}
+
+// checkAllowedSignatureAlgsFlag returns an error if a name in the
+// --allowed-signature-algs flag is not a known signature algorithm.
+func checkAllowedSignatureAlgsFlag(ctx *cli.Context) error {
+	known := make(map[string]bool)
+	for alg := x509.MD2WithRSA; alg <= x509.PureEd25519; alg++ {
+		known[strings.ToLower(alg.String())] = true
+	}
+	for _, name := range splitSignatureAlgorithms(ctx.StringSlice("allowed-signature-algs")) {
+		if !known[name] {
+			return errs.InvalidFlagValueMsg(ctx, "allowed-signature-algs", name, "unknown signature algorithm")
+		}
+	}
+	return nil
+}
+
+// splitSignatureAlgorithms returns the lowercase names in the given list,
+// each element can have multiple names separated by commas.
+func splitSignatureAlgorithms(list []string) []string {
+	var names []string
+	for _, s := range list {
+		for _, name := range strings.Split(s, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, strings.ToLower(name))
+			}
+		}
+	}
+	return names
+}
+
+// checkSANs returns an error if the SANs in the certificate are not the same
+// as the ones in the certificate request.
+func checkSANs(csr *x509.CertificateRequest, leaf *x509.Certificate) error {
+	want := sanList(csr.DNSNames, csr.IPAddresses, csr.EmailAddresses, csr.URIs)
+	got := sanList(leaf.DNSNames, leaf.IPAddresses, leaf.EmailAddresses, leaf.URIs)
+	if strings.Join(want, ",") != strings.Join(got, ",") {
+		return errors.Errorf("the CA has modified the requested SANs: requested [%s], got [%s]",
+			strings.Join(want, ", "), strings.Join(got, ", "))
+	}
+	return nil
+}
+
+// checkChain verifies the certificate chain with the given roots and checks
+// that the leaf does not outlive its issuer.
+func checkChain(roots *x509.CertPool, chain []*x509.Certificate) error {
+	if roots == nil {
+		return errors.New("error verifying certificate chain: root certificate not available")
+	}
+
+	leaf := chain[0]
+	intermediates := x509.NewCertPool()
+	for _, crt := range chain[1:] {
+		intermediates.AddCert(crt)
+	}
+	chains, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return errors.Wrap(err, "error verifying certificate chain")
+	}
+
+	if len(chains[0]) < 2 {
+		return nil
+	}
+	if issuer := chains[0][1]; leaf.NotAfter.After(issuer.NotAfter) {
+		return errors.Errorf("the certificate expires after its issuer: certificate not after %s, issuer not after %s",
+			leaf.NotAfter.UTC().Format(time.RFC3339), issuer.NotAfter.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// sanList returns a sorted list with the string representation of the given
+// SANs.
+func sanList(dnsNames []string, ips []net.IP, emails []string, uris []*url.URL) []string {
+	sans := make([]string, 0, len(dnsNames)+len(ips)+len(emails)+len(uris))
+	for _, s := range dnsNames {
+		sans = append(sans, "dns:"+s)
+	}
+	for _, ip := range ips {
+		sans = append(sans, "ip:"+ip.String())
+	}
+	for _, s := range emails {
+		sans = append(sans, "email:"+s)
+	}
+	for _, u := range uris {
+		sans = append(sans, fmt.Sprintf("uri:%s", u))
+	}
+	sort.Strings(sans)
+	return sans
+}
@@ -0,0 +1,178 @@
+package cautils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"flag"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+	"go.step.sm/crypto/minica"
+)
+
+func mustCertificateRequest(t *testing.T, template *x509.CertificateRequest) *x509.CertificateRequest {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	require.NoError(t, err)
+	csr, err := x509.ParseCertificateRequest(der)
+	require.NoError(t, err)
+	return csr
+}
+
+func Test_checkSANs(t *testing.T) {
+	csr := mustCertificateRequest(t, &x509.CertificateRequest{
+		Subject:     pkix.Name{CommonName: "foo"},
+		DNSNames:    []string{"foo.internal", "bar.internal"},
+		IPAddresses: []net.IP{net.ParseIP("10.0.0.1")},
+	})
+
+	tests := []struct {
+		name    string
+		leaf    *x509.Certificate
+		wantErr bool
+	}{
+		{"ok", &x509.Certificate{DNSNames: []string{"foo.internal", "bar.internal"}, IPAddresses: []net.IP{net.ParseIP("10.0.0.1")}}, false},
+		{"ok/order", &x509.Certificate{DNSNames: []string{"bar.internal", "foo.internal"}, IPAddresses: []net.IP{net.ParseIP("10.0.0.1")}}, false},
+		{"fail/missing", &x509.Certificate{DNSNames: []string{"foo.internal"}, IPAddresses: []net.IP{net.ParseIP("10.0.0.1")}}, true},
+		{"fail/added", &x509.Certificate{DNSNames: []string{"foo.internal", "bar.internal", "zar.internal"}, IPAddresses: []net.IP{net.ParseIP("10.0.0.1")}}, true},
+		{"fail/type", &x509.Certificate{DNSNames: []string{"foo.internal", "bar.internal", "10.0.0.1"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSANs(csr, tt.leaf)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_checkChain(t *testing.T) {
+	ca, err := minica.New()
+	require.NoError(t, err)
+	other, err := minica.New()
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Root)
+
+	sign := func(t *testing.T, notAfter time.Time) *x509.Certificate {
+		t.Helper()
+		csr := mustCertificateRequest(t, &x509.CertificateRequest{
+			Subject:  pkix.Name{CommonName: "foo"},
+			DNSNames: []string{"foo.internal"},
+		})
+		crt, err := ca.SignCSR(csr, minica.WithModifyFunc(func(c *x509.Certificate) error {
+			c.NotAfter = notAfter
+			return nil
+		}))
+		require.NoError(t, err)
+		return crt
+	}
+
+	leaf := sign(t, time.Now().Add(time.Hour))
+	tooLong := sign(t, ca.Intermediate.NotAfter.Add(time.Hour))
+
+	tests := []struct {
+		name    string
+		roots   *x509.CertPool
+		chain   []*x509.Certificate
+		wantErr bool
+	}{
+		{"ok", roots, []*x509.Certificate{leaf, ca.Intermediate}, false},
+		{"fail/no-roots", nil, []*x509.Certificate{leaf, ca.Intermediate}, true},
+		{"fail/no-intermediate", roots, []*x509.Certificate{leaf}, true},
+		{"fail/other-intermediate", roots, []*x509.Certificate{leaf, other.Intermediate}, true},
+		{"fail/outlives-issuer", roots, []*x509.Certificate{tooLong, ca.Intermediate}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkChain(tt.roots, tt.chain)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_checkSignatureAlgorithms(t *testing.T) {
+	ecdsaCert := &x509.Certificate{SignatureAlgorithm: x509.ECDSAWithSHA256}
+	rsaCert := &x509.Certificate{SignatureAlgorithm: x509.SHA256WithRSA}
+	sha1Cert := &x509.Certificate{SignatureAlgorithm: x509.SHA1WithRSA}
+
+	tests := []struct {
+		name    string
+		chain   []*x509.Certificate
+		allowed []string
+		wantErr bool
+	}{
+		{"ok/default", []*x509.Certificate{ecdsaCert, rsaCert}, nil, false},
+		{"ok/allowed", []*x509.Certificate{ecdsaCert}, []string{"ecdsa-sha256"}, false},
+		{"ok/comma-separated", []*x509.Certificate{ecdsaCert, sha1Cert}, []string{"ECDSA-SHA256, SHA1-RSA"}, false},
+		{"fail/default-sha1", []*x509.Certificate{ecdsaCert, sha1Cert}, nil, true},
+		{"fail/not-allowed", []*x509.Certificate{ecdsaCert, rsaCert}, []string{"ECDSA-SHA256"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSignatureAlgorithms(tt.chain, tt.allowed)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func Test_parseOnClamp(t *testing.T) {
+	newContext := func(t *testing.T, args ...string) *cli.Context {
+		t.Helper()
+		fs := flag.NewFlagSet("contrive", 0)
+		_ = fs.String("on-clamp", "warn", "")
+		_ = fs.Bool("strict", false, "")
+		_ = fs.Bool("warn-expiry-fatal", false, "")
+		require.NoError(t, fs.Parse(args))
+		return cli.NewContext(&cli.App{}, fs, nil)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{"ok/default", nil, "warn", false},
+		{"ok/accept", []string{"--on-clamp", "accept"}, "accept", false},
+		{"ok/fail", []string{"--on-clamp", "fail"}, "fail", false},
+		{"ok/strict", []string{"--strict"}, "fail", false},
+		{"ok/strict-accept", []string{"--strict", "--on-clamp", "accept"}, "accept", false},
+		{"ok/warn-expiry-fatal", []string{"--warn-expiry-fatal"}, "fail", false},
+		{"ok/warn-expiry-fatal-fail", []string{"--warn-expiry-fatal", "--on-clamp", "fail"}, "fail", false},
+		{"fail/warn-expiry-fatal-warn", []string{"--warn-expiry-fatal", "--on-clamp", "warn"}, "", true},
+		{"fail/unknown", []string{"--on-clamp", "ignore"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseOnClamp(newContext(t, tt.args...))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
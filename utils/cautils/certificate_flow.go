@@ -1,10 +1,14 @@
 package cautils
 
 import (
+	"bytes"
 	"crypto"
 	"crypto/rand"
+	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/json"
 	"encoding/pem"
 	"fmt"
 	"net"
@@ -28,14 +32,18 @@ import (
 	"go.step.sm/crypto/x509util"
 
 	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/prompt"
+	"github.com/smallstep/cli/internal/tracing"
 	"github.com/smallstep/cli/token"
 	"github.com/smallstep/cli/utils"
 )
 
 // CertificateFlow manages the flow to retrieve a new certificate.
 type CertificateFlow struct {
-	offlineCA *OfflineCA
-	offline   bool
+	offlineCA       *OfflineCA
+	offline         bool
+	cipherSuites    []uint16
+	maxResponseSize int64
 }
 
 type flowContext struct {
@@ -43,6 +51,8 @@ type flowContext struct {
 	SSHPublicKey            ssh.PublicKey
 	CertificateRequest      *x509.CertificateRequest
 	ConfirmationFingerprint string
+	Extensions              []pkix.Extension
+	Tracer                  *tracing.Tracer
 }
 
 // sharedContext is used to share information between commands.
@@ -88,6 +98,22 @@ func WithConfirmationFingerprint(fp string) Option {
 	})
 }
 
+// WithExtensions sets extensions to add to the certificate request created
+// by the flow. The extensions in the --extra-extension flag take precedence.
+func WithExtensions(extensions []pkix.Extension) Option {
+	return newFuncFlowOption(func(fo *flowContext) {
+		fo.Extensions = extensions
+	})
+}
+
+// WithTracer sets the tracer used to record the key generation and the sign
+// request as spans.
+func WithTracer(t *tracing.Tracer) Option {
+	return newFuncFlowOption(func(fo *flowContext) {
+		fo.Tracer = t
+	})
+}
+
 // NewCertificateFlow initializes a cli flow to get a new certificate.
 func NewCertificateFlow(ctx *cli.Context, opts ...Option) (*CertificateFlow, error) {
 	var err error
@@ -110,9 +136,50 @@ func NewCertificateFlow(ctx *cli.Context, opts ...Option) (*CertificateFlow, err
 		}
 	}
 
+	// The cipher suites are only restricted by the commands with the
+	// --tls-cipher-suites flag.
+	var cipherSuites []uint16
+	if !offline {
+		if cipherSuites, err = ParseCipherSuitesFlag(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	// The size of the responses is only limited if the --max-response-size
+	// flag is used.
+	var maxResponseSize int64
+	if ctx.IsSet("max-response-size") {
+		if maxResponseSize = ctx.Int64("max-response-size"); maxResponseSize <= 0 {
+			return nil, errs.InvalidFlagValueMsg(ctx, "max-response-size", ctx.String("max-response-size"), "the size must be greater than 0")
+		}
+	}
+
+	// The flags used when the certificate is requested or written are
+	// checked before generating a key or a token.
+	if _, err := parseChainOrder(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := ParseLineEnding(ctx); err != nil {
+		return nil, err
+	}
+	if err := checkSubjectKeyIDFlag(ctx); err != nil {
+		return nil, err
+	}
+	if err := checkAllowedSignatureAlgsFlag(ctx); err != nil {
+		return nil, err
+	}
+
+	// The tokens generated by the command are short-lived, the deadline is
+	// only meant for a token given in the --token flag.
+	if ctx.Bool("deadline-from-token") && ctx.String("token") == "" {
+		return nil, errs.RequiredWithFlag(ctx, "deadline-from-token", "token")
+	}
+
 	return &CertificateFlow{
-		offlineCA: offlineClient,
-		offline:   offline,
+		offlineCA:       offlineClient,
+		offline:         offline,
+		cipherSuites:    cipherSuites,
+		maxResponseSize: maxResponseSize,
 	}, nil
 }
 
@@ -133,12 +200,20 @@ func (f *CertificateFlow) GetClient(ctx *cli.Context, tok string, options ...ca.
 	if err != nil {
 		return nil, errors.Wrap(err, "error parsing flag '--token'")
 	}
+	// Select the CA URL in the audiences of the token, the hint takes
+	// precedence over the --ca-url flag.
+	if hint := ctx.String("issuer-hint"); hint != "" {
+		if caURL, err = selectAudience(jwt.Payload.Audience, hint); err != nil {
+			return nil, err
+		}
+	}
 	// Prepare client for bootstrap or provisioning tokens
+	var rootOption ca.ClientOption
 	if jwt.Payload.SHA != "" && len(jwt.Payload.Audience) > 0 && strings.HasPrefix(strings.ToLower(jwt.Payload.Audience[0]), "http") {
 		if caURL == "" {
 			caURL = jwt.Payload.Audience[0]
 		}
-		options = append(options, ca.WithRootSHA256(jwt.Payload.SHA))
+		rootOption = ca.WithRootSHA256(jwt.Payload.SHA)
 	} else {
 		if caURL == "" {
 			return nil, errs.RequiredFlag(ctx, "ca-url")
@@ -149,11 +224,50 @@ func (f *CertificateFlow) GetClient(ctx *cli.Context, tok string, options ...ca.
 				return nil, errs.RequiredFlag(ctx, "root")
 			}
 		}
-		options = append(options, ca.WithRootFile(root))
+		rootOption = ca.WithRootFile(root)
 	}
 
 	ui.PrintSelected("CA", caURL)
-	return ca.NewClient(caURL, options...)
+	client, err := ca.NewClient(caURL, append(options, rootOption)...)
+	if err != nil {
+		return nil, err
+	}
+	if len(f.cipherSuites) == 0 && f.maxResponseSize == 0 {
+		return client, nil
+	}
+	return f.newRestrictedClient(caURL, client.GetRootCAs(), options...)
+}
+
+// newRestrictedClient returns a client with a transport that trusts the given
+// roots, and that only allows the cipher suites in the --tls-cipher-suites
+// flag and limits the size of the responses to the --max-response-size flag.
+// The client cannot return its transport, so it is only replaced if one of
+// these flags is used.
+func (f *CertificateFlow) newRestrictedClient(caURL string, rootCAs *x509.CertPool, options ...ca.ClientOption) (CaClient, error) {
+	tr := NewTransport(rootCAs, f.cipherSuites)
+	client, err := ca.NewClient(caURL, append(options, ca.WithTransport(tr))...)
+	if err != nil {
+		return nil, err
+	}
+	if f.maxResponseSize == 0 {
+		return client, nil
+	}
+	// The transport is wrapped after the client has added the default
+	// identity to it, and the client can no longer get the roots from it.
+	client.SetTransport(&limitResponseTransport{base: tr, limit: f.maxResponseSize})
+	return &rootCAsClient{Client: client, rootCAs: rootCAs}, nil
+}
+
+// rootCAsClient is a ca.Client that returns the given root certificates. It
+// is used when the transport of the client is not an http.Transport.
+type rootCAsClient struct {
+	*ca.Client
+	rootCAs *x509.CertPool
+}
+
+// GetRootCAs returns the root certificates used by the client.
+func (c *rootCAsClient) GetRootCAs() *x509.CertPool {
+	return c.rootCAs
 }
 
 // GenerateToken generates a token for immediate use (therefore only default
@@ -181,7 +295,7 @@ func (f *CertificateFlow) GenerateToken(ctx *cli.Context, subject string, sans [
 	}
 
 	if subject == "" {
-		subject, err = ui.Prompt("What DNS names or IP addresses would you like to use? (e.g. internal.smallstep.com)", ui.WithValidateNotEmpty())
+		subject, err = prompt.Prompt("What DNS names or IP addresses would you like to use? (e.g. internal.smallstep.com)", ui.WithValidateNotEmpty())
 		if err != nil {
 			return "", err
 		}
@@ -234,21 +348,137 @@ func (f *CertificateFlow) GenerateIdentityToken(ctx *cli.Context) (string, error
 
 // Sign signs the CSR using the online or the offline certificate authority.
 func (f *CertificateFlow) Sign(ctx *cli.Context, tok string, csr api.CertificateRequest, crtFile string) error {
-	client, err := f.GetClient(ctx, tok)
+	leafLast, err := parseChainOrder(ctx)
+	if err != nil {
+		return err
+	}
+	crlf, err := ParseLineEnding(ctx)
 	if err != nil {
 		return err
 	}
 
+	chain, root, err := f.signChain(ctx, tok, csr)
+	if err != nil {
+		return err
+	}
+
+	if leafLast {
+		for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+			chain[i], chain[j] = chain[j], chain[i]
+		}
+	}
+
+	var data []byte
+	for _, cert := range chain {
+		pemblk, err := pemutil.Serialize(cert)
+		if err != nil {
+			return errors.Wrap(err, "error serializing from step-ca API response")
+		}
+		data = append(data, pem.EncodeToMemory(pemblk)...)
+	}
+	if err := utils.WriteFile(crtFile, FormatLineEnding(data, crlf), 0600); err != nil {
+		return err
+	}
+
+	if rootOut := ctx.String("root-out"); rootOut != "" {
+		data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: root.Raw})
+		if err := utils.WriteFile(rootOut, FormatLineEnding(data, crlf), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TLSCertificate signs the CSR using the online or the offline certificate
+// authority, with the same checks as Sign, and returns the certificate chain
+// and the private key of the request as a tls.Certificate that can be used
+// directly in a tls.Config. No files are written.
+func (f *CertificateFlow) TLSCertificate(ctx *cli.Context, tok string, csr api.CertificateRequest, pk crypto.PrivateKey) (*tls.Certificate, error) {
+	chain, _, err := f.signChain(ctx, tok, csr)
+	if err != nil {
+		return nil, err
+	}
+
+	crt := &tls.Certificate{
+		PrivateKey: pk,
+		Leaf:       chain[0],
+	}
+	for _, c := range chain {
+		crt.Certificate = append(crt.Certificate, c.Raw)
+	}
+	return crt, nil
+}
+
+// responseChain returns the certificate chain in the sign response, with the
+// leaf certificate first.
+func responseChain(resp *api.SignResponse) []*x509.Certificate {
+	certs := resp.CertChainPEM
+	if len(certs) == 0 {
+		certs = []api.Certificate{resp.ServerPEM, resp.CaPEM}
+	}
+	chain := make([]*x509.Certificate, 0, len(certs))
+	for _, certPEM := range certs {
+		chain = append(chain, certPEM.Certificate)
+	}
+	return chain
+}
+
+// signChain signs the CSR and returns the certificate chain, with the leaf
+// certificate first, after running the post-issuance checks. With the
+// --root-out flag, it also returns the root certificate that validates the
+// chain.
+func (f *CertificateFlow) signChain(ctx *cli.Context, tok string, csr api.CertificateRequest) ([]*x509.Certificate, *x509.Certificate, error) {
+	client, err := f.GetClient(ctx, tok)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// verify the token before sending the request
+	if ctx.Bool("verify-token") {
+		provisioners, err := getProvisioners(client)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := verifyTokenSignature(tok, provisioners); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	// parse times or durations
 	notBefore, notAfter, err := flags.ParseTimeDuration(ctx)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	// parse template data
-	templateData, err := flags.ParseTemplateData(ctx)
+	// cap the not after with the expiration of the token
+	if ctx.Bool("deadline-from-token") {
+		var capped bool
+		if notAfter, capped, err = tokenDeadline(tok, notBefore, notAfter); err != nil {
+			return nil, nil, err
+		}
+		if capped {
+			ui.Printf(`{{ "warning:" | yellow }} the certificate validity is capped by the token expiration: not after %s`+"\n",
+				notAfter.Time().UTC().Format(time.RFC3339))
+		}
+		if d := time.Until(notAfter.Time()); d < minDeadlineValidity {
+			ui.Printf(`{{ "warning:" | yellow }} the certificate will be valid for less than %s: not after %s`+"\n",
+				minDeadlineValidity, notAfter.Time().UTC().Format(time.RFC3339))
+		}
+	}
+
+	// Validate the clamp policy before sending the request.
+	if _, err := parseOnClamp(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	// parse the subject key identifier and the template data
+	ski, err := parseSubjectKeyID(ctx, csr.PublicKey)
 	if err != nil {
-		return err
+		return nil, nil, err
+	}
+	templateData, err := parseTemplateData(ctx, ski)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	req := &api.SignRequest{
@@ -259,23 +489,107 @@ func (f *CertificateFlow) Sign(ctx *cli.Context, tok string, csr api.Certificate
 		TemplateData: templateData,
 	}
 
+	span := sharedContext.Tracer.Start("sign")
+	span.SetAttribute("ca.url", client.GetCaURL())
 	resp, err := client.Sign(req)
+	span.End(err)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
-	if len(resp.CertChainPEM) == 0 {
-		resp.CertChainPEM = []api.Certificate{resp.ServerPEM, resp.CaPEM}
-	}
-	var data []byte
-	for _, certPEM := range resp.CertChainPEM {
-		pemblk, err := pemutil.Serialize(certPEM.Certificate)
+	chain := responseChain(resp)
+	if ctx.Bool("cross-signed") {
+		selected, err := selectCrossSignedChain(client.GetRootCAs(), chain, resp.CaPEM.Certificate)
 		if err != nil {
-			return errors.Wrap(err, "error serializing from step-ca API response")
+			ui.Printf(`{{ "warning:" | yellow }} no certificate chain compatible with the trusted root was found: %s`+"\n", err)
+		} else {
+			chain = selected
 		}
-		data = append(data, pem.EncodeToMemory(pemblk)...)
 	}
-	return utils.WriteFile(crtFile, data, 0600)
+	if err := checkSignResponse(ctx, client, csr.CertificateRequest, notAfter, chain); err != nil {
+		return nil, nil, err
+	}
+	if ski != nil && !bytes.Equal(chain[0].SubjectKeyId, ski) {
+		err := errors.Errorf("the CA has not used the requested subject key identifier: requested %x, got %x", ski, chain[0].SubjectKeyId)
+		if ctx.Bool("strict") {
+			return nil, nil, err
+		}
+		ui.Printf(`{{ "warning:" | yellow }} %s`+"\n", err)
+	}
+
+	// The root is only required by the --root-out flag.
+	var root *x509.Certificate
+	if ctx.String("root-out") != "" {
+		if root, err = EffectiveRoot(client.GetRootCAs(), chain); err != nil {
+			return nil, nil, err
+		}
+	}
+	return chain, root, nil
+}
+
+// parseChainOrder returns true if the --chain-order flag requires the leaf
+// certificate at the end of the certificate file.
+func parseChainOrder(ctx *cli.Context) (bool, error) {
+	switch order := ctx.String("chain-order"); order {
+	case "", "leaf-first":
+		return false, nil
+	case "leaf-last":
+		return true, nil
+	default:
+		return false, errs.InvalidFlagValue(ctx, "chain-order", order, "leaf-first, leaf-last")
+	}
+}
+
+// ParseLineEnding returns true if the --line-ending flag requires CRLF line
+// endings in the PEM files.
+func ParseLineEnding(ctx *cli.Context) (bool, error) {
+	switch v := ctx.String("line-ending"); v {
+	case "", "lf":
+		return false, nil
+	case "crlf":
+		return true, nil
+	default:
+		return false, errs.InvalidFlagValue(ctx, "line-ending", v, "lf, crlf")
+	}
+}
+
+// FormatLineEnding returns the data with CRLF line endings if crlf is true,
+// or the data unmodified otherwise.
+func FormatLineEnding(data []byte, crlf bool) []byte {
+	if !crlf {
+		return data
+	}
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	return bytes.ReplaceAll(data, []byte("\n"), []byte("\r\n"))
+}
+
+// parseTemplateData parses the template data flags and adds the variables
+// required by other flags like --no-eku, or the subjectKeyId variable with
+// the value of the --ski flag if ski is not nil.
+func parseTemplateData(ctx *cli.Context, ski []byte) (json.RawMessage, error) {
+	data, err := flags.GetTemplateData(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if ctx.Bool("no-eku") {
+		if _, ok := data["extKeyUsage"]; ok {
+			return nil, errs.IncompatibleFlagValue(ctx, "no-eku", "set", "extKeyUsage")
+		}
+		data["extKeyUsage"] = []string{}
+	}
+
+	if ski != nil {
+		if _, ok := data["subjectKeyId"]; ok {
+			return nil, errs.IncompatibleFlagValue(ctx, "ski", "set", "subjectKeyId")
+		}
+		data["subjectKeyId"] = ski
+	}
+
+	if len(data) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(data)
 }
 
 // CreateSignRequest is a helper function that given an x509 OTT returns a
@@ -286,13 +600,29 @@ func (f *CertificateFlow) CreateSignRequest(ctx *cli.Context, tok, subject strin
 		return nil, nil, err
 	}
 
-	kty, crv, size, err := utils.GetKeyDetailsFromCLI(ctx, false, "kty", "curve", "size")
+	extraExtensions, err := parseExtraExtensions(ctx)
 	if err != nil {
 		return nil, nil, err
 	}
-	pk, err := keyutil.GenerateKey(kty, crv, size)
-	if err != nil {
-		return nil, nil, err
+	extraExtensions = mergeExtensions(extraExtensions, sharedContext.Extensions)
+
+	var pk crypto.PrivateKey
+	if keyFile := ctx.String("key"); keyFile != "" {
+		if pk, err = readPrivateKey(keyFile); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		kty, crv, size, err := utils.GetKeyDetailsFromCLI(ctx, false, "kty", "curve", "size")
+		if err != nil {
+			return nil, nil, err
+		}
+		span := sharedContext.Tracer.Start("key generation")
+		span.SetAttribute("key.type", kty)
+		pk, err = keyutil.GenerateKey(kty, crv, size)
+		span.End(err)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
 	dnsNames, ips, emails, uris := splitSANs(sans, jwt.Payload.SANs)
@@ -367,10 +697,19 @@ func (f *CertificateFlow) CreateSignRequest(ctx *cli.Context, tok, subject strin
 		Subject: pkix.Name{
 			CommonName: subject,
 		},
-		DNSNames:       dnsNames,
-		IPAddresses:    ips,
-		EmailAddresses: emails,
-		URIs:           uris,
+		DNSNames:        dnsNames,
+		IPAddresses:     ips,
+		EmailAddresses:  emails,
+		URIs:            uris,
+		ExtraExtensions: extraExtensions,
+	}
+
+	// Use the full distinguished name if given. The common name must match the
+	// subject, it is added if the name does not have one.
+	if dn := ctx.String("subject-dn"); dn != "" {
+		if template.RawSubject, err = subjectDNBytes(dn, subject); err != nil {
+			return nil, nil, err
+		}
 	}
 
 	csr, err := x509.CreateCertificateRequest(rand.Reader, template, pk)
@@ -390,6 +729,47 @@ func (f *CertificateFlow) CreateSignRequest(ctx *cli.Context, tok, subject strin
 	}, pk, nil
 }
 
+// subjectDNBytes returns the DER encoding of the distinguished name used in
+// the --subject-dn flag.
+func subjectDNBytes(dn, subject string) ([]byte, error) {
+	seq, err := ParseDistinguishedName(dn)
+	if err != nil {
+		return nil, err
+	}
+	if cn, ok := distinguishedNameCommonName(seq); !ok {
+		seq = append(seq, pkix.RelativeDistinguishedNameSET{
+			{Type: oidCommonName, Value: subject},
+		})
+	} else if cn != subject {
+		return nil, errors.Errorf("the common name %q in flag '--subject-dn' does not match the subject %q", cn, subject)
+	}
+	b, err := asn1.Marshal(seq)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling distinguished name")
+	}
+	return b, nil
+}
+
+// readPrivateKey reads the private key used with the --key flag. It reads the
+// key from STDIN if the filename is a hyphen ("-").
+func readPrivateKey(filename string) (crypto.PrivateKey, error) {
+	b, err := utils.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if filename == "-" {
+		filename = "STDIN"
+	}
+	key, err := pemutil.Parse(b, pemutil.WithFilename(filename))
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := key.(crypto.Signer); !ok {
+		return nil, errors.Errorf("error parsing %s: file is not a private key", filename)
+	}
+	return key, nil
+}
+
 // splitSANs unifies the SAN collections passed as arguments and returns a list
 // of DNS names, a list of IP addresses, and a list of emails.
 func splitSANs(args ...[]string) (dnsNames []string, ipAddresses []net.IP, email []string, uris []*url.URL) {
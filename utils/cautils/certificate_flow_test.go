@@ -0,0 +1,355 @@
+package cautils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+	"go.step.sm/crypto/jose"
+	"go.step.sm/crypto/pemutil"
+
+	"github.com/smallstep/certificates/api"
+
+	"github.com/smallstep/cli/internal/testca"
+	"github.com/smallstep/cli/token"
+)
+
+func Test_parseTemplateData(t *testing.T) {
+	newContext := func(t *testing.T, noEKU bool, set ...string) *cli.Context {
+		t.Helper()
+		fs := flag.NewFlagSet("contrive", 0)
+		_ = fs.Bool("no-eku", noEKU, "")
+		_ = fs.String("set-file", "", "")
+		sets := cli.StringSlice(set)
+		fs.Var(&sets, "set", "")
+		return cli.NewContext(&cli.App{}, fs, nil)
+	}
+
+	tests := []struct {
+		name    string
+		ctx     *cli.Context
+		ski     []byte
+		want    json.RawMessage
+		wantErr bool
+	}{
+		{"ok/empty", newContext(t, false), nil, nil, false},
+		{"ok/set", newContext(t, false, "foo=bar"), nil, json.RawMessage(`{"foo":"bar"}`), false},
+		{"ok/no-eku", newContext(t, true), nil, json.RawMessage(`{"extKeyUsage":[]}`), false},
+		{"ok/no-eku-and-set", newContext(t, true, "foo=bar"), nil, json.RawMessage(`{"extKeyUsage":[],"foo":"bar"}`), false},
+		{"ok/ski", newContext(t, false), []byte{1, 2, 3}, json.RawMessage(`{"subjectKeyId":"AQID"}`), false},
+		{"fail/no-eku-and-set-eku", newContext(t, true, `extKeyUsage=["serverAuth"]`), nil, nil, true},
+		{"fail/ski-and-set-ski", newContext(t, false, "subjectKeyId=AQID"), []byte{1, 2, 3}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTemplateData(tt.ctx, tt.ski)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_parseChainOrder(t *testing.T) {
+	newContext := func(t *testing.T, order string) *cli.Context {
+		t.Helper()
+		fs := flag.NewFlagSet("contrive", 0)
+		_ = fs.String("chain-order", order, "")
+		return cli.NewContext(&cli.App{}, fs, nil)
+	}
+
+	tests := []struct {
+		name    string
+		order   string
+		want    bool
+		wantErr bool
+	}{
+		{"ok/empty", "", false, false},
+		{"ok/leaf-first", "leaf-first", false, false},
+		{"ok/leaf-last", "leaf-last", true, false},
+		{"fail/unknown", "root-first", false, true},
+		{"fail/case", "Leaf-Last", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseChainOrder(newContext(t, tt.order))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseLineEnding(t *testing.T) {
+	newContext := func(t *testing.T, v string) *cli.Context {
+		t.Helper()
+		fs := flag.NewFlagSet("contrive", 0)
+		_ = fs.String("line-ending", v, "")
+		return cli.NewContext(&cli.App{}, fs, nil)
+	}
+
+	tests := []struct {
+		name    string
+		value   string
+		want    bool
+		wantErr bool
+	}{
+		{"ok/empty", "", false, false},
+		{"ok/lf", "lf", false, false},
+		{"ok/crlf", "crlf", true, false},
+		{"fail/unknown", "cr", false, true},
+		{"fail/case", "CRLF", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLineEnding(newContext(t, tt.value))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFormatLineEnding(t *testing.T) {
+	data := []byte("-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n")
+	assert.Equal(t, data, FormatLineEnding(data, false))
+	crlf := []byte("-----BEGIN CERTIFICATE-----\r\nMIIB\r\n-----END CERTIFICATE-----\r\n")
+	assert.Equal(t, crlf, FormatLineEnding(data, true))
+	assert.Equal(t, crlf, FormatLineEnding(crlf, true))
+}
+
+func TestNewCertificateFlow(t *testing.T) {
+	newContext := func(t *testing.T, values map[string]string) *cli.Context {
+		t.Helper()
+		fs := flag.NewFlagSet("contrive", 0)
+		_ = fs.String("token", "", "")
+		_ = fs.Bool("deadline-from-token", false, "")
+		_ = fs.String("chain-order", "leaf-first", "")
+		_ = fs.String("line-ending", "lf", "")
+		_ = fs.String("ski", "", "")
+		fs.Var(&cli.StringSlice{}, "allowed-signature-algs", "")
+		for k, v := range values {
+			require.NoError(t, fs.Set(k, v))
+		}
+		return cli.NewContext(&cli.App{}, fs, nil)
+	}
+
+	tests := []struct {
+		name    string
+		values  map[string]string
+		wantErr bool
+	}{
+		{"ok", map[string]string{}, false},
+		{"ok/deadline-from-token", map[string]string{"deadline-from-token": "true", "token": "the-token"}, false},
+		{"fail/deadline-from-token", map[string]string{"deadline-from-token": "true"}, true},
+		{"ok/ski", map[string]string{"ski": "sha256-pubkey"}, false},
+		{"fail/chain-order", map[string]string{"chain-order": "leaf-middle"}, true},
+		{"fail/line-ending", map[string]string{"line-ending": "cr"}, true},
+		{"fail/ski", map[string]string{"ski": "not-hex"}, true},
+		{"ok/allowed-signature-algs", map[string]string{"allowed-signature-algs": "ECDSA-SHA256, sha256-rsa"}, false},
+		{"fail/allowed-signature-algs", map[string]string{"allowed-signature-algs": "ECDSA-SHA256,SHA256-ECDSA"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewCertificateFlow(newContext(t, tt.values))
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestCertificateFlow_GetClient_bootstrap(t *testing.T) {
+	srv := httptest.NewTLSServer(nil)
+	defer srv.Close()
+	root := srv.Certificate()
+	sum := sha256.Sum256(root.Raw)
+	sha := hex.EncodeToString(sum[:])
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/root/"+sha {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(api.RootResponse{RootPEM: api.NewCertificate(root)})
+	})
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	newToken := func(t *testing.T, sha string) string {
+		t.Helper()
+		claims, err := token.NewClaims(token.WithAudience(srv.URL+"/1.0/sign"), token.WithSHA(sha))
+		require.NoError(t, err)
+		tok, err := claims.Sign(jose.ES256, key)
+		require.NoError(t, err)
+		return tok
+	}
+
+	// Without --root and --ca-url the client is configured with the token.
+	fs := flag.NewFlagSet("contrive", 0)
+	_ = fs.String("root", "", "")
+	_ = fs.String("ca-url", "", "")
+	ctx := cli.NewContext(&cli.App{}, fs, nil)
+
+	want := x509.NewCertPool()
+	want.AddCert(root)
+
+	tests := []struct {
+		name string
+		flow *CertificateFlow
+	}{
+		{"default", &CertificateFlow{}},
+		{"cipher-suites", &CertificateFlow{cipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}}},
+		{"max-response-size", &CertificateFlow{maxResponseSize: 1024}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := tt.flow.GetClient(ctx, newToken(t, sha))
+			require.NoError(t, err)
+			assert.True(t, client.GetRootCAs().Equal(want))
+
+			_, err = tt.flow.GetClient(ctx, newToken(t, hex.EncodeToString(make([]byte, 32))))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func Test_readPrivateKey(t *testing.T) {
+	dir := t.TempDir()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	keyFile := filepath.Join(dir, "key.pem")
+	_, err = pemutil.Serialize(key, pemutil.ToFile(keyFile, 0600))
+	require.NoError(t, err)
+	pubFile := filepath.Join(dir, "pub.pem")
+	_, err = pemutil.Serialize(key.Public(), pemutil.ToFile(pubFile, 0600))
+	require.NoError(t, err)
+
+	got, err := readPrivateKey(keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, key, got)
+
+	_, err = readPrivateKey(pubFile)
+	assert.ErrorContains(t, err, "is not a private key")
+
+	_, err = readPrivateKey(filepath.Join(dir, "missing.pem"))
+	assert.Error(t, err)
+}
+
+func TestCertificateFlow_TLSCertificate(t *testing.T) {
+	ca := testca.New(t)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: "foo.internal"},
+		DNSNames: []string{"foo.internal"},
+	}, key)
+	require.NoError(t, err)
+	csr, err := x509.ParseCertificateRequest(der)
+	require.NoError(t, err)
+
+	newContext := func(t *testing.T, algs ...string) *cli.Context {
+		t.Helper()
+		fs := flag.NewFlagSet("contrive", 0)
+		_ = fs.String("ca-url", ca.URL, "")
+		_ = fs.String("root", ca.RootFile, "")
+		allowed := cli.StringSlice(algs)
+		fs.Var(&allowed, "allowed-signature-algs", "")
+		return cli.NewContext(&cli.App{}, fs, nil)
+	}
+
+	tests := []struct {
+		name    string
+		ctx     *cli.Context
+		wantErr bool
+	}{
+		{"ok", newContext(t), false},
+		{"ok/allowed-signature-algs", newContext(t, "ECDSA-SHA256"), false},
+		{"fail/allowed-signature-algs", newContext(t, "SHA256-RSA"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := (&CertificateFlow{}).TLSCertificate(tt.ctx, ca.Token(t, "foo.internal"), api.CertificateRequest{CertificateRequest: csr}, key)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, got)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, got.Certificate, 2)
+			assert.Equal(t, got.Leaf.Raw, got.Certificate[0])
+			assert.Equal(t, ca.Intermediate.Raw, got.Certificate[1])
+			assert.Equal(t, "foo.internal", got.Leaf.Subject.CommonName)
+			assert.Equal(t, key, got.PrivateKey)
+		})
+	}
+}
+
+func TestCertificateFlow_CreateSignRequest(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	newToken := func(t *testing.T, subject string, sans []string) string {
+		t.Helper()
+		claims, err := token.NewClaims(token.WithSubject(subject), token.WithSANS(sans))
+		require.NoError(t, err)
+		tok, err := claims.Sign(jose.ES256, key)
+		require.NoError(t, err)
+		return tok
+	}
+
+	tests := []struct {
+		name         string
+		subject      string
+		tokenSANs    []string
+		sans         []string
+		wantDNSNames []string
+		wantIPs      []net.IP
+	}{
+		{"ok/ip-subject", "10.0.0.5", []string{"10.0.0.5"}, nil, nil, []net.IP{net.ParseIP("10.0.0.5")}},
+		{"ok/dns-subject", "foo.internal", []string{"foo.internal"}, nil, []string{"foo.internal"}, nil},
+		{"ok/mixed", "foo.internal", []string{"foo.internal", "10.0.0.5"}, nil, []string{"foo.internal"}, []net.IP{net.ParseIP("10.0.0.5")}},
+		{"ok/flag-sans", "foo.internal", []string{"foo.internal"}, []string{"10.0.0.6"}, []string{"foo.internal"}, []net.IP{net.ParseIP("10.0.0.6")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := cli.NewContext(&cli.App{}, flag.NewFlagSet("contrive", 0), nil)
+			flow := &CertificateFlow{}
+			req, pk, err := flow.CreateSignRequest(ctx, newToken(t, tt.subject, tt.tokenSANs), tt.subject, tt.sans)
+			require.NoError(t, err)
+			assert.NotNil(t, pk)
+
+			csr := req.CsrPEM.CertificateRequest
+			assert.Equal(t, tt.subject, csr.Subject.CommonName)
+			assert.Equal(t, tt.wantDNSNames, csr.DNSNames)
+			require.Len(t, csr.IPAddresses, len(tt.wantIPs))
+			for i, ip := range tt.wantIPs {
+				assert.True(t, ip.Equal(csr.IPAddresses[i]), "got %s, want %s", csr.IPAddresses[i], ip)
+			}
+		})
+	}
+}
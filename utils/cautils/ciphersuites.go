@@ -0,0 +1,76 @@
+package cautils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+)
+
+// ParseCipherSuites returns the identifiers of the given cipher suite names.
+// Each value can be a comma separated list of names. Unknown names, and the
+// suites that Go considers insecure, are rejected.
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	var ids []uint16
+	for _, value := range names {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.ToUpper(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			id, err := cipherSuiteID(name)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func cipherSuiteID(name string) (uint16, error) {
+	for _, cs := range tls.CipherSuites() {
+		if cs.Name == name {
+			return cs.ID, nil
+		}
+	}
+	for _, cs := range tls.InsecureCipherSuites() {
+		if cs.Name == name {
+			return 0, errors.Errorf("cipher suite %s is not secure", name)
+		}
+	}
+	return 0, errors.Errorf("unknown cipher suite %s", name)
+}
+
+// ParseCipherSuitesFlag returns the identifiers of the cipher suites in the
+// --tls-cipher-suites flag, or nil if the flag is not set.
+func ParseCipherSuitesFlag(ctx *cli.Context) ([]uint16, error) {
+	v := ctx.String("tls-cipher-suites")
+	if v == "" {
+		return nil, nil
+	}
+	ids, err := ParseCipherSuites([]string{v})
+	if err != nil {
+		return nil, errs.InvalidFlagValueMsg(ctx, "tls-cipher-suites", v, err.Error())
+	}
+	return ids, nil
+}
+
+// NewTransport returns an http.Transport with the parameters of the
+// http.DefaultTransport that trusts the given roots and only allows the given
+// TLS 1.2 cipher suites. If cipherSuites is empty, the default suites of Go
+// are used.
+func NewTransport(rootCAs *x509.CertPool, cipherSuites []uint16) *http.Transport {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	tr.TLSClientConfig = &tls.Config{
+		RootCAs:      rootCAs,
+		CipherSuites: cipherSuites,
+		MinVersion:   tls.VersionTLS12,
+	}
+	return tr
+}
@@ -0,0 +1,47 @@
+package cautils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCipherSuites(t *testing.T) {
+	tests := []struct {
+		name    string
+		names   []string
+		want    []uint16
+		wantErr bool
+	}{
+		{"ok/empty", nil, nil, false},
+		{"ok/one", []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"}, []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}, false},
+		{"ok/comma-separated", []string{"tls_ecdhe_rsa_with_aes_256_gcm_sha384, TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256"}, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384, tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256}, false},
+		{"fail/insecure", []string{"TLS_RSA_WITH_RC4_128_SHA"}, nil, true},
+		{"fail/unknown", []string{"TLS_FOO"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCipherSuites(tt.names)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNewTransport(t *testing.T) {
+	pool := x509.NewCertPool()
+	tr := NewTransport(pool, []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256})
+	assert.Equal(t, pool, tr.TLSClientConfig.RootCAs)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}, tr.TLSClientConfig.CipherSuites)
+	assert.Equal(t, uint16(tls.VersionTLS12), tr.TLSClientConfig.MinVersion)
+	assert.NotZero(t, tr.TLSHandshakeTimeout)
+	assert.NotZero(t, tr.IdleConnTimeout)
+	assert.NotNil(t, tr.Proxy)
+}
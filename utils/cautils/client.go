@@ -22,6 +22,7 @@ import (
 	"go.step.sm/crypto/pemutil"
 
 	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/prompt"
 )
 
 // CaClient is the interface implemented by a client used to sign, renew, revoke
@@ -142,7 +143,7 @@ func NewAdminClient(ctx *cli.Context, opts ...ca.ClientOption) (*ca.AdminClient,
 		}
 		subject := ctx.String("admin-subject")
 		if subject == "" {
-			subject, err = ui.Prompt("Please enter admin name/subject (e.g., name@example.com)", ui.WithValidateNotEmpty())
+			subject, err = prompt.Prompt("Please enter admin name/subject (e.g., name@example.com)", ui.WithValidateNotEmpty())
 			if err != nil {
 				return nil, err
 			}
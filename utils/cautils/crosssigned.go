@@ -0,0 +1,59 @@
+package cautils
+
+import (
+	"bytes"
+	"crypto/x509"
+
+	"github.com/pkg/errors"
+)
+
+// selectCrossSignedChain returns the chain that verifies the leaf certificate
+// with the given roots, using the intermediates in the chain returned by the
+// CA and the cross-signed intermediate in caCert. The returned chain starts
+// with the leaf, and it does not include the root.
+func selectCrossSignedChain(roots *x509.CertPool, chain []*x509.Certificate, caCert *x509.Certificate) ([]*x509.Certificate, error) {
+	if roots == nil {
+		return nil, errors.New("error verifying certificate chain: root certificates are not available")
+	}
+	if len(chain) == 0 {
+		return nil, errors.New("error verifying certificate chain: the certificate chain is empty")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, crt := range chain[1:] {
+		intermediates.AddCert(crt)
+	}
+	if caCert != nil {
+		intermediates.AddCert(caCert)
+	}
+
+	chains, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error verifying certificate chain with the trusted roots")
+	}
+
+	// Prefer the chain returned by the CA if it already verifies.
+	for _, c := range chains {
+		if sameChain(c[:len(c)-1], chain) {
+			return chain, nil
+		}
+	}
+	best := chains[0]
+	return best[:len(best)-1], nil
+}
+
+func sameChain(a, b []*x509.Certificate) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i].Raw, b[i].Raw) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,82 @@
+package cautils
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/crypto/minica"
+)
+
+func Test_selectCrossSignedChain(t *testing.T) {
+	oldCA, err := minica.New(minica.WithName("Old"))
+	require.NoError(t, err)
+	newCA, err := minica.New(minica.WithName("New"))
+	require.NoError(t, err)
+	otherCA, err := minica.New(minica.WithName("Other"))
+	require.NoError(t, err)
+
+	// Intermediate of the new CA cross-signed by the old root.
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               newCA.Intermediate.Subject,
+		SubjectKeyId:          newCA.Intermediate.SubjectKeyId,
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLen:            0,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, oldCA.Root, newCA.Intermediate.PublicKey, oldCA.RootSigner)
+	require.NoError(t, err)
+	crossSigned, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	leaf, err := newCA.SignCSR(mustCertificateRequest(t, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: "foo"},
+		DNSNames: []string{"foo.internal"},
+	}))
+	require.NoError(t, err)
+	chain := []*x509.Certificate{leaf, newCA.Intermediate}
+
+	pool := func(crts ...*x509.Certificate) *x509.CertPool {
+		p := x509.NewCertPool()
+		for _, crt := range crts {
+			p.AddCert(crt)
+		}
+		return p
+	}
+
+	tests := []struct {
+		name    string
+		roots   *x509.CertPool
+		caCert  *x509.Certificate
+		want    []*x509.Certificate
+		wantErr bool
+	}{
+		{"ok/new-root", pool(newCA.Root), crossSigned, chain, false},
+		{"ok/old-root", pool(oldCA.Root), crossSigned, []*x509.Certificate{leaf, crossSigned}, false},
+		{"ok/both-roots", pool(oldCA.Root, newCA.Root), crossSigned, chain, false},
+		{"fail/no-cross-signed", pool(oldCA.Root), newCA.Intermediate, nil, true},
+		{"fail/other-root", pool(otherCA.Root), crossSigned, nil, true},
+		{"fail/no-roots", nil, crossSigned, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectCrossSignedChain(tt.roots, chain, tt.caCert)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package cautils
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/smallstep/certificates/api"
+
+	"github.com/smallstep/cli/token"
+)
+
+// minDeadlineValidity is the validity under which the --deadline-from-token
+// flag prints a warning.
+const minDeadlineValidity = 5 * time.Minute
+
+// tokenDeadline returns the not after required by the --deadline-from-token
+// flag: the expiration of the token if the requested not after is not set or
+// is after it, or the requested not after otherwise. It also returns true if
+// a requested not after has been shortened.
+func tokenDeadline(tok string, notBefore, notAfter api.TimeDuration) (api.TimeDuration, bool, error) {
+	jwt, err := token.ParseInsecure(tok)
+	if err != nil {
+		return notAfter, false, err
+	}
+	if jwt.Payload.Expiry == nil {
+		return notAfter, false, errors.New("error using --deadline-from-token: the token does not have an expiration")
+	}
+
+	deadline := jwt.Payload.Expiry.Time()
+	if !notBefore.IsZero() && !notBefore.Time().Before(deadline) {
+		return notAfter, false, errors.Errorf("error using --deadline-from-token: the requested not before %s is not before the token expiration %s",
+			notBefore.Time().UTC().Format(time.RFC3339), deadline.UTC().Format(time.RFC3339))
+	}
+	if notAfter.IsZero() {
+		return api.NewTimeDuration(deadline), false, nil
+	}
+	if !notAfter.Time().After(deadline) {
+		return notAfter, false, nil
+	}
+	return api.NewTimeDuration(deadline), true, nil
+}
@@ -0,0 +1,58 @@
+package cautils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/crypto/jose"
+
+	"github.com/smallstep/certificates/api"
+
+	"github.com/smallstep/cli/token"
+)
+
+func Test_tokenDeadline(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	now := time.Now().Truncate(time.Second)
+	expiry := now.Add(time.Hour)
+	claims, err := token.NewClaims(token.WithSubject("foo"), token.WithValidity(now, expiry))
+	require.NoError(t, err)
+	tok, err := claims.Sign(jose.ES256, key)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		tok        string
+		notBefore  api.TimeDuration
+		notAfter   api.TimeDuration
+		want       time.Time
+		wantCapped bool
+		wantErr    bool
+	}{
+		{"ok/default", tok, api.TimeDuration{}, api.TimeDuration{}, expiry, false, false},
+		{"ok/capped", tok, api.TimeDuration{}, api.NewTimeDuration(now.Add(24 * time.Hour)), expiry, true, false},
+		{"ok/not-capped", tok, api.TimeDuration{}, api.NewTimeDuration(now.Add(time.Minute)), now.Add(time.Minute), false, false},
+		{"ok/not-before", tok, api.NewTimeDuration(now.Add(time.Minute)), api.TimeDuration{}, expiry, false, false},
+		{"fail/not-before", tok, api.NewTimeDuration(expiry), api.TimeDuration{}, time.Time{}, false, true},
+		{"fail/token", "not-a-token", api.TimeDuration{}, api.TimeDuration{}, time.Time{}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, capped, err := tokenDeadline(tt.tok, tt.notBefore, tt.notAfter)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantCapped, capped)
+			assert.True(t, tt.want.Equal(got.Time()), "want %s, got %s", tt.want, got.Time())
+		})
+	}
+}
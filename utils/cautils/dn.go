@@ -0,0 +1,175 @@
+package cautils
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// dnAttributeTypes are the attribute type names supported in a distinguished
+// name. Other attributes can be set using the dotted object identifier.
+var dnAttributeTypes = map[string]asn1.ObjectIdentifier{
+	"CN":           {2, 5, 4, 3},
+	"SN":           {2, 5, 4, 4},
+	"SERIALNUMBER": {2, 5, 4, 5},
+	"C":            {2, 5, 4, 6},
+	"L":            {2, 5, 4, 7},
+	"ST":           {2, 5, 4, 8},
+	"STREET":       {2, 5, 4, 9},
+	"O":            {2, 5, 4, 10},
+	"OU":           {2, 5, 4, 11},
+	"TITLE":        {2, 5, 4, 12},
+	"POSTALCODE":   {2, 5, 4, 17},
+	"GN":           {2, 5, 4, 42},
+	"UID":          {0, 9, 2342, 19200300, 100, 1, 1},
+	"DC":           {0, 9, 2342, 19200300, 100, 1, 25},
+	"EMAILADDRESS": {1, 2, 840, 113549, 1, 9, 1},
+}
+
+var oidCommonName = asn1.ObjectIdentifier{2, 5, 4, 3}
+
+// ParseDistinguishedName parses the string representation of a distinguished
+// name, as defined in RFC 4514, e.g. "CN=foo,O=Acme\, Inc.,C=US". The
+// relative distinguished names in the string are in reverse order, the
+// returned sequence has the most significant one first.
+func ParseDistinguishedName(s string) (pkix.RDNSequence, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, errors.New("error parsing distinguished name: name cannot be empty")
+	}
+
+	var (
+		rdns []pkix.RelativeDistinguishedNameSET
+		rdn  pkix.RelativeDistinguishedNameSET
+	)
+	for i := 0; i <= len(s); {
+		attr, value, next, err := parseAttributeTypeAndValue(s, i)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error parsing distinguished name %q", s)
+		}
+		rdn = append(rdn, pkix.AttributeTypeAndValue{Type: attr, Value: value})
+		if next >= len(s) || s[next] == ',' {
+			rdns = append(rdns, rdn)
+			rdn = nil
+		}
+		i = next + 1
+	}
+
+	seq := make(pkix.RDNSequence, len(rdns))
+	for i, rdn := range rdns {
+		seq[len(rdns)-1-i] = rdn
+	}
+	return seq, nil
+}
+
+// parseAttributeTypeAndValue parses the attribute starting a position i and
+// returns its type and value, and the position of the separator after it.
+func parseAttributeTypeAndValue(s string, i int) (asn1.ObjectIdentifier, string, int, error) {
+	eq := strings.IndexByte(s[i:], '=')
+	if eq < 0 {
+		return nil, "", 0, errors.Errorf("missing '=' after %q", s[i:])
+	}
+	name := strings.TrimSpace(s[i : i+eq])
+	attr, err := parseAttributeType(name)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	// Skip unescaped leading spaces.
+	j := i + eq + 1
+	for j < len(s) && s[j] == ' ' {
+		j++
+	}
+	if j < len(s) && s[j] == '#' {
+		return nil, "", 0, errors.Errorf("hex-encoded value of %s is not supported", name)
+	}
+
+	var (
+		value []byte
+		end   int // length of value without unescaped trailing spaces
+	)
+loop:
+	for ; j < len(s); j++ {
+		switch c := s[j]; c {
+		case ',', '+':
+			break loop
+		case '"', ';', '<', '>':
+			return nil, "", 0, errors.Errorf("character %q in the value of %s must be escaped", c, name)
+		case '\\':
+			if j+1 >= len(s) {
+				return nil, "", 0, errors.Errorf("value of %s ends with an escape character", name)
+			}
+			if strings.IndexByte(`,+"\<>;= #`, s[j+1]) >= 0 {
+				value = append(value, s[j+1])
+				j++
+			} else if j+2 < len(s) {
+				b, err := hex.DecodeString(s[j+1 : j+3])
+				if err != nil {
+					return nil, "", 0, errors.Errorf("invalid escape sequence %q in the value of %s", s[j:j+3], name)
+				}
+				value = append(value, b...)
+				j += 2
+			} else {
+				return nil, "", 0, errors.Errorf("invalid escape sequence %q in the value of %s", s[j:], name)
+			}
+			end = len(value)
+		case ' ':
+			value = append(value, c)
+		default:
+			value = append(value, c)
+			end = len(value)
+		}
+	}
+
+	value = value[:end]
+	switch {
+	case len(value) == 0:
+		return nil, "", 0, errors.Errorf("value of %s cannot be empty", name)
+	case !utf8.Valid(value):
+		return nil, "", 0, errors.Errorf("value of %s is not a valid UTF-8 string", name)
+	}
+	return attr, string(value), j, nil
+}
+
+// parseAttributeType returns the object identifier of the given attribute
+// type name or dotted object identifier.
+func parseAttributeType(name string) (asn1.ObjectIdentifier, error) {
+	if name == "" {
+		return nil, errors.New("attribute type cannot be empty")
+	}
+	if oid, ok := dnAttributeTypes[strings.ToUpper(name)]; ok {
+		return oid, nil
+	}
+	if name[0] >= '0' && name[0] <= '9' {
+		var oid asn1.ObjectIdentifier
+		for _, part := range strings.Split(name, ".") {
+			n, err := strconv.Atoi(part)
+			if err != nil || n < 0 {
+				return nil, errors.Errorf("invalid object identifier %q", name)
+			}
+			oid = append(oid, n)
+		}
+		if len(oid) < 2 {
+			return nil, errors.Errorf("invalid object identifier %q", name)
+		}
+		return oid, nil
+	}
+	return nil, errors.Errorf("unsupported attribute type %q", name)
+}
+
+// distinguishedNameCommonName returns the first common name in the sequence.
+func distinguishedNameCommonName(seq pkix.RDNSequence) (string, bool) {
+	for _, rdn := range seq {
+		for _, atv := range rdn {
+			if atv.Type.Equal(oidCommonName) {
+				cn, _ := atv.Value.(string)
+				return cn, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,89 @@
+package cautils
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDistinguishedName(t *testing.T) {
+	atv := func(oid asn1.ObjectIdentifier, value string) pkix.AttributeTypeAndValue {
+		return pkix.AttributeTypeAndValue{Type: oid, Value: value}
+	}
+	var (
+		cn = asn1.ObjectIdentifier{2, 5, 4, 3}
+		c  = asn1.ObjectIdentifier{2, 5, 4, 6}
+		o  = asn1.ObjectIdentifier{2, 5, 4, 10}
+		ou = asn1.ObjectIdentifier{2, 5, 4, 11}
+		dc = asn1.ObjectIdentifier{0, 9, 2342, 19200300, 100, 1, 25}
+	)
+
+	tests := []struct {
+		name    string
+		dn      string
+		want    pkix.RDNSequence
+		wantErr bool
+	}{
+		{"ok", "CN=foo,O=bar", pkix.RDNSequence{{atv(o, "bar")}, {atv(cn, "foo")}}, false},
+		{"ok/spaces", " cn = foo , o = bar ", pkix.RDNSequence{{atv(o, "bar")}, {atv(cn, "foo")}}, false},
+		{"ok/escaped-comma", `CN=foo,O=Acme\, Inc.,C=US`, pkix.RDNSequence{{atv(c, "US")}, {atv(o, "Acme, Inc.")}, {atv(cn, "foo")}}, false},
+		{"ok/escaped-plus", `CN=a\+b`, pkix.RDNSequence{{atv(cn, "a+b")}}, false},
+		{"ok/escaped-chars", `CN=\"foo\"\\\<\>\;\#`, pkix.RDNSequence{{atv(cn, `"foo"\<>;#`)}}, false},
+		{"ok/escaped-spaces", `CN=\ foo\ `, pkix.RDNSequence{{atv(cn, " foo ")}}, false},
+		{"ok/hex", `CN=caf\C3\A9`, pkix.RDNSequence{{atv(cn, "café")}}, false},
+		{"ok/multi-valued", "CN=foo+OU=bar,DC=example", pkix.RDNSequence{{atv(dc, "example")}, {atv(cn, "foo"), atv(ou, "bar")}}, false},
+		{"ok/oid", "2.5.4.3=foo", pkix.RDNSequence{{atv(cn, "foo")}}, false},
+		{"fail/empty", "", nil, true},
+		{"fail/missing-equal", "CN=foo,bar", nil, true},
+		{"fail/empty-value", "CN=,O=bar", nil, true},
+		{"fail/trailing-comma", "CN=foo,", nil, true},
+		{"fail/unknown-type", "FOO=bar", nil, true},
+		{"fail/bad-oid", "2.5.x=bar", nil, true},
+		{"fail/unescaped", `CN=foo;bar`, nil, true},
+		{"fail/hex-value", "CN=#0403666f6f", nil, true},
+		{"fail/bad-escape", `CN=foo\zz`, nil, true},
+		{"fail/trailing-escape", `CN=foo\`, nil, true},
+		{"fail/invalid-utf8", `CN=\ff`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDistinguishedName(tt.dn)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_subjectDNBytes(t *testing.T) {
+	parse := func(t *testing.T, b []byte) pkix.Name {
+		t.Helper()
+		var seq pkix.RDNSequence
+		_, err := asn1.Unmarshal(b, &seq)
+		require.NoError(t, err)
+		var name pkix.Name
+		name.FillFromRDNSequence(&seq)
+		return name
+	}
+
+	b, err := subjectDNBytes(`CN=foo,O=Acme\, Inc.`, "foo")
+	require.NoError(t, err)
+	name := parse(t, b)
+	assert.Equal(t, "foo", name.CommonName)
+	assert.Equal(t, []string{"Acme, Inc."}, name.Organization)
+
+	b, err = subjectDNBytes("O=bar", "foo")
+	require.NoError(t, err)
+	name = parse(t, b)
+	assert.Equal(t, "foo", name.CommonName)
+	assert.Equal(t, "CN=foo,O=bar", name.String())
+
+	_, err = subjectDNBytes("CN=bar", "foo")
+	assert.Error(t, err)
+}
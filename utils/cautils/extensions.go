@@ -0,0 +1,129 @@
+package cautils
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+)
+
+// parseExtraExtensions parses the values of the --extra-extension flag. Each
+// value has the format "oid:critical:file", where oid is a dotted object
+// identifier, critical is a boolean, and file contains the DER encoded value
+// of the extension.
+func parseExtraExtensions(ctx *cli.Context) ([]pkix.Extension, error) {
+	values := ctx.StringSlice("extra-extension")
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	extensions := make([]pkix.Extension, 0, len(values))
+	for _, v := range values {
+		parts := strings.SplitN(v, ":", 3)
+		if len(parts) != 3 {
+			return nil, errs.InvalidFlagValueMsg(ctx, "extra-extension", v, "value must have the format oid:critical:file")
+		}
+
+		oid, err := parseObjectIdentifier(parts[0])
+		if err != nil {
+			return nil, errs.InvalidFlagValueMsg(ctx, "extra-extension", v, "invalid object identifier "+parts[0])
+		}
+		critical, err := strconv.ParseBool(parts[1])
+		if err != nil {
+			return nil, errs.InvalidFlagValueMsg(ctx, "extra-extension", v, "critical must be true or false")
+		}
+
+		b, err := os.ReadFile(parts[2])
+		if err != nil {
+			return nil, errs.FileError(err, parts[2])
+		}
+		var raw asn1.RawValue
+		if rest, err := asn1.Unmarshal(b, &raw); err != nil || len(rest) > 0 {
+			return nil, errs.InvalidFlagValueMsg(ctx, "extra-extension", v, "file "+parts[2]+" does not contain a DER value")
+		}
+
+		extensions = append(extensions, pkix.Extension{
+			Id:       oid,
+			Critical: critical,
+			Value:    b,
+		})
+	}
+	return extensions, nil
+}
+
+// parseObjectIdentifier parses an object identifier in dotted notation, e.g.
+// "1.3.6.1.4.1.32473.1".
+func parseObjectIdentifier(s string) (asn1.ObjectIdentifier, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 {
+		return nil, errors.New("object identifier must have at least two components")
+	}
+	oid := make(asn1.ObjectIdentifier, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nil, errors.New("invalid object identifier component " + p)
+		}
+		oid[i] = n
+	}
+	if oid[0] > 2 || (oid[0] < 2 && oid[1] > 39) {
+		return nil, errors.New("invalid object identifier " + s)
+	}
+	return oid, nil
+}
+
+// The object identifiers of the extensions that are not considered standard
+// X.509 extensions, besides the id-ce arc, by NonStandardExtensions.
+var (
+	oidExtensionsArc                 = asn1.ObjectIdentifier{2, 5, 29}
+	oidExtensionAuthorityInfoAccess  = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 1}
+	oidExtensionSubjectInfoAccess    = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 11}
+	oidExtensionSignedCertTimestamps = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 2}
+	oidExtensionStepProvisioner      = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 37476, 9000, 64, 1}
+)
+
+// NonStandardExtensions returns the extensions in the certificate that are not
+// standard X.509 extensions, or extensions added by the CA, like the step
+// provisioner extension. These are the extensions that cannot be derived from
+// the flags of a new request.
+func NonStandardExtensions(cert *x509.Certificate) []pkix.Extension {
+	var extensions []pkix.Extension
+	for _, ext := range cert.Extensions {
+		switch {
+		case len(ext.Id) == 4 && ext.Id[:3].Equal(oidExtensionsArc):
+		case ext.Id.Equal(oidExtensionAuthorityInfoAccess),
+			ext.Id.Equal(oidExtensionSubjectInfoAccess),
+			ext.Id.Equal(oidExtensionSignedCertTimestamps),
+			ext.Id.Equal(oidExtensionStepProvisioner):
+		default:
+			extensions = append(extensions, ext)
+		}
+	}
+	return extensions
+}
+
+// mergeExtensions returns the extensions in a, and the ones in b with an
+// object identifier not in a.
+func mergeExtensions(a, b []pkix.Extension) []pkix.Extension {
+	result := append([]pkix.Extension{}, a...)
+	for _, ext := range b {
+		found := false
+		for _, e := range a {
+			if e.Id.Equal(ext.Id) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, ext)
+		}
+	}
+	return result
+}
@@ -0,0 +1,91 @@
+package cautils
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+)
+
+func Test_parseExtraExtensions(t *testing.T) {
+	dir := t.TempDir()
+	der, err := asn1.Marshal("foo")
+	require.NoError(t, err)
+	derFile := filepath.Join(dir, "ext.der")
+	require.NoError(t, os.WriteFile(derFile, der, 0600))
+	badFile := filepath.Join(dir, "bad.der")
+	require.NoError(t, os.WriteFile(badFile, append(der, 0x00), 0600))
+
+	newContext := func(t *testing.T, values ...string) *cli.Context {
+		t.Helper()
+		fs := flag.NewFlagSet("contrive", 0)
+		exts := cli.StringSlice(values)
+		fs.Var(&exts, "extra-extension", "")
+		return cli.NewContext(&cli.App{}, fs, nil)
+	}
+
+	tests := []struct {
+		name    string
+		values  []string
+		want    []pkix.Extension
+		wantErr bool
+	}{
+		{"ok/empty", nil, nil, false},
+		{"ok", []string{"1.2.3.4:true:" + derFile}, []pkix.Extension{
+			{Id: asn1.ObjectIdentifier{1, 2, 3, 4}, Critical: true, Value: der},
+		}, false},
+		{"ok/multiple", []string{"1.2.3.4:false:" + derFile, "2.999.1:true:" + derFile}, []pkix.Extension{
+			{Id: asn1.ObjectIdentifier{1, 2, 3, 4}, Critical: false, Value: der},
+			{Id: asn1.ObjectIdentifier{2, 999, 1}, Critical: true, Value: der},
+		}, false},
+		{"fail/format", []string{"1.2.3.4:" + derFile}, nil, true},
+		{"fail/oid", []string{"1.2.a:true:" + derFile}, nil, true},
+		{"fail/oid-short", []string{"1:true:" + derFile}, nil, true},
+		{"fail/oid-range", []string{"1.40.1:true:" + derFile}, nil, true},
+		{"fail/critical", []string{"1.2.3.4:yes please:" + derFile}, nil, true},
+		{"fail/missing-file", []string{"1.2.3.4:true:" + filepath.Join(dir, "missing.der")}, nil, true},
+		{"fail/not-der", []string{"1.2.3.4:true:" + badFile}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseExtraExtensions(newContext(t, tt.values...))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNonStandardExtensions(t *testing.T) {
+	custom := pkix.Extension{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1}, Value: []byte{0x05, 0x00}}
+	cert := &x509.Certificate{Extensions: []pkix.Extension{
+		{Id: asn1.ObjectIdentifier{2, 5, 29, 17}},                         // SANs
+		{Id: asn1.ObjectIdentifier{2, 5, 29, 35}},                         // AKI
+		{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 1}},            // AIA
+		{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 37476, 9000, 64, 1}}, // step provisioner
+		custom,
+	}}
+	assert.Equal(t, []pkix.Extension{custom}, NonStandardExtensions(cert))
+	assert.Empty(t, NonStandardExtensions(&x509.Certificate{}))
+}
+
+func Test_mergeExtensions(t *testing.T) {
+	a := []pkix.Extension{{Id: asn1.ObjectIdentifier{1, 2, 3}, Value: []byte("a")}}
+	b := []pkix.Extension{
+		{Id: asn1.ObjectIdentifier{1, 2, 3}, Value: []byte("b")},
+		{Id: asn1.ObjectIdentifier{1, 2, 4}, Value: []byte("b")},
+	}
+	assert.Equal(t, []pkix.Extension{a[0], b[1]}, mergeExtensions(a, b))
+	assert.Equal(t, b, mergeExtensions(nil, b))
+	assert.Equal(t, a, mergeExtensions(a, nil))
+}
@@ -0,0 +1,169 @@
+package cautils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// metadataTimeout is the maximum time allowed to get the instance metadata.
+// Metadata endpoints answer almost immediately, a short timeout avoids long
+// waits when the command does not run in the cloud.
+const metadataTimeout = 2 * time.Second
+
+// Cloud metadata endpoints, they are variables so they can be changed in
+// tests.
+var (
+	awsMetadataURL   = "http://169.254.169.254/latest"
+	gcpMetadataURL   = "http://metadata.google.internal/computeMetadata/v1"
+	azureMetadataURL = "http://169.254.169.254/metadata/instance?api-version=2021-02-01"
+)
+
+// metadataClient is the client used to query the metadata endpoints. The
+// metadata endpoints are link-local, and they must never be accessed through
+// a proxy.
+var metadataClient = &http.Client{
+	Transport: &http.Transport{Proxy: nil},
+}
+
+// GetMetadataSANs queries the metadata endpoint of the given cloud ("aws",
+// "gcp", or "azure") and returns the hostname and private IP addresses of the
+// instance.
+func GetMetadataSANs(cloud string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), metadataTimeout)
+	defer cancel()
+
+	var (
+		sans []string
+		err  error
+	)
+	switch strings.ToLower(cloud) {
+	case "aws":
+		sans, err = getAWSMetadataSANs(ctx)
+	case "gcp":
+		sans, err = getGCPMetadataSANs(ctx)
+	case "azure":
+		sans, err = getAzureMetadataSANs(ctx)
+	default:
+		return nil, errors.Errorf("unsupported cloud %q: options are aws, gcp, or azure", cloud)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "error getting %s instance metadata, is step running in %s?", cloud, cloud)
+	}
+
+	var result []string
+	for _, s := range sans {
+		if s = strings.TrimSpace(s); s != "" {
+			result = append(result, s)
+		}
+	}
+	if len(result) == 0 {
+		return nil, errors.Errorf("error getting %s instance metadata: hostname and IP addresses not found", cloud)
+	}
+	return result, nil
+}
+
+func getAWSMetadataSANs(ctx context.Context) ([]string, error) {
+	// Use IMDSv2, it requires a session token.
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, awsMetadataURL+"/api/token", http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+	tok, err := doMetadataRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var sans []string
+	for _, path := range []string{"/meta-data/local-hostname", "/meta-data/local-ipv4"} {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, awsMetadataURL+path, http.NoBody)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("X-aws-ec2-metadata-token", string(tok))
+		b, err := doMetadataRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		sans = append(sans, string(b))
+	}
+	return sans, nil
+}
+
+func getGCPMetadataSANs(ctx context.Context) ([]string, error) {
+	var sans []string
+	for _, path := range []string{"/instance/hostname", "/instance/network-interfaces/0/ip"} {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, gcpMetadataURL+path, http.NoBody)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Metadata-Flavor", "Google")
+		b, err := doMetadataRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		sans = append(sans, string(b))
+	}
+	return sans, nil
+}
+
+func getAzureMetadataSANs(ctx context.Context) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, azureMetadataURL, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Metadata", "true")
+	b, err := doMetadataRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc struct {
+		Compute struct {
+			Name string `json:"name"`
+		} `json:"compute"`
+		Network struct {
+			Interface []struct {
+				IPv4 struct {
+					IPAddress []struct {
+						PrivateIPAddress string `json:"privateIpAddress"`
+					} `json:"ipAddress"`
+				} `json:"ipv4"`
+			} `json:"interface"`
+		} `json:"network"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, errors.Wrap(err, "error parsing metadata")
+	}
+
+	sans := []string{doc.Compute.Name}
+	for _, iface := range doc.Network.Interface {
+		for _, ip := range iface.IPv4.IPAddress {
+			sans = append(sans, ip.PrivateIPAddress)
+		}
+	}
+	return sans, nil
+}
+
+func doMetadataRequest(req *http.Request) ([]byte, error) {
+	resp, err := metadataClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("%s %s failed with status code %d", req.Method, req.URL.Path, resp.StatusCode)
+	}
+	return b, nil
+}
@@ -0,0 +1,92 @@
+package cautils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetMetadataSANs(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT /aws/api/token", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("the-token"))
+	})
+	mux.HandleFunc("GET /aws/meta-data/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-aws-ec2-metadata-token") != "the-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch r.PathValue("name") {
+		case "local-hostname":
+			w.Write([]byte("ip-10-0-0-1.us-west-1.compute.internal"))
+		case "local-ipv4":
+			w.Write([]byte("10.0.0.1"))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	mux.HandleFunc("GET /gcp/instance/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/gcp/instance/hostname":
+			w.Write([]byte("foo.c.project.internal"))
+		case "/gcp/instance/network-interfaces/0/ip":
+			w.Write([]byte("10.0.0.2"))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	mux.HandleFunc("GET /azure", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"compute":{"name":"foo"},"network":{"interface":[{"ipv4":{"ipAddress":[{"privateIpAddress":"10.0.0.3"}]}}]}}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	for _, u := range []*string{&awsMetadataURL, &gcpMetadataURL, &azureMetadataURL} {
+		t.Cleanup(func(u *string, v string) func() {
+			return func() { *u = v }
+		}(u, *u))
+	}
+	awsMetadataURL = srv.URL + "/aws"
+	gcpMetadataURL = srv.URL + "/gcp"
+	azureMetadataURL = srv.URL + "/azure"
+
+	tests := []struct {
+		name    string
+		cloud   string
+		want    []string
+		wantErr bool
+	}{
+		{"ok/aws", "aws", []string{"ip-10-0-0-1.us-west-1.compute.internal", "10.0.0.1"}, false},
+		{"ok/gcp", "GCP", []string{"foo.c.project.internal", "10.0.0.2"}, false},
+		{"ok/azure", "azure", []string{"foo", "10.0.0.3"}, false},
+		{"fail/unknown", "foo", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GetMetadataSANs(tt.cloud)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("fail/off-cloud", func(t *testing.T) {
+		awsMetadataURL = srv.URL + "/not-found"
+		_, err := GetMetadataSANs("aws")
+		assert.ErrorContains(t, err, "is step running in aws?")
+	})
+}
@@ -2,6 +2,7 @@ package cautils
 
 import (
 	"context"
+	"crypto"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
@@ -178,6 +179,16 @@ func certChainToPEM(certChain []*x509.Certificate) []api.Certificate {
 	return certChainPEM
 }
 
+// X509Signer returns the intermediate certificate and the signer used by the
+// offline CA to sign X.509 certificates.
+func (c *OfflineCA) X509Signer() (*x509.Certificate, crypto.Signer, error) {
+	signer, err := c.authority.GetX509Signer()
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.authority.GetIntermediateCertificate(), signer, nil
+}
+
 // Version is a wrapper on top of the Version method. It returns
 // an api.VersionResponse.
 func (c *OfflineCA) Version() (*api.VersionResponse, error) {
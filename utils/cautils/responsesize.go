@@ -0,0 +1,60 @@
+package cautils
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// limitResponseTransport is an http.RoundTripper that fails reading a
+// response body larger than limit bytes.
+type limitResponseTransport struct {
+	base  http.RoundTripper
+	limit int64
+}
+
+func (t *limitResponseTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.ContentLength > t.limit {
+		resp.Body.Close()
+		return nil, errResponseTooLarge(t.limit)
+	}
+	resp.Body = &limitedBody{
+		ReadCloser: resp.Body,
+		remaining:  t.limit,
+		limit:      t.limit,
+	}
+	return resp, nil
+}
+
+// limitedBody is an io.ReadCloser that returns an error after reading more
+// than limit bytes.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	limit     int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, errResponseTooLarge(b.limit)
+	}
+	// Read one more byte than allowed to detect larger bodies.
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n - int(-b.remaining), errResponseTooLarge(b.limit)
+	}
+	return n, err
+}
+
+func errResponseTooLarge(limit int64) error {
+	return errors.Errorf("the CA response exceeds the maximum size of %d bytes", limit)
+}
@@ -0,0 +1,57 @@
+package cautils
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_limitResponseTransport(t *testing.T) {
+	body := strings.Repeat("a", 100)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chunked" {
+			// Flushing before writing the body removes the Content-Length.
+			w.(http.Flusher).Flush()
+		}
+		io.WriteString(w, body)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		limit   int64
+		wantErr bool
+	}{
+		{"ok", "/", 100, false},
+		{"ok/chunked", "/chunked", 100, false},
+		{"fail/content-length", "/", 99, true},
+		{"fail/chunked", "/chunked", 99, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &http.Client{
+				Transport: &limitResponseTransport{base: http.DefaultTransport, limit: tt.limit},
+			}
+			resp, err := client.Get(srv.URL + tt.path)
+			if err == nil {
+				defer resp.Body.Close()
+				var b []byte
+				b, err = io.ReadAll(resp.Body)
+				if !tt.wantErr {
+					assert.Equal(t, body, string(b))
+				}
+			}
+			if tt.wantErr {
+				assert.ErrorContains(t, err, "exceeds the maximum size of 99 bytes")
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
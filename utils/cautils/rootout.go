@@ -0,0 +1,30 @@
+package cautils
+
+import (
+	"crypto/x509"
+
+	"github.com/pkg/errors"
+)
+
+// EffectiveRoot returns the root certificate in roots that validates the
+// given certificate chain, with the leaf first. With multiple valid paths, the
+// root of the first one is returned.
+func EffectiveRoot(roots *x509.CertPool, chain []*x509.Certificate) (*x509.Certificate, error) {
+	if roots == nil {
+		return nil, errors.New("error finding the root certificate: root certificate not available")
+	}
+	intermediates := x509.NewCertPool()
+	for _, crt := range chain[1:] {
+		intermediates.AddCert(crt)
+	}
+	chains, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "error finding the root certificate")
+	}
+	verified := chains[0]
+	return verified[len(verified)-1], nil
+}
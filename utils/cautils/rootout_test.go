@@ -0,0 +1,41 @@
+package cautils
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/crypto/minica"
+)
+
+func TestEffectiveRoot(t *testing.T) {
+	ca, err := minica.New()
+	require.NoError(t, err)
+	other, err := minica.New()
+	require.NoError(t, err)
+
+	csr := mustCertificateRequest(t, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: "foo"},
+		DNSNames: []string{"foo.internal"},
+	})
+	leaf, err := ca.SignCSR(csr)
+	require.NoError(t, err)
+	chain := []*x509.Certificate{leaf, ca.Intermediate}
+
+	both := x509.NewCertPool()
+	both.AddCert(other.Root)
+	both.AddCert(ca.Root)
+	onlyOther := x509.NewCertPool()
+	onlyOther.AddCert(other.Root)
+
+	root, err := EffectiveRoot(both, chain)
+	require.NoError(t, err)
+	assert.Equal(t, ca.Root.Raw, root.Raw)
+
+	_, err = EffectiveRoot(onlyOther, chain)
+	assert.Error(t, err)
+	_, err = EffectiveRoot(nil, chain)
+	assert.Error(t, err)
+}
@@ -0,0 +1,85 @@
+package cautils
+
+import (
+	"net"
+	"strings"
+
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+)
+
+// typedSANFlags are the flags used to add Subject Alternative Names of a
+// given type, in the order they are added to the list of SANs.
+var typedSANFlags = []struct {
+	name, kind string
+}{
+	{"dns", "a DNS name"},
+	{"ip", "an IP address"},
+	{"email", "an email address"},
+	{"uri", "a URI"},
+}
+
+// TypedSANs returns the SANs given with the --dns, --ip, --email, and --uri
+// flags. The SANs are sent to the CA as strings and classified again, so
+// values that would be classified with a different type are rejected.
+func TypedSANs(ctx *cli.Context) ([]string, error) {
+	var sans []string
+	for _, f := range typedSANFlags {
+		for _, v := range ctx.StringSlice(f.name) {
+			if sanType(v) != f.name {
+				return nil, errs.InvalidFlagValueMsg(ctx, f.name, v, "value is not "+f.kind)
+			}
+			sans = append(sans, v)
+		}
+	}
+	return sans, nil
+}
+
+// sanType returns the name of the typed SAN flag that corresponds with the
+// type of the given SAN.
+func sanType(san string) string {
+	if san == "" {
+		return ""
+	}
+	dnsNames, ips, emails, uris := splitSANs([]string{san})
+	switch {
+	case len(dnsNames) == 1:
+		return "dns"
+	case len(ips) == 1:
+		return "ip"
+	case len(emails) == 1:
+		return "email"
+	case len(uris) == 1:
+		return "uri"
+	default:
+		return ""
+	}
+}
+
+// NormalizeSANs lowercases the DNS names in the given SANs and removes the
+// duplicates. DNS names are compared case-insensitively, IP addresses by
+// value, and the rest of SANs exactly. It returns the normalized SANs, in the
+// original order, and the duplicates that were dropped.
+func NormalizeSANs(sans []string) (normalized, dropped []string) {
+	seen := make(map[string]bool, len(sans))
+	for _, san := range sans {
+		key := san
+		switch sanType(san) {
+		case "":
+			continue
+		case "dns":
+			san = strings.ToLower(san)
+			key = san
+		case "ip":
+			key = net.ParseIP(san).String()
+		}
+		if seen[key] {
+			dropped = append(dropped, san)
+			continue
+		}
+		seen[key] = true
+		normalized = append(normalized, san)
+	}
+	return normalized, dropped
+}
@@ -0,0 +1,76 @@
+package cautils
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli"
+)
+
+func TestTypedSANs(t *testing.T) {
+	newContext := func(t *testing.T, values map[string][]string) *cli.Context {
+		t.Helper()
+		fs := flag.NewFlagSet("contrive", 0)
+		for _, name := range []string{"dns", "ip", "email", "uri"} {
+			v := cli.StringSlice(values[name])
+			fs.Var(&v, name, "")
+		}
+		return cli.NewContext(&cli.App{}, fs, nil)
+	}
+
+	tests := []struct {
+		name    string
+		values  map[string][]string
+		want    []string
+		wantErr bool
+	}{
+		{"ok/empty", nil, nil, false},
+		{"ok", map[string][]string{
+			"uri":   {"spiffe://example.com/foo"},
+			"email": {"jane@example.com"},
+			"ip":    {"10.0.0.1", "::1"},
+			"dns":   {"foo.internal"},
+		}, []string{"foo.internal", "10.0.0.1", "::1", "jane@example.com", "spiffe://example.com/foo"}, false},
+		{"fail/dns", map[string][]string{"dns": {"10.0.0.1"}}, nil, true},
+		{"fail/ip", map[string][]string{"ip": {"foo.internal"}}, nil, true},
+		{"fail/email", map[string][]string{"email": {"https://example.com"}}, nil, true},
+		{"fail/uri", map[string][]string{"uri": {"jane@example.com"}}, nil, true},
+		{"fail/empty", map[string][]string{"dns": {""}}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TypedSANs(newContext(t, tt.values))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestNormalizeSANs(t *testing.T) {
+	tests := []struct {
+		name        string
+		sans        []string
+		want        []string
+		wantDropped []string
+	}{
+		{"ok/empty", nil, nil, nil},
+		{"ok/lowercase", []string{"Foo.Internal"}, []string{"foo.internal"}, nil},
+		{"ok/dns", []string{"foo.internal", "FOO.internal", "bar.internal", "foo.internal"}, []string{"foo.internal", "bar.internal"}, []string{"foo.internal", "foo.internal"}},
+		{"ok/ip", []string{"10.0.0.1", "2001:db8::1", "2001:DB8:0::1", "10.0.0.1"}, []string{"10.0.0.1", "2001:db8::1"}, []string{"2001:DB8:0::1", "10.0.0.1"}},
+		{"ok/exact", []string{"foo@example.com", "Foo@example.com", "spiffe://example.com/foo", "spiffe://example.com/Foo", "spiffe://example.com/foo"},
+			[]string{"foo@example.com", "Foo@example.com", "spiffe://example.com/foo", "spiffe://example.com/Foo"}, []string{"spiffe://example.com/foo"}},
+		{"ok/skip-empty", []string{"", "foo.internal", ""}, []string{"foo.internal"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, dropped := NormalizeSANs(tt.sans)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantDropped, dropped)
+		})
+	}
+}
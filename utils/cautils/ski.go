@@ -0,0 +1,87 @@
+package cautils
+
+import (
+	"crypto"
+	"crypto/sha1" //nolint:gosec // used to compute the RFC 5280 key identifier
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/urfave/cli"
+
+	"github.com/smallstep/cli-utils/errs"
+)
+
+// parseSubjectKeyID returns the subject key identifier required by the --ski
+// flag for the public key, or nil if the flag is not set. The method is
+// sha1-pubkey, the SHA-1 hash of the subject public key (RFC 5280, section
+// 4.2.1.2), sha256-pubkey, the leftmost 160 bits of the SHA-256 hash of the
+// subject public key (RFC 7093, section 2), or a literal hexadecimal value.
+func parseSubjectKeyID(ctx *cli.Context, pub crypto.PublicKey) ([]byte, error) {
+	method := ctx.String("ski")
+	if method == "" {
+		return nil, nil
+	}
+
+	switch strings.ToLower(method) {
+	case "sha1-pubkey":
+		b, err := subjectPublicKeyBytes(pub)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha1.Sum(b) //nolint:gosec // used to compute the RFC 5280 key identifier
+		return sum[:], nil
+	case "sha256-pubkey":
+		b, err := subjectPublicKeyBytes(pub)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(b)
+		return sum[:20], nil
+	}
+
+	return parseSubjectKeyIDValue(ctx, method)
+}
+
+// checkSubjectKeyIDFlag returns an error if the --ski flag is not a known
+// method or a hexadecimal string. It does not require the public key, so the
+// flag can be checked before generating it.
+func checkSubjectKeyIDFlag(ctx *cli.Context) error {
+	switch method := ctx.String("ski"); strings.ToLower(method) {
+	case "", "sha1-pubkey", "sha256-pubkey":
+		return nil
+	default:
+		_, err := parseSubjectKeyIDValue(ctx, method)
+		return err
+	}
+}
+
+// parseSubjectKeyIDValue parses a literal hexadecimal subject key identifier,
+// the bytes can be separated by colons.
+func parseSubjectKeyIDValue(ctx *cli.Context, value string) ([]byte, error) {
+	ski, err := hex.DecodeString(strings.ReplaceAll(value, ":", ""))
+	if err != nil || len(ski) == 0 {
+		return nil, errs.InvalidFlagValueMsg(ctx, "ski", value, "value must be sha1-pubkey, sha256-pubkey, or a hexadecimal string")
+	}
+	return ski, nil
+}
+
+// subjectPublicKeyBytes returns the bytes of the subjectPublicKey bit string
+// in the SubjectPublicKeyInfo of the public key.
+func subjectPublicKeyBytes(pub crypto.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling public key")
+	}
+	var info struct {
+		Algorithm        asn1.RawValue
+		SubjectPublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, errors.Wrap(err, "error parsing public key")
+	}
+	return info.SubjectPublicKey.Bytes, nil
+}
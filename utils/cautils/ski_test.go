@@ -0,0 +1,66 @@
+package cautils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // used to compute the RFC 5280 key identifier
+	"crypto/sha256"
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+)
+
+func Test_parseSubjectKeyID(t *testing.T) {
+	newContext := func(t *testing.T, ski string) *cli.Context {
+		t.Helper()
+		fs := flag.NewFlagSet("contrive", 0)
+		_ = fs.String("ski", ski, "")
+		return cli.NewContext(&cli.App{}, fs, nil)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	// The subjectPublicKey of an EC key is the uncompressed point.
+	ecdhKey, err := key.PublicKey.ECDH()
+	require.NoError(t, err)
+	sha1Sum := sha1.Sum(ecdhKey.Bytes()) //nolint:gosec // used to compute the RFC 5280 key identifier
+	sha256Sum := sha256.Sum256(ecdhKey.Bytes())
+
+	tests := []struct {
+		name    string
+		ski     string
+		want    []byte
+		wantErr bool
+	}{
+		{"ok/empty", "", nil, false},
+		{"ok/sha1-pubkey", "sha1-pubkey", sha1Sum[:], false},
+		{"ok/sha1-pubkey-upper", "SHA1-PUBKEY", sha1Sum[:], false},
+		{"ok/sha256-pubkey", "sha256-pubkey", sha256Sum[:20], false},
+		{"ok/hex", "0102ab", []byte{1, 2, 0xab}, false},
+		{"ok/hex-colons", "01:02:AB", []byte{1, 2, 0xab}, false},
+		{"fail/hex", "0102zz", nil, true},
+		{"fail/odd", "012", nil, true},
+		{"fail/method", "md5-pubkey", nil, true},
+		{"fail/colons", ":", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := newContext(t, tt.ski)
+			got, err := parseSubjectKeyID(ctx, key.Public())
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Error(t, checkSubjectKeyIDFlag(ctx))
+				return
+			}
+			assert.NoError(t, checkSubjectKeyIDFlag(ctx))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+}
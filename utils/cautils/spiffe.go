@@ -0,0 +1,97 @@
+package cautils
+
+import (
+	"bytes"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// SPIFFETemplateData is the data used to render the --spiffe-template flag.
+type SPIFFETemplateData struct {
+	TrustDomain string
+	Workload    string
+	Env         map[string]string
+}
+
+// NewSPIFFETemplateData returns the template data with the given trust domain
+// and workload, and the environment variables of the process.
+func NewSPIFFETemplateData(trustDomain, workload string) SPIFFETemplateData {
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+	return SPIFFETemplateData{
+		TrustDomain: trustDomain,
+		Workload:    workload,
+		Env:         env,
+	}
+}
+
+// RenderSPIFFETemplate renders the SPIFFE ID template with the given data,
+// and validates that the result is a valid SPIFFE ID.
+func RenderSPIFFETemplate(text string, data SPIFFETemplateData) (string, error) {
+	tmpl, err := template.New("spiffe").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", errors.Wrap(err, "error parsing SPIFFE template")
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", errors.Wrap(err, "error rendering SPIFFE template")
+	}
+	id := buf.String()
+	if err := validateSPIFFEID(id); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// validateSPIFFEID checks that id is a valid SPIFFE ID, as defined in the
+// SPIFFE ID specification: the scheme must be spiffe, the trust domain can
+// only contain lowercase letters, digits, dots, dashes, and underscores, and
+// the path segments cannot be empty, "." or "..".
+func validateSPIFFEID(id string) error {
+	u, err := url.Parse(id)
+	if err != nil {
+		return errors.Errorf("invalid SPIFFE ID %q: %s", id, err)
+	}
+	switch {
+	case u.Scheme != "spiffe":
+		return errors.Errorf("invalid SPIFFE ID %q: scheme must be spiffe", id)
+	case u.Host == "":
+		return errors.Errorf("invalid SPIFFE ID %q: trust domain is missing", id)
+	case u.User != nil, u.Port() != "":
+		return errors.Errorf("invalid SPIFFE ID %q: trust domain cannot contain a user or a port", id)
+	case u.RawQuery != "", u.Fragment != "", strings.ContainsAny(id, "?#"):
+		return errors.Errorf("invalid SPIFFE ID %q: query and fragment are not allowed", id)
+	}
+	for _, r := range u.Host {
+		if !isSPIFFEChar(r) || (r >= 'A' && r <= 'Z') {
+			return errors.Errorf("invalid SPIFFE ID %q: trust domain can only contain lowercase letters, digits, dots, dashes, and underscores", id)
+		}
+	}
+	if u.Path == "" {
+		return errors.Errorf("invalid SPIFFE ID %q: workload path is missing", id)
+	}
+	for _, segment := range strings.Split(strings.TrimPrefix(u.Path, "/"), "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return errors.Errorf("invalid SPIFFE ID %q: path segments cannot be empty, '.' or '..'", id)
+		}
+		for _, r := range segment {
+			if !isSPIFFEChar(r) {
+				return errors.Errorf("invalid SPIFFE ID %q: path can only contain letters, digits, dots, dashes, and underscores", id)
+			}
+		}
+	}
+	return nil
+}
+
+func isSPIFFEChar(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') ||
+		r == '.' || r == '-' || r == '_'
+}
@@ -0,0 +1,44 @@
+package cautils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderSPIFFETemplate(t *testing.T) {
+	t.Setenv("STEP_TEST_NAMESPACE", "prod")
+	data := NewSPIFFETemplateData("example.org", "web")
+
+	tests := []struct {
+		name    string
+		text    string
+		want    string
+		wantErr bool
+	}{
+		{"ok", "spiffe://{{.TrustDomain}}/{{.Workload}}", "spiffe://example.org/web", false},
+		{"ok/env", "spiffe://{{.TrustDomain}}/ns/{{.Env.STEP_TEST_NAMESPACE}}/{{.Workload}}", "spiffe://example.org/ns/prod/web", false},
+		{"fail/parse", "spiffe://{{.TrustDomain", "", true},
+		{"fail/missing-env", "spiffe://{{.TrustDomain}}/{{.Env.STEP_TEST_MISSING}}", "", true},
+		{"fail/scheme", "https://{{.TrustDomain}}/{{.Workload}}", "", true},
+		{"fail/no-path", "spiffe://{{.TrustDomain}}", "", true},
+		{"fail/empty-segment", "spiffe://{{.TrustDomain}}//{{.Workload}}", "", true},
+		{"fail/dot-segment", "spiffe://{{.TrustDomain}}/../{{.Workload}}", "", true},
+		{"fail/uppercase", "spiffe://Example.org/{{.Workload}}", "", true},
+		{"fail/port", "spiffe://{{.TrustDomain}}:8443/{{.Workload}}", "", true},
+		{"fail/query", "spiffe://{{.TrustDomain}}/{{.Workload}}?foo=bar", "", true},
+		{"fail/char", "spiffe://{{.TrustDomain}}/web%20app", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderSPIFFETemplate(tt.text, data)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/smallstep/cli-utils/ui"
 
 	"github.com/smallstep/cli/flags"
+	"github.com/smallstep/cli/internal/prompt"
 	"github.com/smallstep/cli/utils"
 )
 
@@ -108,6 +109,24 @@ func NewTokenFlow(ctx *cli.Context, tokType int, subject string, sans []string,
 	if err != nil {
 		return "", err
 	}
+
+	if ctx.Bool("auto-provisioner") {
+		if subject == "" {
+			return "", errors.New("cannot create a new token: a subject is required with '--auto-provisioner'")
+		}
+		return autoProvisionerToken(ctx, provisioners, tokType, tokenAttrs{
+			subject:       subject,
+			root:          root,
+			caURL:         caURL,
+			audience:      audience,
+			sans:          sans,
+			notBefore:     notBefore,
+			notAfter:      notAfter,
+			certNotBefore: certNotBefore,
+			certNotAfter:  certNotAfter,
+		})
+	}
+
 	p, err := provisionerPrompt(ctx, provisioners)
 	if err != nil {
 		return "", err
@@ -121,7 +140,7 @@ func NewTokenFlow(ctx *cli.Context, tokType int, subject string, sans []string,
 			if tokType == SSHUserSignType {
 				q = "What user principal would you like to use? (e.g. alice)"
 			}
-			subject, err = ui.Prompt(q, ui.WithValidateNotEmpty())
+			subject, err = prompt.Prompt(q, ui.WithValidateNotEmpty())
 			if err != nil {
 				return "", err
 			}
@@ -140,6 +159,11 @@ func NewTokenFlow(ctx *cli.Context, tokType int, subject string, sans []string,
 		certNotAfter:  certNotAfter,
 	}
 
+	return generateProvisionerToken(ctx, p, tokType, tokAttrs)
+}
+
+// generateProvisionerToken generates a token using the given provisioner.
+func generateProvisionerToken(ctx *cli.Context, p provisioner.Interface, tokType int, tokAttrs tokenAttrs) (string, error) {
 	switch p := p.(type) {
 	case *provisioner.JWK: // Get the step standard JWT.
 		return generateJWKToken(ctx, p, tokType, tokAttrs)
@@ -155,13 +179,13 @@ func NewTokenFlow(ctx *cli.Context, tokType int, subject string, sans []string,
 		return generateK8sSAToken(ctx)
 	case *provisioner.GCP: // Do the identity request to get the token.
 		sharedContext.DisableCustomSANs = p.DisableCustomSANs
-		return p.GetIdentityToken(subject, caURL)
+		return p.GetIdentityToken(tokAttrs.subject, tokAttrs.caURL)
 	case *provisioner.AWS: // Do the identity request to get the token.
 		sharedContext.DisableCustomSANs = p.DisableCustomSANs
-		return p.GetIdentityToken(subject, caURL)
+		return p.GetIdentityToken(tokAttrs.subject, tokAttrs.caURL)
 	case *provisioner.Azure: // Do the identity request to get the token.
 		sharedContext.DisableCustomSANs = p.DisableCustomSANs
-		return p.GetIdentityToken(subject, caURL)
+		return p.GetIdentityToken(tokAttrs.subject, tokAttrs.caURL)
 	case *provisioner.ACME: // Return an error with the provisioner ID.
 		return "", &ACMETokenError{p.GetName()}
 	default:
@@ -276,6 +300,66 @@ func allowNebulaProvisionerFilter(p provisioner.Interface) bool {
 }
 
 func provisionerPrompt(ctx *cli.Context, provisioners provisioner.List) (provisioner.Interface, error) {
+	items, err := provisionerItems(ctx, provisioners)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(items) == 1 {
+		if err := ui.PrintSelected("Provisioner", items[0].Name); err != nil {
+			return nil, err
+		}
+		return items[0].Provisioner, nil
+	}
+
+	i, _, err := prompt.Select("What provisioner key do you want to use?", items, ui.WithSelectTemplates(ui.NamedSelectTemplates("Provisioner")))
+	if err != nil {
+		return nil, err
+	}
+
+	return items[i].Provisioner, nil
+}
+
+// autoProvisionerToken tries the provisioners in order, and returns the token
+// generated by the first one that can generate one without user interaction.
+// OIDC and ACME provisioners are skipped, and JWK provisioners are only tried
+// if the provisioner password file is given.
+func autoProvisionerToken(ctx *cli.Context, provisioners provisioner.List, tokType int, tokAttrs tokenAttrs) (string, error) {
+	items, err := provisionerItems(ctx, provisioners)
+	if err != nil {
+		return "", err
+	}
+
+	var failures []string
+	for _, item := range items {
+		switch item.Provisioner.(type) {
+		case *provisioner.OIDC, *provisioner.ACME:
+			continue
+		case *provisioner.JWK:
+			if ctx.String("provisioner-password-file") == "" {
+				continue
+			}
+		}
+		tok, err := generateProvisionerToken(ctx, item.Provisioner, tokType, tokAttrs)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", item.Name, err))
+			continue
+		}
+		if err := ui.PrintSelected("Provisioner", item.Name); err != nil {
+			return "", err
+		}
+		return tok, nil
+	}
+
+	if len(failures) == 0 {
+		return "", errors.New("cannot create a new token: there are no provisioners that can be used with '--auto-provisioner'")
+	}
+	return "", errors.Errorf("cannot create a new token: all the provisioners failed:\n%s", strings.Join(failures, "\n"))
+}
+
+// provisionerItems filters the provisioners using the flags in the context and
+// returns the list of provisioners to select from.
+func provisionerItems(ctx *cli.Context, provisioners provisioner.List) ([]*provisionersSelect, error) {
 	switch {
 	// If x5c flags then only list x5c provisioners.
 	case ctx.IsSet("x5c-cert") || ctx.IsSet("x5c-key"):
@@ -379,19 +463,7 @@ func provisionerPrompt(ctx *cli.Context, provisioners provisioner.List) (provisi
 		}
 	}
 
-	if len(items) == 1 {
-		if err := ui.PrintSelected("Provisioner", items[0].Name); err != nil {
-			return nil, err
-		}
-		return items[0].Provisioner, nil
-	}
-
-	i, _, err := ui.Select("What provisioner key do you want to use?", items, ui.WithSelectTemplates(ui.NamedSelectTemplates("Provisioner")))
-	if err != nil {
-		return nil, err
-	}
-
-	return items[i].Provisioner, nil
+	return items, nil
 }
 
 // provisionerFilter returns a slice of provisioners that pass the given filter.
@@ -0,0 +1,91 @@
+package cautils
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli"
+	"go.step.sm/crypto/jose"
+
+	"github.com/smallstep/certificates/authority/provisioner"
+
+	"github.com/smallstep/cli/token"
+)
+
+func Test_autoProvisionerToken(t *testing.T) {
+	newJWK := func(t *testing.T, name, password string) *provisioner.JWK {
+		t.Helper()
+		jwk, err := jose.GenerateJWK("EC", "P-256", "ES256", "sig", "", 0)
+		require.NoError(t, err)
+		jwe, err := jose.EncryptJWK(jwk, []byte(password))
+		require.NoError(t, err)
+		encryptedKey, err := jwe.CompactSerialize()
+		require.NoError(t, err)
+		pub := jwk.Public()
+		return &provisioner.JWK{
+			Type:         "JWK",
+			Name:         name,
+			Key:          &pub,
+			EncryptedKey: encryptedKey,
+		}
+	}
+
+	passwordFile := filepath.Join(t.TempDir(), "password.txt")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("password"), 0600))
+
+	newContext := func(t *testing.T, passwordFile, issuer string) *cli.Context {
+		t.Helper()
+		fs := flag.NewFlagSet("contrive", 0)
+		_ = fs.Bool("offline", false, "")
+		_ = fs.String("provisioner-password-file", passwordFile, "")
+		_ = fs.String("issuer", issuer, "")
+		require.NoError(t, fs.Set("offline", "true"))
+		return cli.NewContext(&cli.App{}, fs, nil)
+	}
+
+	good := newJWK(t, "good", "password")
+	bad := newJWK(t, "bad", "other-password")
+	other := newJWK(t, "other", "password")
+	oidc := &provisioner.OIDC{Type: "OIDC", Name: "oidc", ClientID: "client-id"}
+	acme := &provisioner.ACME{Type: "ACME", Name: "acme"}
+
+	tests := []struct {
+		name         string
+		ctx          *cli.Context
+		provisioners provisioner.List
+		wantIssuer   string
+		wantErr      string
+	}{
+		{"ok", newContext(t, passwordFile, ""), provisioner.List{good}, "good", ""},
+		{"ok/skip-oidc-and-acme", newContext(t, passwordFile, ""), provisioner.List{oidc, acme, good}, "good", ""},
+		{"ok/skip-failed", newContext(t, passwordFile, ""), provisioner.List{bad, good}, "good", ""},
+		{"ok/first", newContext(t, passwordFile, ""), provisioner.List{other, good}, "other", ""},
+		{"ok/issuer", newContext(t, passwordFile, "good"), provisioner.List{other, good}, "good", ""},
+		{"fail/empty", newContext(t, passwordFile, ""), provisioner.List{}, "", "the CA does not have any provisioner configured"},
+		{"fail/no-password-file", newContext(t, "", ""), provisioner.List{good}, "", "there are no provisioners that can be used"},
+		{"fail/only-oidc-and-acme", newContext(t, passwordFile, ""), provisioner.List{oidc, acme}, "", "there are no provisioners that can be used"},
+		{"fail/all-failed", newContext(t, passwordFile, ""), provisioner.List{bad}, "", "all the provisioners failed:\nbad (JWK)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := autoProvisionerToken(tt.ctx, tt.provisioners, SignType, tokenAttrs{
+				subject:  "test.example.com",
+				audience: "https://ca.example.com/1.0/sign",
+			})
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				assert.Empty(t, got)
+				return
+			}
+			require.NoError(t, err)
+			jwt, err := token.ParseInsecure(got)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantIssuer, jwt.Payload.Issuer)
+			assert.Equal(t, "test.example.com", jwt.Payload.Subject)
+		})
+	}
+}
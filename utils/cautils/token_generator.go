@@ -19,13 +19,13 @@ import (
 	"github.com/smallstep/certificates/authority/provisioner"
 	"github.com/smallstep/certificates/pki"
 	"github.com/smallstep/cli-utils/errs"
-	"github.com/smallstep/cli-utils/ui"
 	"go.step.sm/crypto/jose"
 	"go.step.sm/crypto/pemutil"
 	"go.step.sm/crypto/randutil"
 
 	"github.com/smallstep/cli/exec"
 	"github.com/smallstep/cli/internal/cryptoutil"
+	"github.com/smallstep/cli/internal/prompt"
 	"github.com/smallstep/cli/token"
 	"github.com/smallstep/cli/token/provision"
 )
@@ -395,7 +395,7 @@ func loadJWK(ctx *cli.Context, p *provisioner.JWK, tokAttrs tokenAttrs) (jwk *jo
 
 		opts = append(opts, jose.WithPasswordPrompter("Please enter the password to decrypt the provisioner key",
 			func(s string) ([]byte, error) {
-				return ui.PromptPassword(s)
+				return prompt.PromptPassword(s)
 			}),
 		)
 
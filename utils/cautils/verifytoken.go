@@ -0,0 +1,80 @@
+package cautils
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/smallstep/certificates/api"
+	"github.com/smallstep/certificates/authority/provisioner"
+	"github.com/smallstep/certificates/ca"
+	"go.step.sm/crypto/jose"
+
+	"github.com/smallstep/cli/token"
+)
+
+// provisionersClient is implemented by the online CA clients.
+type provisionersClient interface {
+	Provisioners(opts ...ca.ProvisionerOption) (*api.ProvisionersResponse, error)
+}
+
+// getProvisioners returns the list of provisioners of the CA.
+func getProvisioners(client CaClient) (provisioner.List, error) {
+	switch c := client.(type) {
+	case *OfflineCA:
+		return c.Provisioners(), nil
+	case provisionersClient:
+		var list provisioner.List
+		var cursor string
+		for {
+			resp, err := c.Provisioners(ca.WithProvisionerCursor(cursor), ca.WithProvisionerLimit(100))
+			if err != nil {
+				return nil, errors.Wrap(err, "error getting the provisioners")
+			}
+			list = append(list, resp.Provisioners...)
+			if resp.NextCursor == "" {
+				return list, nil
+			}
+			cursor = resp.NextCursor
+		}
+	default:
+		return nil, errors.Errorf("error getting the provisioners: unsupported client %T", client)
+	}
+}
+
+// verifyTokenSignature verifies the signature of the token with the key of
+// the JWK provisioner that issued it, as required by the --verify-token flag.
+// Only tokens of JWK provisioners can be verified.
+func verifyTokenSignature(tok string, provisioners provisioner.List) error {
+	jwt, err := jose.ParseSigned(tok)
+	if err != nil {
+		return errors.Wrap(err, "error parsing token")
+	}
+	if len(jwt.Headers) == 0 {
+		return errors.New("error verifying token: the token does not have a header")
+	}
+	payload, err := token.ParseInsecure(tok)
+	if err != nil {
+		return err
+	}
+
+	kid, name := jwt.Headers[0].KeyID, payload.Payload.Issuer
+	var found bool
+	for _, p := range provisioners {
+		jwk, ok := p.(*provisioner.JWK)
+		if !ok || jwk.Name != name {
+			continue
+		}
+		found = true
+		if jwk.Key == nil || jwk.Key.KeyID != kid {
+			continue
+		}
+		var claims jose.Claims
+		if err := jwt.Claims(jwk.Key.Public(), &claims); err != nil {
+			return errors.Errorf("error verifying token: the signature is not valid for the key %s of the provisioner %s", kid, name)
+		}
+		return nil
+	}
+	if !found {
+		return errors.Errorf("error verifying token: the CA does not have a JWK provisioner named %s, only tokens of JWK provisioners can be verified", name)
+	}
+	return errors.Errorf("error verifying token: the provisioner %s does not have the key %s", name, kid)
+}
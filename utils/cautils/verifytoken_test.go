@@ -0,0 +1,60 @@
+package cautils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.step.sm/crypto/jose"
+
+	"github.com/smallstep/certificates/authority/provisioner"
+
+	"github.com/smallstep/cli/token"
+)
+
+func Test_verifyTokenSignature(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	newToken := func(t *testing.T, issuer, kid string, key *ecdsa.PrivateKey) string {
+		t.Helper()
+		claims, err := token.NewClaims(token.WithIssuer(issuer), token.WithSubject("foo"), token.WithKid(kid))
+		require.NoError(t, err)
+		tok, err := claims.Sign(jose.ES256, key)
+		require.NoError(t, err)
+		return tok
+	}
+
+	provisioners := provisioner.List{
+		&provisioner.OIDC{Name: "oidc", Type: "OIDC"},
+		&provisioner.JWK{Name: "admin", Type: "JWK", Key: &jose.JSONWebKey{Key: key.Public(), KeyID: "the-kid"}},
+	}
+
+	tests := []struct {
+		name    string
+		tok     string
+		wantErr string
+	}{
+		{"ok", newToken(t, "admin", "the-kid", key), ""},
+		{"fail/signature", newToken(t, "admin", "the-kid", other), "signature is not valid"},
+		{"fail/kid", newToken(t, "admin", "other-kid", key), "does not have the key"},
+		{"fail/provisioner", newToken(t, "other", "the-kid", key), "does not have a JWK provisioner"},
+		{"fail/not-jwk", newToken(t, "oidc", "the-kid", key), "does not have a JWK provisioner"},
+		{"fail/token", "not-a-token", "error parsing token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyTokenSignature(tt.tok, provisioners)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
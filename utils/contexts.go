@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/smallstep/cli-utils/errs"
+	"github.com/smallstep/cli-utils/step"
+)
+
+// stepPathLockTimeout is the maximum time to wait for another step process
+// writing the defaults or contexts files.
+const stepPathLockTimeout = 30 * time.Second
+
+// LockStepPath acquires the lock that serializes the writes to the defaults
+// and contexts files in the base step path. It returns a function that
+// releases the lock.
+func LockStepPath() (func(), error) {
+	base := step.BasePath()
+	if err := os.MkdirAll(base, 0700); err != nil {
+		return nil, errs.FileError(err, base)
+	}
+	return AcquireLockFile(filepath.Join(base, ".step.lock"), stepPathLockTimeout)
+}
+
+// SaveContext adds the given context to the contexts file, stores it as the
+// current context, and reloads the context state. The contexts file is read
+// again before adding the context, so contexts added by other processes are
+// preserved. The caller must hold the lock returned by LockStepPath.
+func SaveContext(c *step.Context) error {
+	if err := c.Validate(); err != nil {
+		return errors.Wrap(err, "error adding context")
+	}
+	if err := updateContextsFile(step.ContextsFile(), func(m step.ContextMap) error {
+		m[c.Name] = c
+		return nil
+	}); err != nil {
+		return err
+	}
+	if err := writeCurrentContext(step.CurrentContextFile(), c.Name); err != nil {
+		return err
+	}
+	return step.Contexts().Init()
+}
+
+// SaveCurrentContext stores the given context name as the current context.
+// The caller must hold the lock returned by LockStepPath.
+func SaveCurrentContext(name string) error {
+	if _, ok := step.Contexts().Get(name); !ok {
+		return errors.Errorf("context '%s' not found", name)
+	}
+	return writeCurrentContext(step.CurrentContextFile(), name)
+}
+
+// RemoveContext removes the given context from the contexts file and reloads
+// the context state. The caller must hold the lock returned by LockStepPath.
+func RemoveContext(name string) error {
+	cs := step.Contexts()
+	if _, ok := cs.Get(name); !ok {
+		return errors.Errorf("context '%s' not found", name)
+	}
+	if cur := cs.GetCurrent(); cur != nil && cur.Name == name {
+		return errors.New("cannot remove current context; use 'step context select' to switch contexts")
+	}
+	if err := updateContextsFile(step.ContextsFile(), func(m step.ContextMap) error {
+		delete(m, name)
+		return nil
+	}); err != nil {
+		return err
+	}
+	return cs.Init()
+}
+
+// updateContextsFile reads the contexts in filename, calls fn to modify them,
+// and atomically writes them back.
+func updateContextsFile(filename string, fn func(step.ContextMap) error) error {
+	m := step.ContextMap{}
+	b, err := os.ReadFile(filename)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return errs.FileError(err, filename)
+	default:
+		if err := json.Unmarshal(b, &m); err != nil {
+			return errors.Wrapf(err, "error parsing %s", filename)
+		}
+	}
+
+	if err := fn(m); err != nil {
+		return err
+	}
+
+	if b, err = json.MarshalIndent(m, "", "    "); err != nil {
+		return errors.Wrap(err, "error marshaling contexts")
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+		return errs.FileError(err, filename)
+	}
+	return ReplaceFile(filename, b, 0600)
+}
+
+func writeCurrentContext(filename, name string) error {
+	b, err := json.Marshal(map[string]string{"context": name})
+	if err != nil {
+		return errors.Wrap(err, "error marshaling current context")
+	}
+	return ReplaceFile(filename, b, 0644)
+}
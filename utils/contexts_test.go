@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smallstep/cli-utils/step"
+)
+
+func Test_updateContextsFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file locks are not supported on windows")
+	}
+
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "contexts.json")
+	lockFile := filepath.Join(dir, ".step.lock")
+
+	// Simulate concurrent step processes adding a context each.
+	const writers = 20
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			unlock, err := AcquireLockFile(lockFile, 10*time.Second)
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer unlock()
+			assert.NoError(t, updateContextsFile(filename, func(m step.ContextMap) error {
+				m[name] = &step.Context{Name: name, Authority: name, Profile: name}
+				return nil
+			}))
+		}(fmt.Sprintf("ctx-%02d", i))
+	}
+	wg.Wait()
+
+	b, err := os.ReadFile(filename)
+	require.NoError(t, err)
+	var m step.ContextMap
+	require.NoError(t, json.Unmarshal(b, &m))
+	assert.Len(t, m, writers)
+	assert.Equal(t, "ctx-07", m["ctx-07"].Authority)
+
+	require.NoError(t, updateContextsFile(filename, func(m step.ContextMap) error {
+		delete(m, "ctx-07")
+		return nil
+	}))
+	b, err = os.ReadFile(filename)
+	require.NoError(t, err)
+	m = step.ContextMap{}
+	require.NoError(t, json.Unmarshal(b, &m))
+	assert.Len(t, m, writers-1)
+
+	require.NoError(t, os.WriteFile(filename, []byte(`{"foo":`), 0600))
+	assert.Error(t, updateContextsFile(filename, func(step.ContextMap) error { return nil }))
+}
@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/smallstep/cli/utils/sysutils"
+)
+
+// lockRetryInterval is the time between attempts to acquire a lock file.
+const lockRetryInterval = 100 * time.Millisecond
+
+// AcquireLockFile acquires an exclusive lock on the given file, waiting up to
+// the given timeout if another process holds it. It returns a function that
+// releases the lock. The operating system releases the lock if the process
+// exits without calling it, e.g. on an interrupt.
+//
+// File locks are not implemented on Windows, where the file is created but
+// the lock always succeeds immediately, so it does not serialize processes.
+func AcquireLockFile(filename string, timeout time.Duration) (func(), error) {
+	f, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error opening %s", filename)
+	}
+
+	fd := int(f.Fd())
+	deadline := time.Now().Add(timeout)
+	for {
+		// non-blocking exclusive lock
+		err = sysutils.FileLock(fd)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			f.Close()
+			return nil, errors.Wrapf(err, "error locking %s", filename)
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, errors.Errorf("error locking %s: timeout after %s", filename, timeout)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+
+	return func() {
+		sysutils.FileUnlock(fd)
+		f.Close()
+	}, nil
+}
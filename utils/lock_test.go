@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquireLockFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file locks are not supported on windows")
+	}
+
+	filename := filepath.Join(t.TempDir(), "step.lock")
+
+	unlock, err := AcquireLockFile(filename, 0)
+	require.NoError(t, err)
+
+	_, err = AcquireLockFile(filename, 200*time.Millisecond)
+	assert.ErrorContains(t, err, "timeout")
+
+	unlock()
+	unlock, err = AcquireLockFile(filename, 0)
+	require.NoError(t, err)
+	unlock()
+}
@@ -11,8 +11,8 @@ import (
 	"github.com/pkg/errors"
 
 	"github.com/smallstep/cli-utils/errs"
-	"github.com/smallstep/cli-utils/ui"
 
+	"github.com/smallstep/cli/internal/prompt"
 	"github.com/smallstep/cli/utils/internal/utfbom"
 )
 
@@ -73,14 +73,14 @@ func ReadStringPasswordFromFile(filename string) (string, error) {
 
 // ReadInput from stdin if something is detected or ask the user for an input
 // using the given prompt.
-func ReadInput(prompt string) ([]byte, error) {
+func ReadInput(label string) ([]byte, error) {
 	st, err := stdin.Stat()
 	if err != nil {
 		return nil, errors.Wrap(err, "error reading data")
 	}
 
 	if st.Size() == 0 && st.Mode()&os.ModeNamedPipe == 0 {
-		return ui.PromptPassword(prompt)
+		return prompt.PromptPassword(label)
 	}
 
 	return ReadAll(stdin)
@@ -2,22 +2,38 @@ package sysutils
 
 import "syscall"
 
+// Flock applies or removes an advisory lock on the open file descriptor fd
+// using flock(2). It returns an error on Windows.
 func Flock(fd, how int) error {
 	return flock(fd, how)
 }
 
+// FileLock acquires an exclusive lock on the open file descriptor fd without
+// blocking, it fails if the lock is held. It is a no-op on Windows.
 func FileLock(fd int) error {
 	return fileLock(fd)
 }
 
+// FileUnlock releases the lock acquired with FileLock. It is a no-op on
+// Windows.
 func FileUnlock(fd int) error {
 	return fileUnlock(fd)
 }
 
+// Kill sends the signal signum to the process pid. It returns an error on
+// Windows.
 func Kill(pid int, signum syscall.Signal) error {
 	return kill(pid, signum)
 }
 
+// Exec replaces the current process with argv0 using execve(2). It returns an
+// error on Windows.
 func Exec(argv0 string, argv, envv []string) error {
 	return exec(argv0, argv, envv)
 }
+
+// Umask sets the file mode creation mask of the process and returns the
+// previous one. It returns an error on Windows, where umask is not supported.
+func Umask(mask int) (int, error) {
+	return umask(mask)
+}
@@ -26,3 +26,7 @@ func kill(pid int, signum syscall.Signal) error {
 func exec(argv0 string, argv, envv []string) error {
 	return syscall.Exec(argv0, argv, envv)
 }
+
+func umask(mask int) (int, error) {
+	return syscall.Umask(mask), nil
+}
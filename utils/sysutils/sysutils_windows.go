@@ -33,10 +33,13 @@ func flock(fd int, how int) error {
 	return syscall.EWINDOWS
 }
 
+// fileLock is a no-op on Windows, file locks are not implemented and the
+// lock always succeeds.
 func fileLock(fd int) error {
 	return nil
 }
 
+// fileUnlock is a no-op on Windows, see fileLock.
 func fileUnlock(fd int) error {
 	return nil
 }
@@ -48,3 +51,7 @@ func kill(pid int, signum syscall.Signal) error {
 func exec(argv0 string, argv []string, envv []string) error {
 	return syscall.EWINDOWS
 }
+
+func umask(mask int) (int, error) {
+	return 0, syscall.EWINDOWS
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/smallstep/cli/utils/sysutils"
+)
+
+// umask is the mask applied to the permissions of the written files. The
+// umask of the process does not apply to the files written with an explicit
+// chmod, so it is also kept here to mask their permissions. It is initialized
+// with the umask of the process, and it is changed with SetUmask.
+var umask = processUmask()
+
+// processUmask returns the umask of the process. There is no way to read it
+// without setting it, so it is set to 0 and restored. It returns 0 on
+// Windows.
+func processUmask() os.FileMode {
+	old, err := sysutils.Umask(0)
+	if err != nil {
+		return 0
+	}
+	sysutils.Umask(old)
+	return os.FileMode(old).Perm()
+}
+
+// ParseUmask parses the value of the --umask flag, an octal number like 077
+// or 0027.
+func ParseUmask(s string) (os.FileMode, error) {
+	n, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || n > 0o777 {
+		return 0, errors.Errorf("invalid umask %q, it must be an octal number between 000 and 777", s)
+	}
+	return os.FileMode(n), nil
+}
+
+// SetUmask sets the umask of the process and the mask applied to the
+// permissions of the files written by this package.
+func SetUmask(mask os.FileMode) error {
+	if _, err := sysutils.Umask(int(mask.Perm())); err != nil {
+		return errors.Wrap(err, "error setting umask")
+	}
+	umask = mask.Perm()
+	return nil
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smallstep/cli/utils/sysutils"
+)
+
+func TestParseUmask(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    os.FileMode
+		wantErr bool
+	}{
+		{"ok", "077", 0o077, false},
+		{"ok/leading-zero", "0027", 0o027, false},
+		{"ok/zero", "0", 0, false},
+		{"fail/decimal", "089", 0, true},
+		{"fail/too-large", "1777", 0, true},
+		{"fail/text", "u=rwx", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseUmask(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSetUmask(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("umask is not supported on Windows")
+	}
+	old, err := sysutils.Umask(0o022)
+	require.NoError(t, err)
+	oldUmask := umask
+	t.Cleanup(func() {
+		sysutils.Umask(old)
+		umask = oldUmask
+	})
+
+	require.NoError(t, SetUmask(0o077))
+	dir := t.TempDir()
+
+	// Files written with an explicit chmod and with the process umask.
+	replaced := filepath.Join(dir, "replaced.crt")
+	require.NoError(t, ReplaceFile(replaced, []byte("data"), 0644))
+	written := filepath.Join(dir, "written.crt")
+	require.NoError(t, writeFile(written, []byte("data"), 0644))
+	for _, filename := range []string{replaced, written} {
+		st, err := os.Stat(filename)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), st.Mode().Perm(), filename)
+	}
+}
+
+func Test_processUmask(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("umask is not supported on Windows")
+	}
+	old, err := sysutils.Umask(0o077)
+	require.NoError(t, err)
+	oldUmask := umask
+	t.Cleanup(func() {
+		sysutils.Umask(old)
+		umask = oldUmask
+	})
+
+	// The umask of the process is kept.
+	umask = processUmask()
+	assert.Equal(t, os.FileMode(0o077), umask)
+	current, err := sysutils.Umask(0o077)
+	require.NoError(t, err)
+	assert.Equal(t, 0o077, current)
+
+	// The files written with an explicit chmod use the umask of the process.
+	filename := filepath.Join(t.TempDir(), "defaults.json")
+	require.NoError(t, ReplaceFile(filename, []byte("{}"), 0644))
+	st, err := os.Stat(filename)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), st.Mode().Perm())
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"time"
 
@@ -14,6 +16,8 @@ import (
 	"github.com/smallstep/cli-utils/command"
 	"github.com/smallstep/cli-utils/errs"
 	"github.com/smallstep/cli-utils/ui"
+
+	"github.com/smallstep/cli/internal/prompt"
 )
 
 var (
@@ -32,19 +36,77 @@ var (
 	SnippetFooter = "# end"
 )
 
+// fsync indicates if the files written by WriteFile and the atomic writers
+// are flushed to disk before returning.
+var fsync = true
+
+// SetFsync enables or disables flushing the written files and their
+// directories to disk. It is enabled by default, disabling it speeds up the
+// writes in environments where durability is not required, like CI.
+func SetFsync(enabled bool) {
+	fsync = enabled
+}
+
+// SyncFile flushes the contents of f to disk if fsync is enabled and f is a
+// regular file.
+func SyncFile(f *os.File) error {
+	if !fsync {
+		return nil
+	}
+	if st, err := f.Stat(); err != nil || !st.Mode().IsRegular() {
+		return nil
+	}
+	return f.Sync()
+}
+
+// SyncDir flushes the directory entries of dir to disk if fsync is enabled,
+// so a created or renamed file survives a crash. Directories cannot be synced
+// on Windows, where it does nothing.
+func SyncDir(dir string) error {
+	if !fsync || runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
 // WriteFile wraps os.WriteFile with a prompt to overwrite a file if
 // the file exists. It returns ErrFileExists if the user picks to not overwrite
 // the file. If force is set to true, the prompt will not be presented and the
 // file if exists will be overwritten.
 func WriteFile(filename string, data []byte, perm os.FileMode) error {
+	if err := ConfirmOverwrite(filename); err != nil {
+		return err
+	}
+	return writeFile(filename, data, perm)
+}
+
+// WriteFileAtomic is like WriteFile, but the file is replaced atomically using
+// ReplaceFile.
+func WriteFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	if err := ConfirmOverwrite(filename); err != nil {
+		return err
+	}
+	return ReplaceFile(filename, data, perm)
+}
+
+// ConfirmOverwrite asks the user to overwrite filename if it exists. It
+// returns ErrFileExists if the user picks to not overwrite the file, and it
+// does not prompt if force is set to true. It can be used to ask before
+// taking a lock and writing the file with ReplaceFile.
+func ConfirmOverwrite(filename string) error {
 	if command.IsForce() {
-		return os.WriteFile(filename, data, perm)
+		return nil
 	}
 
 	st, err := os.Stat(filename)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return os.WriteFile(filename, data, perm)
+			return nil
 		}
 		return errors.Wrapf(err, "error reading information for %s", filename)
 	}
@@ -53,7 +115,7 @@ func WriteFile(filename string, data []byte, perm os.FileMode) error {
 		return ErrIsDir
 	}
 
-	str, err := ui.Prompt(fmt.Sprintf("Would you like to overwrite %s [y/n]", filename), ui.WithValidateYesNo())
+	str, err := prompt.Prompt(fmt.Sprintf("Would you like to overwrite %s [y/n]", filename), ui.WithValidateYesNo())
 	if err != nil {
 		return err
 	}
@@ -62,8 +124,73 @@ func WriteFile(filename string, data []byte, perm os.FileMode) error {
 	case "n", "no":
 		return ErrFileExists
 	}
+	return nil
+}
+
+// ReplaceFile writes data to a temporary file in the directory of filename
+// and renames it to filename. Other processes reading filename will see the
+// old or the new contents, but never a partially written file. Unlike
+// WriteFile, it does not ask to overwrite an existing file.
+func ReplaceFile(filename string, data []byte, perm os.FileMode) error {
+	dir, base := filepath.Split(filename)
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.CreateTemp(dir, "."+base+".*")
+	if err != nil {
+		return errs.FileError(err, filename)
+	}
+	tmp := f.Name()
+	if err := replaceFile(f, data, perm); err != nil {
+		os.Remove(tmp)
+		return errs.FileError(err, filename)
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		os.Remove(tmp)
+		return errs.FileError(err, filename)
+	}
+	return SyncDir(dir)
+}
+
+func replaceFile(f *os.File, data []byte, perm os.FileMode) error {
+	if err := f.Chmod(perm &^ umask); err != nil && runtime.GOOS != "windows" {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := SyncFile(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
 
-	return os.WriteFile(filename, data, perm)
+// writeFile is like os.WriteFile, but it flushes the file and its directory
+// to disk if fsync is enabled.
+func writeFile(filename string, data []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := SyncFile(f); err != nil {
+		f.Close()
+		return err
+	}
+	st, statErr := f.Stat()
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if statErr == nil && st.Mode().IsRegular() {
+		return SyncDir(filepath.Dir(filename))
+	}
+	return nil
 }
 
 // AppendNewLine appends the given data at the end of the file. If the last
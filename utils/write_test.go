@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_writeFile(t *testing.T) {
+	t.Cleanup(func() { SetFsync(true) })
+
+	for _, enabled := range []bool{true, false} {
+		SetFsync(enabled)
+		filename := filepath.Join(t.TempDir(), "file.txt")
+		require.NoError(t, writeFile(filename, []byte("foo"), 0600))
+		require.NoError(t, writeFile(filename, []byte("bar"), 0600))
+		b, err := os.ReadFile(filename)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("bar"), b)
+	}
+
+	assert.Error(t, writeFile(filepath.Join(t.TempDir(), "missing", "file.txt"), []byte("foo"), 0600))
+}
+
+func TestSyncFile(t *testing.T) {
+	t.Cleanup(func() { SetFsync(true) })
+
+	// Pipes are not regular files, and they are not synced.
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer r.Close()
+	defer w.Close()
+	assert.NoError(t, SyncFile(w))
+
+	f, err := os.Create(filepath.Join(t.TempDir(), "file.txt"))
+	require.NoError(t, err)
+	defer f.Close()
+	assert.NoError(t, SyncFile(f))
+	assert.NoError(t, SyncDir(filepath.Dir(f.Name())))
+
+	SetFsync(false)
+	assert.NoError(t, SyncDir(filepath.Join(t.TempDir(), "missing")))
+	if runtime.GOOS != "windows" {
+		SetFsync(true)
+		assert.Error(t, SyncDir(filepath.Join(t.TempDir(), "missing")))
+	}
+}
+
+func TestReplaceFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "defaults.json")
+	require.NoError(t, ReplaceFile(filename, []byte(`{"ca-url":"https://ca.internal"}`), 0600))
+
+	// Readers must see one of the complete contents, never a partial write.
+	contents := [][]byte{
+		[]byte(`{"ca-url":"https://ca.internal"}`),
+		bytes.Repeat([]byte("a"), 1<<20),
+		bytes.Repeat([]byte("b"), 1<<20),
+	}
+	var wg sync.WaitGroup
+	for i := 1; i < len(contents); i++ {
+		wg.Add(1)
+		go func(data []byte) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				assert.NoError(t, ReplaceFile(filename, data, 0600))
+			}
+		}(contents[i])
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for {
+		b, err := os.ReadFile(filename)
+		require.NoError(t, err)
+		assert.Contains(t, contents, b)
+		select {
+		case <-done:
+			matches, err := filepath.Glob(filepath.Join(filepath.Dir(filename), ".defaults.json.*"))
+			require.NoError(t, err)
+			assert.Empty(t, matches)
+			if runtime.GOOS != "windows" {
+				st, err := os.Stat(filename)
+				require.NoError(t, err)
+				assert.Equal(t, os.FileMode(0600), st.Mode().Perm())
+			}
+			return
+		default:
+		}
+	}
+}